@@ -22,6 +22,9 @@ type ConcurrentMap interface {
 	Delete(key string) bool
 	// Len 会返回当前字典中键 - 元素对的数量
 	Len() uint64
+	// Range 会依次以每个键-元素对调用参数fn，若fn返回false则停止遍历
+	// 遍历基于各散列段的快照，遍历期间的修改不一定会被反映出来
+	Range(fn func(key string, element interface{}) bool)
 }
 
 /**
@@ -113,3 +116,17 @@ func (cmap *myConcurrentMap) Delete(key string) bool {
 func (cmap *myConcurrentMap) Len() uint64 {
 	return atomic.LoadUint64(&cmap.total)
 }
+
+func (cmap *myConcurrentMap) Range(fn func(key string, element interface{}) bool) {
+	if fn == nil {
+		return
+	}
+
+	for _, s := range cmap.segments {
+		for _, p := range s.Pairs() {
+			if !fn(p.Key(), p.Element()) {
+				return
+			}
+		}
+	}
+}
@@ -310,3 +310,53 @@ func TestCmapAllInParallel(t *testing.T) {
 		})
 	})
 }
+
+func TestCmapRange(t *testing.T) {
+	number := 30
+	testCases := genNoRepetitiveTestingPairs(number)
+	cm, _ := NewConcurrentMap(10, nil)
+	for _, p := range testCases {
+		cm.Put(p.Key(), p.Element())
+	}
+
+	visited := map[string]interface{}{}
+	cm.Range(func(key string, element interface{}) bool {
+		visited[key] = element
+		return true
+	})
+
+	if len(visited) != number {
+		t.Fatalf("Inconsistent visited number: expected: %d, actual: %d", number, len(visited))
+	}
+
+	for _, p := range testCases {
+		element, ok := visited[p.Key()]
+		if !ok {
+			t.Fatalf("Not visited key: %s", p.Key())
+		}
+		if element != p.Element() {
+			t.Fatalf("Inconsistent element: expected: %#v, actual: %#v", p.Element(), element)
+		}
+	}
+
+	// 测试提前停止遍历的情况。
+	var count int
+	cm.Range(func(key string, element interface{}) bool {
+		count++
+		return count < 5
+	})
+
+	if count != 5 {
+		t.Fatalf("Inconsistent visited number: expected: %d, actual: %d", 5, count)
+	}
+
+	// 测试遍历期间修改字典的情况。
+	cm.Range(func(key string, element interface{}) bool {
+		cm.Delete(key)
+		return true
+	})
+
+	if cm.Len() != 0 {
+		t.Fatalf("Inconsistent size: expected: %d, actual: %d", 0, cm.Len())
+	}
+}
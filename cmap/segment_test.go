@@ -295,3 +295,28 @@ func TestSegmentAllInParallel(t *testing.T) {
 		})
 	})
 }
+
+func TestSegmentPairs(t *testing.T) {
+	number := 30
+	testCases := genNoRepetitiveTestingPairs(number)
+	s := newSegment(-1, nil)
+	for _, p := range testCases {
+		s.Put(p)
+	}
+
+	pairs := s.Pairs()
+	if len(pairs) != number {
+		t.Fatalf("Inconsistent pair number: expected: %d, actual: %d", number, len(pairs))
+	}
+
+	keys := map[string]struct{}{}
+	for _, p := range pairs {
+		keys[p.Key()] = struct{}{}
+	}
+
+	for _, p := range testCases {
+		if _, ok := keys[p.Key()]; !ok {
+			t.Fatalf("Not found pair with key %q in snapshot!", p.Key())
+		}
+	}
+}
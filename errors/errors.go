@@ -19,6 +19,8 @@ const (
 	ERROR_TYPE_PIPELINE ErrorType = "pipeline error"
 	// ERROR_TYPE_SCHEDULER 代表调度器错误
 	ERROR_TYPE_SCHEDULER ErrorType = "scheduler error"
+	// ERROR_TYPE_RETRY_EXHAUSTED 代表下载重试次数已耗尽的错误
+	ERROR_TYPE_RETRY_EXHAUSTED ErrorType = "retry exhausted error"
 )
 
 // CrawlerError 代表爬虫错误的接口类型
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bufio"
 	"flag"
 	"fmt"
 	"net/http"
@@ -11,7 +12,10 @@ import (
 	lib "crawler/finder/internal"
 	"crawler/finder/monitor"
 	log "crawler/logger"
+	"crawler/module/local/downloader"
 	sched "crawler/scheduler"
+	"crawler/toolkit/dedupe"
+	"crawler/toolkit/publicsuffix"
 )
 
 // 命令参数。
@@ -20,6 +24,40 @@ var (
 	domains  string
 	depth    uint
 	dirPath  string
+	// checkpointPath 代表检查点文件的路径。
+	checkpointPath string
+	// checkpointInterval 代表保存检查点的间隔时间。
+	checkpointInterval time.Duration
+	// hostDelay 代表对同一主机的两次请求之间的最小间隔。
+	hostDelay time.Duration
+	// hostMaxInFlight 代表对同一主机的最大并发请求数。
+	hostMaxInFlight uint
+	// ignoreRobots 代表是否忽略robots.txt的限制。
+	ignoreRobots bool
+	// maxRetries 代表下载失败时最多重试的次数。
+	maxRetries uint
+	// shallowFirst 代表是否优先爬取深度较小的页面。
+	shallowFirst bool
+	// seedsPath 代表种子URL文件的路径。
+	seedsPath string
+	// seedDomains 代表是否接受种子URL的主域名。
+	seedDomains bool
+	// pslPath 代表公共后缀列表文件的路径。
+	pslPath string
+	// stripTracking 代表判断URL是否重复时是否忽略常见的跟踪参数。
+	stripTracking bool
+	// dedupeKind 代表去重器的种类。
+	dedupeKind string
+	// maxPages 代表最多发送的请求的数量。
+	maxPages uint64
+	// maxBytes 代表最多下载的字节数。
+	maxBytes uint64
+	// maxDuration 代表最长爬取时长。
+	maxDuration time.Duration
+	// maxPagesPerDomain 代表每个主域名最多发送的请求的数量。
+	maxPagesPerDomain uint64
+	// sessionsDir 代表保存下载会话的Cookie的目录。
+	sessionsDir string
 )
 
 // 日志记录器。
@@ -36,6 +74,64 @@ func init() {
 
 	flag.StringVar(&dirPath, "dir", "./pictures",
 		"The path which you want to save the image files.")
+
+	flag.StringVar(&checkpointPath, "checkpoint", "",
+		"The path of the checkpoint file. "+
+			"If the file exists, the crawl will be restored from it.")
+
+	flag.DurationVar(&checkpointInterval, "checkpoint-interval", time.Minute,
+		"The interval for saving the checkpoint and the cookies of download sessions.")
+
+	flag.DurationVar(&hostDelay, "host-delay", 0,
+		"The minimum delay between two requests to the same host.")
+
+	flag.UintVar(&hostMaxInFlight, "host-max-in-flight", 0,
+		"The maximum number of concurrent requests to the same host. 0 means no limit.")
+
+	flag.BoolVar(&ignoreRobots, "ignore-robots", false,
+		"Ignore the restrictions of robots.txt.")
+
+	flag.UintVar(&maxRetries, "max-retries", 0,
+		"The maximum number of retries for a failed download. 0 means no retry.")
+
+	flag.BoolVar(&shallowFirst, "shallow-first", false,
+		"Crawl the shallow pages first.")
+
+	flag.StringVar(&seedsPath, "seeds", "",
+		"The path of a file which contains seed URLs, one per line. "+
+			"Blank lines and lines starting with '#' are ignored. "+
+			"If it is set, the first URL will be ignored.")
+
+	flag.BoolVar(&seedDomains, "seed-domains", true,
+		"Accept the primary domains of the seed URLs.")
+
+	flag.StringVar(&pslPath, "psl", "",
+		"The path of an updated public suffix list file. "+
+			"If it is empty, the embedded list will be used.")
+
+	flag.BoolVar(&stripTracking, "strip-tracking", true,
+		"Ignore the common tracking parameters such as utm_* when detecting repeated URLs.")
+
+	flag.StringVar(&dedupeKind, "dedupe", "map",
+		"The kind of the deduper for detecting repeated requests: map, bloom or disk. "+
+			"The bloom and disk dedupers use bounded memory for large crawls.")
+
+	flag.Uint64Var(&maxPages, "max-pages", 0,
+		"The maximum number of pages to crawl. 0 means no limit.")
+
+	flag.Uint64Var(&maxBytes, "max-bytes", 0,
+		"The maximum number of bytes to download. 0 means no limit.")
+
+	flag.DurationVar(&maxDuration, "max-duration", 0,
+		"The maximum duration of the crawl. 0 means no limit.")
+
+	flag.Uint64Var(&maxPagesPerDomain, "max-pages-per-domain", 0,
+		"The maximum number of pages to crawl for each primary domain. 0 means no limit.")
+
+	flag.StringVar(&sessionsDir, "sessions", "",
+		"The directory to load and save the cookies of download sessions. "+
+			"Requests use the \"default\" session unless their metadata selects another one. "+
+			"Empty means cookies are kept in memory only.")
 }
 
 func Usage() {
@@ -48,6 +144,14 @@ func Usage() {
 func main() {
 	flag.Usage = Usage
 	flag.Parse()
+	// 加载更新的公共后缀列表。
+	if pslPath != "" {
+		list, err := publicsuffix.LoadFile(pslPath)
+		if err != nil {
+			logger.Fatalf("加载公共后缀列表时出错: %s", err)
+		}
+		publicsuffix.SetDefault(list)
+	}
 	// 创建调度器。
 	scheduler := sched.NewScheduler()
 	// 准备调度器的初始化参数。
@@ -61,8 +165,30 @@ func main() {
 	}
 
 	requestArgs := sched.RequestArgs{
-		AcceptedDomains: acceptedDomains,
-		MaxDepth:        uint32(depth),
+		AcceptedDomains:   acceptedDomains,
+		MaxDepth:          uint32(depth),
+		HostDelay:         hostDelay,
+		HostMaxInFlight:   uint32(hostMaxInFlight),
+		IgnoreRobots:      ignoreRobots,
+		IgnoreSeedDomains: !seedDomains,
+		Retry: sched.RetryPolicy{
+			MaxAttempts: uint32(maxRetries),
+		},
+		Dedupe: sched.DedupeArgs{
+			Kind: dedupe.Kind(dedupeKind),
+		},
+		Budget: sched.Budget{
+			MaxPages:          maxPages,
+			MaxBytes:          maxBytes,
+			MaxDuration:       maxDuration,
+			MaxPagesPerDomain: maxPagesPerDomain,
+		},
+	}
+	if stripTracking {
+		requestArgs.Canonical.RemoveParams = sched.DefaultTrackingParams
+	}
+	if shallowFirst {
+		requestArgs.Prioritizer = sched.ShallowFirst
 	}
 
 	dataArgs := sched.DataArgs{
@@ -76,7 +202,12 @@ func main() {
 		ErrorMaxBufferNumber: 1,    // 代表错误缓冲器的最大数量
 	}
 
-	downloaders, err := lib.GetDownloaders(1)
+	sessions, err := downloader.NewSessions(sessionsDir)
+	if err != nil {
+		logger.Fatalf("加载下载会话时出错: %s", err)
+	}
+
+	downloaders, err := lib.GetDownloaders(1, sessions)
 	if err != nil {
 		logger.Fatalf("创建下载程序时出错: %s", err)
 	}
@@ -110,18 +241,133 @@ func main() {
 	// 开始监控。
 	checkCountChan := monitor.Monitor(scheduler, checkInterval, summarizeInterval, maxIdleCount, true, lib.Record)
 	// 准备调度器的启动参数。
-	firstHTTPReq, err := http.NewRequest("GET", firstURL, nil)
-	if err != nil {
-		logger.Fatalln(err)
-		return
+	seedURLs := []string{firstURL}
+	if seedsPath != "" {
+		seedURLs, err = readSeeds(seedsPath)
+		if err != nil {
+			logger.Fatalf("读取种子文件时出错: %s", err)
+		}
+	}
+
+	seeds := make([]*http.Request, 0, len(seedURLs))
+	for _, seedURL := range seedURLs {
+		seed, err := http.NewRequest("GET", seedURL, nil)
+		if err != nil {
+			logger.Fatalln(err)
+			return
+		}
+		seeds = append(seeds, seed)
 	}
 
 	// 开启调度器
-	err = scheduler.Start(firstHTTPReq)
+	if checkpointPath != "" && fileExists(checkpointPath) {
+		logger.Infof("从检查点恢复: %s", checkpointPath)
+		err = scheduler.StartFrom(checkpointPath)
+	} else {
+		err = scheduler.StartWithSeeds(seeds)
+	}
+
 	if err != nil {
 		logger.Fatalf("启动计划程序时出错: %s", err)
 	}
 
+	// 定期保存检查点和下载会话，以免爬取中断时丢失登录状态。
+	if checkpointPath != "" {
+		go saveCheckpoints(scheduler, sessions, checkpointPath, checkpointInterval)
+	} else if sessionsDir != "" {
+		go saveSessions(sessions, checkpointInterval)
+	}
+
 	// 等待监控结束。
 	<-checkCountChan
+
+	// 保存下载会话。
+	if err := sessions.Save(); err != nil {
+		logger.Errorf("保存下载会话时出错: %s", err)
+	}
+
+	// 释放调度器占用的资源。
+	if err := scheduler.Close(); err != nil {
+		logger.Errorf("关闭调度器时出错: %s", err)
+	}
+}
+
+// readSeeds 用于从给定的文件中读取种子URL，每行一个。
+// 空行和以'#'开头的行会被忽略。
+func readSeeds(filePath string) ([]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	seedURLs := []string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		seedURLs = append(seedURLs, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(seedURLs) == 0 {
+		return nil, fmt.Errorf("no seed URL in %s", filePath)
+	}
+	return seedURLs, nil
+}
+
+// fileExists 用于判断给定路径的文件是否存在。
+func fileExists(filePath string) bool {
+	_, err := os.Stat(filePath)
+	return err == nil
+}
+
+// saveCheckpoints 会按照给定的间隔时间暂停调度器并保存检查点和下载会话。
+func saveCheckpoints(scheduler sched.Scheduler, sessions downloader.Sessions, filePath string, interval time.Duration) {
+	if interval < time.Second {
+		interval = time.Second
+	}
+
+	for range time.Tick(interval) {
+		if scheduler.Status() != sched.SCHED_STATUS_STARTED {
+			continue
+		}
+
+		if err := scheduler.Pause(); err != nil {
+			logger.Warnf("暂停调度器时出错: %s", err)
+			continue
+		}
+
+		if err := scheduler.Checkpoint(filePath); err != nil {
+			logger.Errorf("保存检查点时出错: %s", err)
+		}
+
+		// 会话与检查点一同保存，从检查点恢复时才能使用当时的Cookie。
+		if err := sessions.Save(); err != nil {
+			logger.Errorf("保存下载会话时出错: %s", err)
+		}
+
+		if err := scheduler.Resume(); err != nil {
+			logger.Errorf("恢复调度器时出错: %s", err)
+			return
+		}
+	}
+}
+
+// saveSessions 会按照给定的间隔时间保存下载会话。
+func saveSessions(sessions downloader.Sessions, interval time.Duration) {
+	if interval < time.Second {
+		interval = time.Second
+	}
+
+	for range time.Tick(interval) {
+		if err := sessions.Save(); err != nil {
+			logger.Errorf("保存下载会话时出错: %s", err)
+		}
+	}
 }
@@ -11,7 +11,8 @@ import (
 var snGen = module.NewSNGenertor(1, 0)
 
 // GetDownloaders 用于获取下载器列表。
-func GetDownloaders(number uint8) ([]module.Downloader, error) {
+// 所有下载器共享给定的下载会话，以便保持登录等状态。
+func GetDownloaders(number uint8, sessions downloader.Sessions) ([]module.Downloader, error) {
 	downloaders := []module.Downloader{}
 	if number == 0 {
 		return downloaders, nil
@@ -23,7 +24,8 @@ func GetDownloaders(number uint8) ([]module.Downloader, error) {
 			return downloaders, err
 		}
 
-		d, err := downloader.New(mid, genHTTPClient(), module.CalculateScoreSimple)
+		options := downloader.Options{Sessions: sessions}
+		d, err := downloader.NewWithOptions(mid, genHTTPClient(), options, module.CalculateScoreSimple)
 		if err != nil {
 			return downloaders, err
 		}
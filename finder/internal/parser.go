@@ -90,6 +90,7 @@ func genResponseParsers() []module.ParseResponse {
 				errs = append(errs, err)
 			} else {
 				req := module.NewRequest(httpReq, respDepth)
+				req.SetSource(module.SOURCE_LINK)
 				dataList = append(dataList, req)
 			}
 
@@ -119,6 +120,7 @@ func genResponseParsers() []module.ParseResponse {
 				errs = append(errs, err)
 			} else {
 				req := module.NewRequest(httpReq, respDepth)
+				req.SetSource(module.SOURCE_IMG)
 				dataList = append(dataList, req)
 			}
 		})
@@ -32,6 +32,9 @@ var msgReachMaxIdleCount = "The scheduler has been idle for a period of time" +
 // msgStopScheduler 代表停止调度器的消息模板。
 var msgStopScheduler = "Stop scheduler...%s."
 
+// msgSchedulerStopped 代表调度器已停止的消息模板。
+var msgSchedulerStopped = "The scheduler has been stopped (reason: %s)."
+
 // Record 代表日志记录函数的类型。
 // 参数level代表日志级别。级别设定：0-普通；1-警告；2-错误。
 type Record func(level uint8, content string)
@@ -98,6 +101,20 @@ func checkStatus(scheduler sched.Scheduler, checkInterval time.Duration, maxIdle
 		var idleCount uint
 		var firstIdleTime time.Time
 		for {
+			// 调度器可能因爬取预算用尽而自动停止。
+			if scheduler.Status() == sched.SCHED_STATUS_STOPPED {
+				record(0, fmt.Sprintf(msgSchedulerStopped, scheduler.StopReason()))
+				break
+			}
+
+			// 调度器暂停期间不累计空闲计数。
+			if scheduler.Status() == sched.SCHED_STATUS_PAUSED {
+				idleCount = 0
+				checkCount++
+				time.Sleep(checkInterval)
+				continue
+			}
+
 			// 检查调度器的空闲状态。
 			if scheduler.Idle() {
 				idleCount++
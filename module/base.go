@@ -22,6 +22,25 @@ type SummaryStruct struct {
 	Completed uint64      `json:"completed"`
 	Handling  uint64      `json:"handling"`
 	Extra     interface{} `json:"extra,omitempty"`
+	// Health 代表组件的健康状态，组件自身的摘要中为nil，由调度器从组件注册器获取后填充
+	Health *HealthSummaryStruct `json:"health,omitempty"`
+}
+
+// Same 用于判断两份组件摘要是否相同
+func (one *SummaryStruct) Same(another SummaryStruct) bool {
+	if another.ID != one.ID ||
+		another.Called != one.Called ||
+		another.Accepted != one.Accepted ||
+		another.Completed != one.Completed ||
+		another.Handling != one.Handling ||
+		another.Extra != one.Extra {
+		return false
+	}
+
+	if another.Health == nil || one.Health == nil {
+		return another.Health == one.Health
+	}
+	return *another.Health == *one.Health
 }
 
 // ParseResponse 代笔用于解析HTTP响应的函数的类型
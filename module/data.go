@@ -1,6 +1,14 @@
 package module
 
-import "net/http"
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+)
 
 // Data 代表数据的接口类型
 type Data interface {
@@ -15,11 +23,121 @@ type Request struct {
 	// depth 代表请求的深度
 	// 一个请求的深度值等于对它的父请求的深度值递增一次后的结果
 	depth uint32
+	// attempt 代表请求已被重试的次数，首次下载时为0
+	attempt uint32
+	// notBefore 代表请求可被下载的最早时间
+	notBefore time.Time
+	// redirects 代表得到该请求之前经过的重定向的次数，不是由重定向产生的请求为0
+	redirects uint32
+	// handOffRedirects 代表是否把所有重定向都交给调度器，而不由下载器直接跟随
+	handOffRedirects bool
+	// priority 代表请求的优先级，值越大越先被下载
+	priority int
+	// body 代表请求体的内容，为nil时代表没有请求体
+	// 请求被重试或从检查点恢复时会用它重新生成请求体
+	body []byte
+	// meta 代表请求的元数据
+	meta Meta
+	// parent 代表发现该请求的父请求，为nil时代表未知或没有父请求
+	parent *Request
+	// source 代表请求的发现来源
+	source Source
+	// createdAt 代表请求的创建时间
+	createdAt time.Time
 }
 
 // NewRequest 用于创建一个新的请求实例
+// 若HTTP请求有请求体，则其内容会被读出并保存，以便请求可以被多次发送
 func NewRequest(httpReq *http.Request, depth uint32) *Request {
-	return &Request{httpReq: httpReq, depth: depth}
+	return &Request{
+		httpReq:   httpReq,
+		depth:     depth,
+		body:      readBody(httpReq),
+		createdAt: time.Now(),
+	}
+}
+
+// NewChildRequest 用于根据给定的请求创建一个作为parent的子请求的请求实例
+// 新请求的深度为depth，并会继承父请求的元数据，其中与给定请求的元数据重名的键以后者为准
+func NewChildRequest(parent *Request, req *Request, depth uint32) *Request {
+	child := *req
+	child.depth = depth
+	child.attempt = 0
+	child.notBefore = time.Time{}
+	if parent != nil {
+		child.parent = parent
+		meta := parent.meta.Copy()
+		if meta == nil && len(req.meta) > 0 {
+			meta = Meta{}
+		}
+		for key, value := range req.meta {
+			meta[key] = value
+		}
+		child.meta = meta
+	}
+	return &child
+}
+
+// readBody 用于读出HTTP请求的请求体的内容，并把请求体替换为可重复读取的形式
+func readBody(httpReq *http.Request) []byte {
+	if httpReq == nil || httpReq.Body == nil || httpReq.Body == http.NoBody {
+		return nil
+	}
+
+	var body io.ReadCloser
+	if httpReq.GetBody != nil {
+		// 请求体可以重新获取，不必消耗原有的请求体。
+		rc, err := httpReq.GetBody()
+		if err != nil {
+			return nil
+		}
+		body = rc
+	} else {
+		body = httpReq.Body
+	}
+	defer body.Close()
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil
+	}
+	setBody(httpReq, b)
+	return b
+}
+
+// setBody 用于把HTTP请求的请求体设置为给定的内容
+func setBody(httpReq *http.Request, b []byte) {
+	httpReq.ContentLength = int64(len(b))
+	httpReq.Body = ioutil.NopCloser(bytes.NewReader(b))
+	httpReq.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(b)), nil
+	}
+}
+
+// NewRetryRequest 用于根据给定的请求创建一个用于重试的请求实例
+// 新请求的重试次数会递增一次，并且不会早于notBefore被下载
+func NewRetryRequest(req *Request, notBefore time.Time) *Request {
+	return &Request{
+		httpReq:          req.httpReq,
+		depth:            req.depth,
+		attempt:          req.attempt + 1,
+		notBefore:        notBefore,
+		redirects:        req.redirects,
+		handOffRedirects: req.handOffRedirects,
+		priority:         req.priority,
+		body:             req.body,
+		meta:             req.meta,
+		parent:           req.parent,
+		source:           req.source,
+		createdAt:        req.createdAt,
+	}
+}
+
+// SetRetryState 用于设置请求已被重试的次数和可被下载的最早时间
+// 应该只在从检查点恢复请求时、且在请求被放入请求缓冲池之前调用
+func (req *Request) SetRetryState(attempt uint32, notBefore time.Time) {
+	req.attempt = attempt
+	req.notBefore = notBefore
 }
 
 // HTTPReq 用于获取HTTP请求
@@ -27,11 +145,116 @@ func (req *Request) HTTPReq() *http.Request {
 	return req.httpReq
 }
 
+// HTTPReqToSend 用于获取可被发送的HTTP请求
+// 若请求有请求体，则返回一个带有新的请求体的副本，否则返回HTTP请求本身
+func (req *Request) HTTPReqToSend() *http.Request {
+	if req.httpReq == nil || req.body == nil {
+		return req.httpReq
+	}
+
+	httpReq := req.httpReq.Clone(req.httpReq.Context())
+	setBody(httpReq, req.body)
+	return httpReq
+}
+
+// Body 用于获取请求体的内容，调用方不应修改它
+func (req *Request) Body() []byte {
+	return req.body
+}
+
 // Depth 用于获取请求的深度
 func (req *Request) Depth() uint32 {
 	return req.depth
 }
 
+// Attempt 用于获取请求已被重试的次数
+func (req *Request) Attempt() uint32 {
+	return req.attempt
+}
+
+// NotBefore 用于获取请求可被下载的最早时间
+func (req *Request) NotBefore() time.Time {
+	return req.notBefore
+}
+
+// Redirects 用于获取得到该请求之前经过的重定向的次数
+func (req *Request) Redirects() uint32 {
+	return req.redirects
+}
+
+// SetRedirects 用于设置得到该请求之前经过的重定向的次数
+// 应该在请求被放入请求缓冲池之前调用
+func (req *Request) SetRedirects(redirects uint32) {
+	req.redirects = redirects
+}
+
+// HandOffRedirects 用于判断是否把所有重定向都交给调度器，而不由下载器直接跟随
+func (req *Request) HandOffRedirects() bool {
+	return req.handOffRedirects
+}
+
+// SetHandOffRedirects 用于设置是否把所有重定向都交给调度器，而不由下载器直接跟随
+// 这样每一次重定向都会经过调度器的过滤和去重，应该在请求被放入请求缓冲池之前调用
+func (req *Request) SetHandOffRedirects(handOff bool) {
+	req.handOffRedirects = handOff
+}
+
+// Priority 用于获取请求的优先级
+func (req *Request) Priority() int {
+	return req.priority
+}
+
+// SetPriority 用于设置请求的优先级
+// 应该在请求被放入请求缓冲池之前调用
+func (req *Request) SetPriority(priority int) {
+	req.priority = priority
+}
+
+// Meta 用于获取请求的元数据，调用方不应修改它
+func (req *Request) Meta() Meta {
+	return req.meta
+}
+
+// SetMeta 用于设置请求的元数据中指定键的值
+// 应该在请求被放入请求缓冲池之前调用
+func (req *Request) SetMeta(key string, value interface{}) {
+	meta := req.meta.Copy()
+	if meta == nil {
+		meta = Meta{}
+	}
+	meta[key] = value
+	req.meta = meta
+}
+
+// Parent 用于获取发现该请求的父请求
+func (req *Request) Parent() *Request {
+	return req.parent
+}
+
+// Referrer 用于获取父请求的URL，没有父请求时返回空字符串
+func (req *Request) Referrer() string {
+	if req.parent == nil || !req.parent.Valid() {
+		return ""
+	}
+	return req.parent.httpReq.URL.String()
+}
+
+// Source 用于获取请求的发现来源
+func (req *Request) Source() Source {
+	return req.source
+}
+
+// SetSource 用于设置请求的发现来源
+// 应该在请求被放入请求缓冲池之前调用
+func (req *Request) SetSource(source Source) {
+	req.source = source
+}
+
+// CreatedAt 用于获取请求的创建时间
+func (req *Request) CreatedAt() time.Time {
+	return req.createdAt
+}
+
 // Valid 用于判断请求是否有效
 func (req *Request) Valid() bool {
 	return req.httpReq != nil && req.httpReq.URL != nil
@@ -43,6 +266,16 @@ type Response struct {
 	httResp *http.Response
 	// depth 代表响应的深度
 	depth uint32
+	// req 代表响应对应的请求，为nil时代表未知
+	req *Request
+	// fetchInfo 代表响应的下载信息
+	fetchInfo FetchInfo
+	// bytes 代表已从响应体中读出的字节数，只在调用TrackBody之后统计
+	bytes uint64
+	// redirectReq 代表下载器未跟随而交给调度器的重定向请求
+	redirectReq *Request
+	// cached 代表响应是否来自下载器的缓存
+	cached bool
 }
 
 // NewResponse 用于创建一个新的响应实例
@@ -50,6 +283,12 @@ func NewResponse(httResp *http.Response, depth uint32) *Response {
 	return &Response{httResp: httResp, depth: depth}
 }
 
+// NewResponseForRequest 用于根据给定的请求创建一个新的响应实例
+// 响应的深度与请求的深度相同
+func NewResponseForRequest(httResp *http.Response, req *Request) *Response {
+	return &Response{httResp: httResp, depth: req.Depth(), req: req}
+}
+
 // HTTPResp 用于获取HTTP响应
 func (resp *Response) HTTPResp() *http.Response {
 	return resp.httResp
@@ -60,6 +299,65 @@ func (resp *Response) Depth() uint32 {
 	return resp.depth
 }
 
+// Request 用于获取响应对应的请求，未知时返回nil
+func (resp *Response) Request() *Request {
+	return resp.req
+}
+
+// FetchInfo 用于获取响应的下载信息
+func (resp *Response) FetchInfo() FetchInfo {
+	return resp.fetchInfo
+}
+
+// SetFetchInfo 用于设置响应的下载信息，应该由下载器在返回响应之前调用
+func (resp *Response) SetFetchInfo(info FetchInfo) {
+	resp.fetchInfo = info
+}
+
+// RedirectRequest 用于获取下载器未跟随的重定向请求，为nil时代表没有
+// 若结果值不为nil，则说明响应本身是一个重定向响应，不应被解析，而应把该请求交给调度器
+func (resp *Response) RedirectRequest() *Request {
+	return resp.redirectReq
+}
+
+// SetRedirectRequest 用于设置下载器未跟随的重定向请求，应该由下载器在返回响应之前调用
+func (resp *Response) SetRedirectRequest(req *Request) {
+	resp.redirectReq = req
+}
+
+// Cached 用于判断响应是否来自下载器的缓存
+// 缓存仍然新鲜或经重新验证（304 Not Modified）后由缓存提供的响应都属于此类
+func (resp *Response) Cached() bool {
+	return resp.cached
+}
+
+// SetCached 用于设置响应是否来自下载器的缓存，应该由下载器在返回响应之前调用
+func (resp *Response) SetCached(cached bool) {
+	resp.cached = cached
+}
+
+// FinalURL 用于获取经过重定向之后的最终的URL，未知时返回nil
+func (resp *Response) FinalURL() *url.URL {
+	if resp.httResp == nil || resp.httResp.Request == nil {
+		return nil
+	}
+	return resp.httResp.Request.URL
+}
+
+// TrackBody 用于包装HTTP响应的响应体，以便统计从中读出的字节数
+// 应该由下载器在返回响应之前调用
+func (resp *Response) TrackBody() {
+	if resp.httResp == nil || resp.httResp.Body == nil {
+		return
+	}
+	resp.httResp.Body = &trackingBody{ReadCloser: resp.httResp.Body, bytes: &resp.bytes}
+}
+
+// BytesRead 用于获取已从响应体中读出的字节数
+func (resp *Response) BytesRead() uint64 {
+	return atomic.LoadUint64(&resp.bytes)
+}
+
 // Valid 用于判断响应是否有效
 func (resp *Response) Valid() bool {
 	return resp.httResp != nil && resp.httResp.Body != nil
@@ -0,0 +1,135 @@
+package module
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewRetryRequest(t *testing.T) {
+	httpReq, err := http.NewRequest("GET", "http://cn.bing.com/search?q=golang", nil)
+	if err != nil {
+		t.Fatalf("An error occurs when creating a HTTP request: %s", err)
+	}
+
+	req := NewRequest(httpReq, 2)
+	if req.Attempt() != 0 || !req.NotBefore().IsZero() {
+		t.Fatalf("Inconsistent retry state of new request: attempt: %d, not before: %s",
+			req.Attempt(), req.NotBefore())
+	}
+
+	req.SetPriority(3)
+	notBefore := time.Now().Add(time.Second)
+	retryReq := NewRetryRequest(NewRetryRequest(req, time.Now()), notBefore)
+	if retryReq.HTTPReq() != httpReq || retryReq.Depth() != 2 {
+		t.Fatalf("The retry request should keep the HTTP request and depth!")
+	}
+
+	if retryReq.Priority() != 3 {
+		t.Fatalf("Inconsistent priority: expected: %d, actual: %d", 3, retryReq.Priority())
+	}
+
+	if retryReq.Attempt() != 2 {
+		t.Fatalf("Inconsistent attempt: expected: %d, actual: %d", 2, retryReq.Attempt())
+	}
+
+	if !retryReq.NotBefore().Equal(notBefore) {
+		t.Fatalf("Inconsistent not before time: expected: %s, actual: %s", notBefore, retryReq.NotBefore())
+	}
+}
+
+func TestRequestBody(t *testing.T) {
+	httpReq, err := http.NewRequest("POST", "http://cn.bing.com/search", strings.NewReader("q=golang"))
+	if err != nil {
+		t.Fatalf("An error occurs when creating a HTTP request: %s", err)
+	}
+
+	req := NewRequest(httpReq, 0)
+	if string(req.Body()) != "q=golang" {
+		t.Fatalf("Inconsistent request body: expected: %q, actual: %q", "q=golang", req.Body())
+	}
+
+	// 请求体在每次发送前都可以被完整地读出，重试的请求也是如此。
+	retryReq := NewRetryRequest(req, time.Now())
+	for i, r := range []*Request{req, req, retryReq} {
+		sendReq := r.HTTPReqToSend()
+		b, err := ioutil.ReadAll(sendReq.Body)
+		if err != nil {
+			t.Fatalf("An error occurs when reading request body: %s", err)
+		}
+		if string(b) != "q=golang" || sendReq.ContentLength != int64(len(b)) {
+			t.Fatalf("Inconsistent request body (%d): expected: %q, actual: %q (content length: %d)",
+				i, "q=golang", b, sendReq.ContentLength)
+		}
+	}
+
+	// 没有可重新获取的请求体时，请求体会被读出并替换。
+	httpReq, _ = http.NewRequest("PUT", "http://cn.bing.com/", nil)
+	httpReq.Body = ioutil.NopCloser(strings.NewReader("data"))
+	req = NewRequest(httpReq, 0)
+	if string(req.Body()) != "data" {
+		t.Fatalf("Inconsistent request body: expected: %q, actual: %q", "data", req.Body())
+	}
+	if b, _ := ioutil.ReadAll(req.HTTPReq().Body); string(b) != "data" {
+		t.Fatalf("The original request body should be restored: %q", b)
+	}
+
+	httpReq, _ = http.NewRequest("GET", "http://cn.bing.com/", nil)
+	req = NewRequest(httpReq, 0)
+	if req.Body() != nil || req.HTTPReqToSend() != httpReq {
+		t.Fatal("The request without body should be sent as it is!")
+	}
+}
+
+func TestRequestMeta(t *testing.T) {
+	httpReq, _ := http.NewRequest("GET", "http://cn.bing.com/", nil)
+	parent := NewRequest(httpReq, 0)
+	if parent.CreatedAt().IsZero() {
+		t.Fatal("The creation time of request should be set!")
+	}
+	if parent.Parent() != nil || parent.Referrer() != "" || parent.Meta() != nil {
+		t.Fatal("There should be no parent or meta of new request!")
+	}
+
+	parent.SetSource(SOURCE_SEED)
+	parent.SetMeta("session", "alice")
+	parent.SetMeta("page", 1)
+
+	httpReq, _ = http.NewRequest("GET", "http://cn.bing.com/search", nil)
+	req := NewRequest(httpReq, 5)
+	req.SetSource(SOURCE_LINK)
+	req.SetMeta("page", 2)
+	child := NewChildRequest(parent, NewRetryRequest(req, time.Now().Add(time.Hour)), 1)
+	if child.Parent() != parent || child.Referrer() != "http://cn.bing.com/" {
+		t.Fatalf("Inconsistent parent: expected: %p, actual: %p", parent, child.Parent())
+	}
+	if child.Depth() != 1 || child.Attempt() != 0 || !child.NotBefore().IsZero() {
+		t.Fatalf("Inconsistent child request: depth: %d, attempt: %d, not before: %s",
+			child.Depth(), child.Attempt(), child.NotBefore())
+	}
+	if child.Source() != SOURCE_LINK || !child.CreatedAt().Equal(req.CreatedAt()) {
+		t.Fatalf("Inconsistent source: expected: %s, actual: %s", SOURCE_LINK, child.Source())
+	}
+	session, _ := child.Meta().GetString("session")
+	page, _ := child.Meta().GetInt("page")
+	if session != "alice" || page != 2 {
+		t.Fatalf("Inconsistent meta: %v", child.Meta())
+	}
+
+	// 设置元数据不会影响共享元数据的其他请求。
+	child.SetMeta("session", "bob")
+	if session, _ := parent.Meta().GetString("session"); session != "alice" {
+		t.Fatalf("Inconsistent meta of parent: %v", parent.Meta())
+	}
+	retryReq := NewRetryRequest(child, time.Now())
+	if retryReq.Parent() != parent || retryReq.Source() != SOURCE_LINK || retryReq.Meta()["session"] != "bob" {
+		t.Fatal("The retry request should keep the parent, source and meta!")
+	}
+
+	resp := NewResponseForRequest(&http.Response{}, child)
+	if resp.Request() != child || resp.Depth() != child.Depth() {
+		t.Fatalf("Inconsistent response: request: %p, depth: %d", resp.Request(), resp.Depth())
+	}
+}
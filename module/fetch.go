@@ -0,0 +1,64 @@
+package module
+
+import (
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// Timing 代表下载一个响应的各阶段的耗时，未经历的阶段（如复用连接时的DNS查询和建立连接）为0
+// 发生重定向时，DNS查询、建立连接和TLS握手的耗时为各次请求的总和
+type Timing struct {
+	// DNS 代表DNS查询的耗时
+	DNS time.Duration `json:"dns"`
+	// Connect 代表建立TCP连接的耗时
+	Connect time.Duration `json:"connect"`
+	// TLS 代表TLS握手的耗时
+	TLS time.Duration `json:"tls"`
+	// TTFB 代表从开始下载到收到最终响应的第一个字节的耗时
+	TTFB time.Duration `json:"ttfb"`
+	// Total 代表从开始下载到收到最终响应的响应头的耗时，不包括读取响应体的时间
+	Total time.Duration `json:"total"`
+}
+
+// FetchInfo 代表响应的下载信息
+type FetchInfo struct {
+	// Downloader 代表下载该响应的下载器的ID
+	Downloader MID `json:"downloader"`
+	// Timing 代表下载的各阶段的耗时
+	Timing Timing `json:"timing"`
+	// Redirects 代表按顺序经过的重定向前的URL，不包括最终的URL
+	Redirects []string `json:"redirects,omitempty"`
+}
+
+// RedirectChain 用于从HTTP响应中还原按顺序经过的重定向前的URL
+func RedirectChain(httpResp *http.Response) []string {
+	if httpResp == nil || httpResp.Request == nil {
+		return nil
+	}
+
+	var chain []string
+	for r := httpResp.Request; r.Response != nil && r.Response.Request != nil; r = r.Response.Request {
+		chain = append(chain, r.Response.Request.URL.String())
+	}
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain
+}
+
+// trackingBody 代表会统计读出的字节数的响应体
+type trackingBody struct {
+	io.ReadCloser
+	// bytes 代表已读出的字节数
+	bytes *uint64
+}
+
+func (body *trackingBody) Read(p []byte) (int, error) {
+	n, err := body.ReadCloser.Read(p)
+	if n > 0 {
+		atomic.AddUint64(body.bytes, uint64(n))
+	}
+	return n, err
+}
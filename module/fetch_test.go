@@ -0,0 +1,53 @@
+package module
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRedirectChain(t *testing.T) {
+	if chain := RedirectChain(nil); chain != nil {
+		t.Fatalf("Inconsistent redirect chain: expected: %v, actual: %v", nil, chain)
+	}
+
+	// 模拟两次重定向：a -> b -> c。
+	var last *http.Request
+	for _, rawURL := range []string{"http://a.com/", "http://b.com/", "http://c.com/"} {
+		u, _ := url.Parse(rawURL)
+		r := &http.Request{URL: u}
+		if last != nil {
+			r.Response = &http.Response{StatusCode: http.StatusFound, Request: last}
+		}
+		last = r
+	}
+	chain := RedirectChain(&http.Response{Request: last})
+	if strings.Join(chain, " ") != "http://a.com/ http://b.com/" {
+		t.Fatalf("Inconsistent redirect chain: expected: %v, actual: %v",
+			[]string{"http://a.com/", "http://b.com/"}, chain)
+	}
+
+	resp := NewResponse(&http.Response{Request: last}, 0)
+	if finalURL := resp.FinalURL(); finalURL.String() != "http://c.com/" {
+		t.Fatalf("Inconsistent final URL: expected: %s, actual: %s", "http://c.com/", finalURL)
+	}
+}
+
+func TestResponseTrackBody(t *testing.T) {
+	resp := NewResponse(&http.Response{Body: ioutil.NopCloser(strings.NewReader("hello world"))}, 0)
+	resp.TrackBody()
+	b := make([]byte, 5)
+	if n, _ := resp.HTTPResp().Body.Read(b); resp.BytesRead() != uint64(n) {
+		t.Fatalf("Inconsistent bytes read: expected: %d, actual: %d", n, resp.BytesRead())
+	}
+	ioutil.ReadAll(resp.HTTPResp().Body)
+	if resp.BytesRead() != 11 {
+		t.Fatalf("Inconsistent bytes read: expected: %d, actual: %d", 11, resp.BytesRead())
+	}
+
+	// 没有响应体时不做任何事。
+	NewResponse(&http.Response{}, 0).TrackBody()
+	NewResponse(nil, 0).TrackBody()
+}
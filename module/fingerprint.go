@@ -0,0 +1,81 @@
+package module
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Fingerprint 用于计算请求的指纹。
+// 指纹由请求方法、URL、请求体的摘要和指定的请求头共同决定，
+// 只有指纹相同的请求才会被视为重复的请求。
+// 参数canonicalURL代表请求URL的规范形式，为空时使用请求URL本身。
+// 参数headers代表需要计入指纹的请求头的名称，名称不区分大小写且与顺序无关。
+func Fingerprint(req *Request, canonicalURL string, headers ...string) string {
+	if req == nil || req.httpReq == nil {
+		return ""
+	}
+
+	httpReq := req.httpReq
+	method := strings.ToUpper(httpReq.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	if canonicalURL == "" && httpReq.URL != nil {
+		canonicalURL = httpReq.URL.String()
+	}
+
+	h := sha1.New()
+	writeField(h, method)
+	writeField(h, canonicalURL)
+	if len(req.body) > 0 {
+		sum := sha256.Sum256(req.body)
+		writeField(h, hex.EncodeToString(sum[:]))
+	} else {
+		writeField(h, "")
+	}
+
+	for _, name := range canonicalHeaderNames(headers) {
+		values := httpReq.Header[name]
+		if len(values) == 0 {
+			continue
+		}
+		writeField(h, name)
+		writeField(h, strings.Join(values, ","))
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// writeField 用于向摘要写入带长度前缀的字段，以免相邻字段的内容产生歧义。
+func writeField(w io.Writer, field string) {
+	io.WriteString(w, strconv.Itoa(len(field)))
+	io.WriteString(w, ":")
+	io.WriteString(w, field)
+}
+
+// canonicalHeaderNames 用于获取规范化、去重并排序后的请求头名称。
+func canonicalHeaderNames(headers []string) []string {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(headers))
+	seen := make(map[string]bool, len(headers))
+	for _, header := range headers {
+		name := http.CanonicalHeaderKey(strings.TrimSpace(header))
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,67 @@
+package module
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	newReq := func(method, url, body string, header http.Header) *Request {
+		var httpReq *http.Request
+		var err error
+		if body == "" {
+			httpReq, err = http.NewRequest(method, url, nil)
+		} else {
+			httpReq, err = http.NewRequest(method, url, strings.NewReader(body))
+		}
+		if err != nil {
+			t.Fatalf("An error occurs when creating a HTTP request: %s", err)
+		}
+		for key, values := range header {
+			httpReq.Header[key] = values
+		}
+		return NewRequest(httpReq, 0)
+	}
+
+	url := "http://cn.bing.com/search"
+	get := Fingerprint(newReq("GET", url, "", nil), "")
+	if get == "" {
+		t.Fatal("Empty fingerprint!")
+	}
+	if fp := Fingerprint(newReq("get", url, "", nil), ""); fp != get {
+		t.Fatalf("The method should be case-insensitive: %s != %s", fp, get)
+	}
+
+	post := Fingerprint(newReq("POST", url, "q=golang", nil), "")
+	if post == get {
+		t.Fatal("The fingerprints of GET and POST requests should be different!")
+	}
+	if fp := Fingerprint(newReq("POST", url, "q=gopher", nil), ""); fp == post {
+		t.Fatal("The fingerprints of requests with different bodies should be different!")
+	}
+	if fp := Fingerprint(newReq("POST", url, "q=golang", nil), ""); fp != post {
+		t.Fatalf("Inconsistent fingerprint for the same request: %s != %s", fp, post)
+	}
+
+	// 规范化后的URL相同时，指纹也相同。
+	if fp := Fingerprint(newReq("GET", "HTTP://CN.BING.COM/search", "", nil), url); fp != get {
+		t.Fatalf("The canonical URL should be used: %s != %s", fp, get)
+	}
+
+	// 请求头只有在被指定时才会计入指纹。
+	langReq := newReq("GET", url, "", http.Header{"Accept-Language": {"zh-CN"}})
+	if fp := Fingerprint(langReq, ""); fp != get {
+		t.Fatalf("The headers should not be counted by default: %s != %s", fp, get)
+	}
+	if fp := Fingerprint(langReq, "", "accept-language", "Cookie"); fp == get {
+		t.Fatal("The specified header should be counted!")
+	}
+	if fp := Fingerprint(newReq("GET", url, "", nil), "", "Accept-Language"); fp != get {
+		t.Fatalf("The missing header should not be counted: %s != %s", fp, get)
+	}
+
+	if fp := Fingerprint(nil, ""); fp != "" {
+		t.Fatalf("Inconsistent fingerprint for nil request: expected: %q, actual: %q", "", fp)
+	}
+}
@@ -0,0 +1,228 @@
+package module
+
+import (
+	"crawler/errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// BreakerState 代表组件熔断器的状态
+type BreakerState string
+
+const (
+	// BREAKER_STATE_CLOSED 代表熔断器闭合，组件可以正常被获取
+	BREAKER_STATE_CLOSED BreakerState = "closed"
+	// BREAKER_STATE_OPEN 代表熔断器打开，组件暂时不会被获取
+	BREAKER_STATE_OPEN BreakerState = "open"
+	// BREAKER_STATE_HALF_OPEN 代表熔断器半开，组件只能被获取一次以试探其是否已恢复
+	BREAKER_STATE_HALF_OPEN BreakerState = "half_open"
+)
+
+// 健康检查参数的默认值
+const (
+	// defaultFailureThreshold 代表打开熔断器所需的默认连续失败次数
+	defaultFailureThreshold = 5
+	// defaultWindowSize 代表用于计算错误率的默认调用窗口大小
+	defaultWindowSize = 20
+	// defaultMaxErrorRate 代表默认的错误率上限
+	defaultMaxErrorRate = 0.5
+	// defaultOpenTimeout 代表熔断器打开后转为半开的默认时长
+	defaultOpenTimeout = 30 * time.Second
+)
+
+// HealthArgs 代表组件健康检查的参数，为0的字段会使用默认值
+type HealthArgs struct {
+	// Disabled 代表是否禁用健康检查，禁用后组件的熔断器始终闭合
+	Disabled bool `json:"disabled"`
+	// FailureThreshold 代表打开熔断器所需的连续失败次数，为0时使用5
+	FailureThreshold uint32 `json:"failure_threshold"`
+	// WindowSize 代表用于计算错误率的最近调用的数量，为0时使用20
+	WindowSize uint32 `json:"window_size"`
+	// MaxErrorRate 代表错误率的上限，窗口已满且错误率达到上限时会打开熔断器，为0时使用0.5
+	MaxErrorRate float64 `json:"max_error_rate"`
+	// OpenTimeout 代表熔断器打开后转为半开的时长，为0时使用30秒
+	OpenTimeout time.Duration `json:"open_timeout"`
+}
+
+// Check 用于检查健康检查参数的有效性
+func (args HealthArgs) Check() error {
+	if args.MaxErrorRate < 0 || args.MaxErrorRate > 1 {
+		return errors.NewIllegalParameterError(fmt.Sprintf("非法错误率上限: %v", args.MaxErrorRate))
+	}
+
+	if args.OpenTimeout < 0 {
+		return errors.NewIllegalParameterError(fmt.Sprintf("负的熔断时长: %s", args.OpenTimeout))
+	}
+	return nil
+}
+
+// withDefaults 用于获取以默认值填充了为0的字段的参数
+func (args HealthArgs) withDefaults() HealthArgs {
+	if args.FailureThreshold == 0 {
+		args.FailureThreshold = defaultFailureThreshold
+	}
+	if args.WindowSize == 0 {
+		args.WindowSize = defaultWindowSize
+	}
+	if args.MaxErrorRate == 0 {
+		args.MaxErrorRate = defaultMaxErrorRate
+	}
+	if args.OpenTimeout == 0 {
+		args.OpenTimeout = defaultOpenTimeout
+	}
+	return args
+}
+
+// HealthSummaryStruct 代表组件健康状态的摘要类型
+type HealthSummaryStruct struct {
+	// State 代表熔断器的状态
+	State BreakerState `json:"state"`
+	// ConsecutiveFailures 代表连续失败的次数
+	ConsecutiveFailures uint32 `json:"consecutive_failures"`
+	// ErrorRate 代表最近调用的错误率
+	ErrorRate float64 `json:"error_rate"`
+	// Successes 代表成功的调用的总数
+	Successes uint64 `json:"successes"`
+	// Failures 代表失败的调用的总数
+	Failures uint64 `json:"failures"`
+}
+
+// health 代表单个组件的健康状态
+type health struct {
+	// args 代表健康检查的参数
+	args HealthArgs
+	// state 代表熔断器的状态
+	state BreakerState
+	// openedAt 代表熔断器最近一次打开的时间
+	openedAt time.Time
+	// probeAt 代表半开状态下最近一次试探的时间，为零值时代表没有进行中的试探
+	probeAt time.Time
+	// consecutiveFailures 代表连续失败的次数
+	consecutiveFailures uint32
+	// window 代表最近调用的结果的环形缓冲，true代表失败
+	window []bool
+	// next 代表环形缓冲中下一个写入的位置
+	next int
+	// filled 代表环形缓冲中已写入的结果的数量
+	filled int
+	// windowFailures 代表环形缓冲中失败的数量
+	windowFailures int
+	// successes 代表成功的调用的总数
+	successes uint64
+	// failures 代表失败的调用的总数
+	failures uint64
+	// lock 代表互斥锁
+	lock sync.Mutex
+}
+
+// newHealth 用于创建组件的健康状态，参数args应已填充了默认值
+func newHealth(args HealthArgs) *health {
+	return &health{
+		args:   args,
+		state:  BREAKER_STATE_CLOSED,
+		window: make([]bool, args.WindowSize),
+	}
+}
+
+// available 用于判断组件当前是否可以被获取
+func (h *health) available(now time.Time) bool {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return h.availableLocked(now)
+}
+
+// availableLocked 用于判断组件当前是否可以被获取，调用方需持有锁
+func (h *health) availableLocked(now time.Time) bool {
+	switch h.state {
+	case BREAKER_STATE_OPEN:
+		return !now.Before(h.openedAt.Add(h.args.OpenTimeout))
+	case BREAKER_STATE_HALF_OPEN:
+		// 试探的结果迟迟未报告时，允许再次试探。
+		return h.probeAt.IsZero() || !now.Before(h.probeAt.Add(h.args.OpenTimeout))
+	}
+	return true
+}
+
+// acquire 用于在组件被获取时更新熔断器的状态
+// 若结果值为false，则说明组件已不可被获取
+func (h *health) acquire(now time.Time) bool {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if !h.availableLocked(now) {
+		return false
+	}
+	if h.state != BREAKER_STATE_CLOSED {
+		h.state = BREAKER_STATE_HALF_OPEN
+		h.probeAt = now
+	}
+	return true
+}
+
+// report 用于记录一次调用的结果
+func (h *health) report(success bool, now time.Time) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.window[h.next] {
+		h.windowFailures--
+	}
+	h.window[h.next] = !success
+	h.next = (h.next + 1) % len(h.window)
+	if h.filled < len(h.window) {
+		h.filled++
+	}
+
+	if success {
+		h.successes++
+		h.consecutiveFailures = 0
+		if h.state == BREAKER_STATE_HALF_OPEN {
+			h.close()
+		}
+		return
+	}
+
+	h.failures++
+	h.windowFailures++
+	h.consecutiveFailures++
+	switch {
+	case h.state == BREAKER_STATE_HALF_OPEN,
+		h.consecutiveFailures >= h.args.FailureThreshold,
+		h.filled == len(h.window) && h.errorRate() >= h.args.MaxErrorRate:
+		h.state = BREAKER_STATE_OPEN
+		h.openedAt = now
+		h.probeAt = time.Time{}
+	}
+}
+
+// close 用于闭合熔断器并清空最近调用的结果，调用方需持有锁
+func (h *health) close() {
+	h.state = BREAKER_STATE_CLOSED
+	h.probeAt = time.Time{}
+	for i := range h.window {
+		h.window[i] = false
+	}
+	h.next, h.filled, h.windowFailures = 0, 0, 0
+}
+
+// errorRate 用于计算最近调用的错误率，调用方需持有锁
+func (h *health) errorRate() float64 {
+	if h.filled == 0 {
+		return 0
+	}
+	return float64(h.windowFailures) / float64(h.filled)
+}
+
+// summary 用于获取健康状态的摘要
+func (h *health) summary() HealthSummaryStruct {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return HealthSummaryStruct{
+		State:               h.state,
+		ConsecutiveFailures: h.consecutiveFailures,
+		ErrorRate:           h.errorRate(),
+		Successes:           h.successes,
+		Failures:            h.failures,
+	}
+}
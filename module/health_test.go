@@ -0,0 +1,107 @@
+package module
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHealthArgsCheck(t *testing.T) {
+	invalidArgsList := []HealthArgs{
+		{MaxErrorRate: -0.1},
+		{MaxErrorRate: 1.1},
+		{OpenTimeout: -time.Second},
+	}
+	for _, args := range invalidArgsList {
+		if err := args.Check(); err == nil {
+			t.Fatalf("No error when checking invalid health args: %#v", args)
+		}
+	}
+
+	args := HealthArgs{}.withDefaults()
+	expected := HealthArgs{
+		FailureThreshold: defaultFailureThreshold,
+		WindowSize:       defaultWindowSize,
+		MaxErrorRate:     defaultMaxErrorRate,
+		OpenTimeout:      defaultOpenTimeout,
+	}
+	if args != expected {
+		t.Fatalf("Inconsistent health args: expected: %#v, actual: %#v", expected, args)
+	}
+}
+
+func TestHealthConsecutiveFailures(t *testing.T) {
+	now := time.Now()
+	h := newHealth(HealthArgs{FailureThreshold: 3, WindowSize: 100, OpenTimeout: time.Second}.withDefaults())
+	for i := 0; i < 2; i++ {
+		h.report(false, now)
+	}
+	h.report(true, now)
+	for i := 0; i < 2; i++ {
+		h.report(false, now)
+	}
+	if state := h.summary().State; state != BREAKER_STATE_CLOSED {
+		t.Fatalf("Inconsistent breaker state: expected: %s, actual: %s", BREAKER_STATE_CLOSED, state)
+	}
+
+	h.report(false, now)
+	expected := HealthSummaryStruct{
+		State:               BREAKER_STATE_OPEN,
+		ConsecutiveFailures: 3,
+		ErrorRate:           5.0 / 6,
+		Successes:           1,
+		Failures:            5,
+	}
+	if summary := h.summary(); summary != expected {
+		t.Fatalf("Inconsistent health summary: expected: %#v, actual: %#v", expected, summary)
+	}
+
+	// 熔断器打开期间不可获取，超时后转为半开并只允许一次试探。
+	if h.available(now.Add(time.Millisecond*500)) || h.acquire(now.Add(time.Millisecond*500)) {
+		t.Fatal("The module with open breaker should not be available!")
+	}
+	later := now.Add(time.Second)
+	if !h.acquire(later) {
+		t.Fatal("The module should be available after the open timeout!")
+	}
+	if state := h.summary().State; state != BREAKER_STATE_HALF_OPEN {
+		t.Fatalf("Inconsistent breaker state: expected: %s, actual: %s", BREAKER_STATE_HALF_OPEN, state)
+	}
+	if h.acquire(later) {
+		t.Fatal("Only one probe should be allowed in half-open state!")
+	}
+
+	// 试探失败会重新打开熔断器。
+	h.report(false, later)
+	if state := h.summary().State; state != BREAKER_STATE_OPEN {
+		t.Fatalf("Inconsistent breaker state: expected: %s, actual: %s", BREAKER_STATE_OPEN, state)
+	}
+
+	// 试探成功会闭合熔断器并清空最近调用的结果。
+	later = later.Add(time.Second)
+	if !h.acquire(later) {
+		t.Fatal("The module should be available after the open timeout!")
+	}
+	h.report(true, later)
+	summary := h.summary()
+	if summary.State != BREAKER_STATE_CLOSED || summary.ConsecutiveFailures != 0 || summary.ErrorRate != 0 {
+		t.Fatalf("Inconsistent health summary: %#v", summary)
+	}
+}
+
+func TestHealthErrorRate(t *testing.T) {
+	now := time.Now()
+	h := newHealth(HealthArgs{FailureThreshold: 100, WindowSize: 4, MaxErrorRate: 0.5}.withDefaults())
+	h.report(false, now)
+	h.report(true, now)
+	h.report(false, now)
+	if state := h.summary().State; state != BREAKER_STATE_CLOSED {
+		t.Fatalf("Inconsistent breaker state before the window is full: expected: %s, actual: %s",
+			BREAKER_STATE_CLOSED, state)
+	}
+
+	h.report(true, now)
+	h.report(false, now)
+	if summary := h.summary(); summary.State != BREAKER_STATE_OPEN || summary.ErrorRate != 0.5 {
+		t.Fatalf("Inconsistent health summary: %#v", summary)
+	}
+}
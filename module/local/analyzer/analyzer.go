@@ -98,6 +98,14 @@ func (analyzer *myAnalyzer) Analyze(resp *module.Response) (dataList []module.Da
 		return
 	}
 
+	// 把响应及其对应的请求放入HTTP请求的上下文，以便响应解析函数获取其下载信息和元数据。
+	ctx := module.ContextWithResponse(httpReq.Context(), resp)
+	parent := resp.Request()
+	if parent != nil {
+		ctx = module.ContextWithRequest(ctx, parent)
+	}
+	httpResp.Request = httpReq.WithContext(ctx)
+
 	dataList = []module.Data{}
 	for _, respParser := range analyzer.respParsers {
 		httpResp.Body = multipleReader.Reader()
@@ -107,7 +115,7 @@ func (analyzer *myAnalyzer) Analyze(resp *module.Response) (dataList []module.Da
 				if pData == nil {
 					continue
 				}
-				dataList = appendDataList(dataList, pData, respDepth)
+				dataList = appendDataList(dataList, pData, parent, respDepth)
 			}
 		}
 
@@ -129,7 +137,8 @@ func (analyzer *myAnalyzer) Analyze(resp *module.Response) (dataList []module.Da
 }
 
 // appendDataList 用于添加请求值或条目值到列表。
-func appendDataList(dataList []module.Data, data module.Data, respDepth uint32) []module.Data {
+// 请求会被关联到父请求parent（可以为nil），并继承其元数据。
+func appendDataList(dataList []module.Data, data module.Data, parent *module.Request, respDepth uint32) []module.Data {
 	if data == nil {
 		return dataList
 	}
@@ -140,8 +149,8 @@ func appendDataList(dataList []module.Data, data module.Data, respDepth uint32)
 	}
 
 	newDepth := respDepth + 1
-	if req.Depth() != newDepth {
-		req = module.NewRequest(req.HTTPReq(), newDepth)
+	if parent != nil || req.Depth() != newDepth {
+		req = module.NewChildRequest(parent, req, newDepth)
 	}
 
 	return append(dataList, req)
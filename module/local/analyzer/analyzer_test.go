@@ -334,3 +334,85 @@ func TestCount(t *testing.T) {
 		t.Fatalf("内部模块的处理数不一字。预期: %d, 实际: %d", 0, ai.HandlingNumber())
 	}
 }
+
+func TestAppendDataListWithBody(t *testing.T) {
+	httpReq, err := http.NewRequest("POST", "https://gitee.com/search", strings.NewReader("q=golang"))
+	if err != nil {
+		t.Fatalf("创建HTTP请求时出错: %s", err)
+	}
+
+	dataList := appendDataList(nil, module.NewRequest(httpReq, 0), nil, 1)
+	if len(dataList) != 1 {
+		t.Fatalf("数据列表的长度不一致。预期: %d, 实际: %d", 1, len(dataList))
+	}
+
+	req := dataList[0].(*module.Request)
+	if req.Depth() != 2 {
+		t.Fatalf("请求的深度不一致。预期: %d, 实际: %d", 2, req.Depth())
+	}
+
+	if body := string(req.Body()); body != "q=golang" {
+		t.Fatalf("请求体不一致。预期: %s, 实际: %s", "q=golang", body)
+	}
+}
+
+func TestAnalyzeWithParentRequest(t *testing.T) {
+	parser := func(httpResp *http.Response, respDepth uint32) ([]module.Data, []error) {
+		parent, ok := module.RequestFromContext(httpResp.Request.Context())
+		if !ok {
+			return nil, []error{fmt.Errorf("no parent request")}
+		}
+		category, _ := parent.Meta().GetString("category")
+		if resp, ok := module.ResponseFromContext(httpResp.Request.Context()); !ok || resp.FetchInfo().Downloader != "D1" {
+			return nil, []error{fmt.Errorf("no response")}
+		}
+		httpReq, _ := http.NewRequest("GET", "https://gitee.com/next", nil)
+		req := module.NewRequest(httpReq, respDepth)
+		req.SetSource(module.SOURCE_LINK)
+		req.SetMeta("page", 2)
+		return []module.Data{req, module.Item{"category": category}}, nil
+	}
+	a, err := New("A1", []module.ParseResponse{parser}, nil)
+	if err != nil {
+		t.Fatalf("创建分析器时出错: %s", err)
+	}
+
+	httpReq, _ := http.NewRequest("GET", "https://gitee.com/", nil)
+	parent := module.NewRequest(httpReq, 1)
+	parent.SetMeta("category", "news")
+	parent.SetMeta("page", 1)
+	httpResp := &http.Response{
+		StatusCode: 200,
+		Body:       testingReader{strings.NewReader("body")},
+		Request:    httpReq,
+	}
+	resp := module.NewResponseForRequest(httpResp, parent)
+	resp.SetFetchInfo(module.FetchInfo{Downloader: "D1"})
+	dataList, errs := a.Analyze(resp)
+	if len(errs) != 0 {
+		t.Fatalf("解析响应时出错: %v", errs)
+	}
+	if len(dataList) != 2 {
+		t.Fatalf("数据列表的长度不一致。预期: %d, 实际: %d", 2, len(dataList))
+	}
+
+	req := dataList[0].(*module.Request)
+	if req.Parent() != parent || req.Referrer() != "https://gitee.com/" {
+		t.Fatalf("父请求不一致。预期: %p, 实际: %p (referrer: %s)", parent, req.Parent(), req.Referrer())
+	}
+	if req.Source() != module.SOURCE_LINK || req.Depth() != 2 {
+		t.Fatalf("请求不一致: source: %s, depth: %d", req.Source(), req.Depth())
+	}
+	// 子请求继承父请求的元数据，同名的键以子请求为准。
+	category, _ := req.Meta().GetString("category")
+	page, _ := req.Meta().GetInt("page")
+	if category != "news" || page != 2 {
+		t.Fatalf("元数据不一致: %v", req.Meta())
+	}
+	if page, _ := parent.Meta().GetInt("page"); page != 1 {
+		t.Fatalf("父请求的元数据不应被修改: %v", parent.Meta())
+	}
+	if item := dataList[1].(module.Item); item["category"] != "news" {
+		t.Fatalf("条目不一致: %v", item)
+	}
+}
@@ -0,0 +1,337 @@
+package downloader
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CacheEntry 代表缓存中的一个HTTP响应
+type CacheEntry struct {
+	// URL 代表响应对应的请求URL
+	URL string `json:"url"`
+	// Status 代表响应的状态行，如"200 OK"
+	Status string `json:"status"`
+	// StatusCode 代表响应的状态码
+	StatusCode int `json:"status_code"`
+	// Proto 代表响应的协议版本
+	Proto string `json:"proto"`
+	// Header 代表响应头
+	Header http.Header `json:"header,omitempty"`
+	// Body 代表响应体的内容
+	Body []byte `json:"body,omitempty"`
+	// Vary 代表响应的Vary头所列出的请求头在原请求中的值
+	Vary http.Header `json:"vary,omitempty"`
+	// StoredAt 代表响应被存入或最近一次被重新验证的时间
+	StoredAt time.Time `json:"stored_at"`
+}
+
+// Cache 代表HTTP响应缓存的接口类型，实现类型必须是并发安全的
+// 键是请求的指纹，由下载器负责计算
+type Cache interface {
+	// Get 用于获取给定键对应的缓存项，不存在时返回nil和nil
+	Get(key string) (*CacheEntry, error)
+	// Put 用于存入给定键对应的缓存项，已存在时会被覆盖
+	Put(key string, entry *CacheEntry) error
+	// Delete 用于删除给定键对应的缓存项，不存在时不做任何事
+	Delete(key string) error
+}
+
+// diskCache 代表把缓存项保存在磁盘上的缓存的实现类型
+// 每个缓存项保存为一个JSON文件，并按照键的前两个字符分散到子目录中
+type diskCache struct {
+	// dir 代表缓存的根目录
+	dir string
+}
+
+// NewDiskCache 用于创建一个把缓存项保存在给定目录中的缓存
+// 目录不存在时会被创建
+func NewDiskCache(dir string) (Cache, error) {
+	if dir == "" {
+		return nil, genParameterError("空的缓存目录")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &diskCache{dir: dir}, nil
+}
+
+// path 用于获取给定键对应的文件路径
+func (cache *diskCache) path(key string) (string, error) {
+	if len(key) < 2 || strings.ContainsAny(key, `/\.`) {
+		return "", genParameterError(fmt.Sprintf("无效的缓存键: %q", key))
+	}
+	return filepath.Join(cache.dir, key[:2], key+".json"), nil
+}
+
+func (cache *diskCache) Get(key string) (*CacheEntry, error) {
+	path, err := cache.path(key)
+	if err != nil {
+		return nil, err
+	}
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entry CacheEntry
+	if err := json.Unmarshal(content, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (cache *diskCache) Put(key string, entry *CacheEntry) error {
+	if entry == nil {
+		return genParameterError("无缓存项")
+	}
+	path, err := cache.path(key)
+	if err != nil {
+		return err
+	}
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	// 先写入临时文件再重命名，以免并发读取时读到不完整的内容。
+	tmp, err := ioutil.TempFile(filepath.Dir(path), key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+func (cache *diskCache) Delete(key string) error {
+	path, err := cache.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// CacheSummaryStruct 代表下载器缓存的摘要类型，会作为下载器摘要的额外信息
+type CacheSummaryStruct struct {
+	// Hits 代表由缓存提供的响应的数量，包括经重新验证的响应
+	Hits uint64 `json:"hits"`
+	// Revalidated 代表经重新验证（304 Not Modified）后由缓存提供的响应的数量
+	Revalidated uint64 `json:"revalidated"`
+	// Misses 代表可缓存但未由缓存提供的响应的数量
+	Misses uint64 `json:"misses"`
+}
+
+// cacheControl 用于解析Cache-Control头中的指令，指令名称为小写
+func cacheControl(header http.Header) map[string]string {
+	directives := map[string]string{}
+	for _, value := range header.Values("Cache-Control") {
+		for _, part := range strings.Split(value, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			name, arg := part, ""
+			if i := strings.IndexByte(part, '='); i >= 0 {
+				name, arg = part[:i], strings.Trim(strings.TrimSpace(part[i+1:]), `"`)
+			}
+			directives[strings.ToLower(strings.TrimSpace(name))] = arg
+		}
+	}
+	return directives
+}
+
+// cacheableRequest 用于判断请求是否可以使用缓存
+func cacheableRequest(httpReq *http.Request) bool {
+	if httpReq.Method != "" && httpReq.Method != http.MethodGet {
+		return false
+	}
+	_, noStore := cacheControl(httpReq.Header)["no-store"]
+	return !noStore
+}
+
+// storable 用于判断响应是否可以被存入缓存
+// 只有未经重定向的200响应才会被存入，且响应头不能包含no-store或Vary: *
+func storable(httpResp *http.Response) bool {
+	if httpResp.StatusCode != http.StatusOK {
+		return false
+	}
+	if httpResp.Request != nil && httpResp.Request.Response != nil {
+		return false
+	}
+	directives := cacheControl(httpResp.Header)
+	if _, ok := directives["no-store"]; ok {
+		return false
+	}
+	for _, name := range varyNames(httpResp.Header) {
+		if name == "*" {
+			return false
+		}
+	}
+	return true
+}
+
+// varyNames 用于获取Vary头所列出的请求头的规范名称
+func varyNames(header http.Header) []string {
+	var names []string
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	return names
+}
+
+// newCacheEntry 用于根据HTTP响应及其已读出的响应体创建缓存项
+func newCacheEntry(httpReq *http.Request, httpResp *http.Response, body []byte, now time.Time) *CacheEntry {
+	entry := &CacheEntry{
+		URL:        httpReq.URL.String(),
+		Status:     httpResp.Status,
+		StatusCode: httpResp.StatusCode,
+		Proto:      httpResp.Proto,
+		Header:     httpResp.Header.Clone(),
+		Body:       body,
+		StoredAt:   now,
+	}
+	if names := varyNames(httpResp.Header); len(names) > 0 {
+		entry.Vary = http.Header{}
+		for _, name := range names {
+			entry.Vary[name] = httpReq.Header.Values(name)
+		}
+	}
+	return entry
+}
+
+// matches 用于判断缓存项是否适用于给定的请求
+func (entry *CacheEntry) matches(httpReq *http.Request) bool {
+	if entry.URL != httpReq.URL.String() {
+		return false
+	}
+	for name, values := range entry.Vary {
+		if strings.Join(values, ",") != strings.Join(httpReq.Header.Values(name), ",") {
+			return false
+		}
+	}
+	return true
+}
+
+// lifetime 用于计算缓存项的新鲜期，优先使用max-age，其次使用Expires
+// 响应头包含no-cache时新鲜期为0，即每次使用前都需要重新验证
+func (entry *CacheEntry) lifetime() time.Duration {
+	directives := cacheControl(entry.Header)
+	if _, ok := directives["no-cache"]; ok {
+		return 0
+	}
+	if maxAge, ok := directives["max-age"]; ok {
+		seconds, err := strconv.ParseInt(maxAge, 10, 64)
+		if err != nil || seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if expires := entry.Header.Get("Expires"); expires != "" {
+		expiresAt, err := http.ParseTime(expires)
+		if err != nil {
+			return 0
+		}
+		date := entry.StoredAt
+		if d, err := http.ParseTime(entry.Header.Get("Date")); err == nil {
+			date = d
+		}
+		return expiresAt.Sub(date)
+	}
+	return 0
+}
+
+// age 用于计算缓存项在给定时间的年龄，包括响应在存入时已有的年龄
+func (entry *CacheEntry) age(now time.Time) time.Duration {
+	age := now.Sub(entry.StoredAt)
+	if seconds, err := strconv.ParseInt(entry.Header.Get("Age"), 10, 64); err == nil && seconds > 0 {
+		age += time.Duration(seconds) * time.Second
+	}
+	return age
+}
+
+// fresh 用于判断缓存项在给定时间是否仍然新鲜
+func (entry *CacheEntry) fresh(now time.Time) bool {
+	return entry.age(now) < entry.lifetime()
+}
+
+// setValidators 用于根据缓存项为请求设置用于重新验证的条件请求头
+// 缓存项既没有ETag也没有Last-Modified时返回false
+func (entry *CacheEntry) setValidators(httpReq *http.Request) bool {
+	etag := entry.Header.Get("Etag")
+	lastModified := entry.Header.Get("Last-Modified")
+	if etag != "" {
+		httpReq.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		httpReq.Header.Set("If-Modified-Since", lastModified)
+	}
+	return etag != "" || lastModified != ""
+}
+
+// revalidated 用于根据304响应更新缓存项的响应头和存入时间
+func (entry *CacheEntry) revalidated(httpResp *http.Response, now time.Time) {
+	for name, values := range httpResp.Header {
+		switch name {
+		case "Content-Length", "Content-Encoding", "Transfer-Encoding":
+			continue
+		}
+		entry.Header[name] = values
+	}
+	// 304响应中没有的Age头不应沿用旧值。
+	if httpResp.Header.Get("Age") == "" {
+		entry.Header.Del("Age")
+	}
+	entry.StoredAt = now
+}
+
+// httpResponse 用于根据缓存项生成对应给定请求的HTTP响应
+func (entry *CacheEntry) httpResponse(httpReq *http.Request) *http.Response {
+	major, minor, ok := http.ParseHTTPVersion(entry.Proto)
+	if !ok {
+		major, minor = 1, 1
+	}
+	return &http.Response{
+		Status:        entry.Status,
+		StatusCode:    entry.StatusCode,
+		Proto:         entry.Proto,
+		ProtoMajor:    major,
+		ProtoMinor:    minor,
+		Header:        entry.Header.Clone(),
+		Body:          ioutil.NopCloser(bytes.NewReader(entry.Body)),
+		ContentLength: int64(len(entry.Body)),
+		Request:       httpReq,
+	}
+}
@@ -0,0 +1,261 @@
+package downloader
+
+import (
+	"crawler/module"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDiskCache(t *testing.T) {
+	if _, err := NewDiskCache(""); err == nil {
+		t.Fatal("No error when creating disk cache with empty directory!")
+	}
+
+	cache, err := NewDiskCache(t.TempDir())
+	if err != nil {
+		t.Fatalf("An error occurs when creating disk cache: %s", err)
+	}
+	if entry, err := cache.Get("ab12"); entry != nil || err != nil {
+		t.Fatalf("Inconsistent missing entry: %#v, %v", entry, err)
+	}
+	if err := cache.Put("../x", &CacheEntry{}); err == nil {
+		t.Fatal("No error when putting entry with invalid key!")
+	}
+
+	expected := &CacheEntry{
+		URL:        "http://sogou.com/",
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Proto:      "HTTP/1.1",
+		Header:     http.Header{"Etag": {`"v1"`}},
+		Body:       []byte("body"),
+		StoredAt:   time.Now().Round(time.Second),
+	}
+	if err := cache.Put("ab12", expected); err != nil {
+		t.Fatalf("An error occurs when putting entry: %s", err)
+	}
+	entry, err := cache.Get("ab12")
+	if err != nil || entry == nil {
+		t.Fatalf("Couldn't get entry: %v", err)
+	}
+	if entry.URL != expected.URL || string(entry.Body) != "body" ||
+		entry.Header.Get("Etag") != `"v1"` || !entry.StoredAt.Equal(expected.StoredAt) {
+		t.Fatalf("Inconsistent entry: expected: %#v, actual: %#v", expected, entry)
+	}
+
+	if err := cache.Delete("ab12"); err != nil {
+		t.Fatalf("An error occurs when deleting entry: %s", err)
+	}
+	if err := cache.Delete("ab12"); err != nil {
+		t.Fatalf("An error occurs when deleting missing entry: %s", err)
+	}
+	if entry, _ := cache.Get("ab12"); entry != nil {
+		t.Fatalf("Entry still exists after deleting: %#v", entry)
+	}
+}
+
+func TestCacheEntryFreshness(t *testing.T) {
+	now := time.Now()
+	testCases := []struct {
+		header http.Header
+		fresh  bool
+	}{
+		{http.Header{"Cache-Control": {"max-age=60"}}, true},
+		{http.Header{"Cache-Control": {"public, max-age=60"}, "Age": {"90"}}, false},
+		{http.Header{"Cache-Control": {"max-age=60, no-cache"}}, false},
+		{http.Header{"Cache-Control": {"max-age=0"}}, false},
+		{http.Header{"Expires": {now.Add(time.Hour).UTC().Format(http.TimeFormat)}}, true},
+		{http.Header{"Expires": {now.Add(-time.Hour).UTC().Format(http.TimeFormat)}}, false},
+		{http.Header{"Expires": {"0"}}, false},
+		{http.Header{}, false},
+	}
+	for _, tc := range testCases {
+		entry := &CacheEntry{Header: tc.header, StoredAt: now.Add(-time.Second)}
+		if fresh := entry.fresh(now); fresh != tc.fresh {
+			t.Fatalf("Inconsistent freshness for %v: expected: %v, actual: %v", tc.header, tc.fresh, fresh)
+		}
+	}
+}
+
+func TestDownloadWithCache(t *testing.T) {
+	var requests, conditional uint32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddUint32(&requests, 1)
+		switch r.URL.Path {
+		case "/fresh":
+			w.Header().Set("Cache-Control", "max-age=3600")
+			w.Write([]byte("fresh"))
+		case "/etag":
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("Etag", `"v1"`)
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				atomic.AddUint32(&conditional, 1)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Write([]byte("etag"))
+		case "/no-store":
+			w.Header().Set("Cache-Control", "no-store")
+			w.Write([]byte("no-store"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cache, err := NewDiskCache(t.TempDir())
+	if err != nil {
+		t.Fatalf("创建缓存时出错: %s", err)
+	}
+	d, err := NewWithOptions("D1", &http.Client{}, Options{Cache: cache}, nil)
+	if err != nil {
+		t.Fatalf("创建下载器时出错: %s", err)
+	}
+	download := func(path string) (*module.Response, string) {
+		httpReq, _ := http.NewRequest("GET", server.URL+path, nil)
+		resp, err := d.Download(module.NewRequest(httpReq, 0))
+		if err != nil {
+			t.Fatalf("下载内容时出错: %s", err)
+		}
+		body, _ := ioutil.ReadAll(resp.HTTPResp().Body)
+		return resp, string(body)
+	}
+
+	// 新鲜的缓存项直接由缓存提供。
+	resp, body := download("/fresh")
+	if resp.Cached() || body != "fresh" {
+		t.Fatalf("首次下载不应来自缓存: %v, %q", resp.Cached(), body)
+	}
+	resp, body = download("/fresh")
+	if !resp.Cached() || body != "fresh" || resp.HTTPResp().StatusCode != http.StatusOK {
+		t.Fatalf("新鲜的响应应来自缓存: %v, %q", resp.Cached(), body)
+	}
+	if resp.BytesRead() != uint64(len("fresh")) {
+		t.Fatalf("不一致的读出字节数: 期望: %d, 实际: %d", len("fresh"), resp.BytesRead())
+	}
+	if n := atomic.LoadUint32(&requests); n != 1 {
+		t.Fatalf("不一致的请求数: 期望: %d, 实际: %d", 1, n)
+	}
+
+	// 需要重新验证的缓存项在收到304响应后由缓存提供。
+	download("/etag")
+	resp, body = download("/etag")
+	if !resp.Cached() || body != "etag" || resp.HTTPResp().StatusCode != http.StatusOK {
+		t.Fatalf("经重新验证的响应应来自缓存: %v, %q", resp.Cached(), body)
+	}
+	if n := atomic.LoadUint32(&conditional); n != 1 {
+		t.Fatalf("不一致的条件请求数: 期望: %d, 实际: %d", 1, n)
+	}
+	if resp.FetchInfo().Timing.Total == 0 {
+		t.Fatal("经重新验证的响应应记录下载耗时!")
+	}
+
+	// no-store的响应不会被缓存。
+	download("/no-store")
+	if resp, _ = download("/no-store"); resp.Cached() {
+		t.Fatal("no-store的响应不应来自缓存!")
+	}
+
+	// 非GET请求不使用缓存。
+	httpReq, _ := http.NewRequest("HEAD", server.URL+"/fresh", nil)
+	if resp, err = d.Download(module.NewRequest(httpReq, 0)); err != nil || resp.Cached() {
+		t.Fatalf("HEAD请求不应使用缓存: %v", err)
+	}
+
+	expected := CacheSummaryStruct{Hits: 2, Revalidated: 1, Misses: 4}
+	if extra := d.Summary().Extra; extra != expected {
+		t.Fatalf("不一致的缓存摘要: 期望: %#v, 实际: %#v", expected, extra)
+	}
+
+	// 没有缓存的下载器不提供额外信息。
+	d, _ = New("D2", &http.Client{}, nil)
+	if extra := d.Summary().Extra; extra != nil {
+		t.Fatalf("不一致的缓存摘要: 期望: nil, 实际: %#v", extra)
+	}
+}
+
+func TestDownloadWithCacheLimit(t *testing.T) {
+	var requests uint32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddUint32(&requests, 1)
+		w.Header().Set("Cache-Control", "max-age=3600")
+		w.Write([]byte(strings.TrimPrefix(r.URL.Path, "/")))
+	}))
+	defer server.Close()
+
+	if _, err := NewWithOptions("D1", &http.Client{}, Options{MaxCacheBodySize: -1}, nil); err == nil {
+		t.Fatal("创建可缓存的响应体的最大字节数为负数的下载器时没有出错!")
+	}
+	cache, _ := NewDiskCache(t.TempDir())
+	d, err := NewWithOptions("D1", &http.Client{}, Options{Cache: cache, MaxCacheBodySize: 5}, nil)
+	if err != nil {
+		t.Fatalf("创建下载器时出错: %s", err)
+	}
+	download := func(path string) (*module.Response, string) {
+		httpReq, _ := http.NewRequest("GET", server.URL+path, nil)
+		resp, err := d.Download(module.NewRequest(httpReq, 0))
+		if err != nil {
+			t.Fatalf("下载内容时出错: %s", err)
+		}
+		body, _ := ioutil.ReadAll(resp.HTTPResp().Body)
+		return resp, string(body)
+	}
+
+	// 响应体不超过最大字节数的响应会被缓存。
+	download("/small")
+	if resp, body := download("/small"); !resp.Cached() || body != "small" {
+		t.Fatalf("响应体不大的响应应来自缓存: %v, %q", resp.Cached(), body)
+	}
+
+	// 响应体过大的响应不会被缓存，但其内容会被完整地交给调用方。
+	for i := 0; i < 2; i++ {
+		if resp, body := download("/larger"); resp.Cached() || body != "larger" {
+			t.Fatalf("响应体过大的响应不应来自缓存: %v, %q", resp.Cached(), body)
+		}
+	}
+	if n := atomic.LoadUint32(&requests); n != 3 {
+		t.Fatalf("不一致的请求数: 期望: %d, 实际: %d", 3, n)
+	}
+}
+
+func TestDownloadWithCacheVary(t *testing.T) {
+	var requests uint32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddUint32(&requests, 1)
+		w.Header().Set("Cache-Control", "max-age=3600")
+		w.Header().Set("Vary", "Accept-Language")
+		w.Write([]byte(r.Header.Get("Accept-Language")))
+	}))
+	defer server.Close()
+
+	cache, _ := NewDiskCache(t.TempDir())
+	d, err := NewWithOptions("D1", &http.Client{}, Options{Cache: cache}, nil)
+	if err != nil {
+		t.Fatalf("创建下载器时出错: %s", err)
+	}
+	download := func(lang string) *module.Response {
+		httpReq, _ := http.NewRequest("GET", server.URL, nil)
+		httpReq.Header.Set("Accept-Language", lang)
+		resp, err := d.Download(module.NewRequest(httpReq, 0))
+		if err != nil {
+			t.Fatalf("下载内容时出错: %s", err)
+		}
+		return resp
+	}
+
+	download("zh")
+	if !download("zh").Cached() {
+		t.Fatal("Vary头相同的响应应来自缓存!")
+	}
+	if download("en").Cached() {
+		t.Fatal("Vary头不同的响应不应来自缓存!")
+	}
+	if n := atomic.LoadUint32(&requests); n != 2 {
+		t.Fatalf("不一致的请求数: 期望: %d, 实际: %d", 2, n)
+	}
+}
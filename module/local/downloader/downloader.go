@@ -1,25 +1,78 @@
 package downloader
 
 import (
+	"bytes"
+	"crawler/errors"
 	log "crawler/logger"
 	"crawler/module"
 	"crawler/module/stub"
+	goerrors "errors"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sync/atomic"
+	"time"
 )
 
 // logger 代表日志记录器
 var logger = log.DLogger()
 
+// defaultMaxCacheBodySize 代表默认的可缓存的响应体的最大字节数
+const defaultMaxCacheBodySize = 8 << 20
+
 // myDownloader 代表下载器的实现类型。
 type myDownloader struct {
 	// stub.ModuleInternal 代表组件基础实例。
 	stub.ModuleInternal
 	// httpClient 代表下载用的HTTP客户端。
 	httpClient http.Client
+	// cache 代表HTTP响应缓存，为nil时不使用缓存。
+	cache Cache
+	// maxCacheBodySize 代表可缓存的响应体的最大字节数。
+	maxCacheBodySize int64
+	// sessions 代表下载会话，为nil时使用HTTP客户端本身的Cookie容器。
+	sessions Sessions
+	// cacheHits 代表由缓存提供的响应的数量。
+	cacheHits uint64
+	// cacheRevalidated 代表经重新验证后由缓存提供的响应的数量。
+	cacheRevalidated uint64
+	// cacheMisses 代表可缓存但未由缓存提供的响应的数量。
+	cacheMisses uint64
+}
+
+// Options 代表下载器的可选项
+type Options struct {
+	// RedirectPolicy 代表重定向策略
+	RedirectPolicy RedirectPolicy
+	// Cache 代表HTTP响应缓存，为nil时不使用缓存
+	// 缓存会遵循Cache-Control和Expires头，并在缓存过期后使用If-None-Match和If-Modified-Since重新验证
+	Cache Cache
+	// MaxCacheBodySize 代表可缓存的响应体的最大字节数，为0时使用8MB
+	// 响应体超过该大小的响应不会被缓存，而是直接交给调用方
+	MaxCacheBodySize int64
+	// Sessions 代表下载会话，为nil时使用HTTP客户端本身的Cookie容器
+	// 请求会使用其元数据中module.META_SESSION对应的会话，未指定时使用DefaultSession
+	Sessions Sessions
 }
 
-// New 用于创建一个下载器实例
+// New 用于创建一个使用默认重定向策略的下载器实例
 func New(mid module.MID, client *http.Client, scoreCalculator module.CalculateScore) (module.Downloader, error) {
+	return NewWithOptions(mid, client, Options{}, scoreCalculator)
+}
+
+// NewWithRedirectPolicy 用于创建一个使用给定重定向策略的下载器实例
+// HTTP客户端原有的CheckRedirect函数会在重定向策略允许跟随时被调用
+func NewWithRedirectPolicy(mid module.MID, client *http.Client, policy RedirectPolicy,
+	scoreCalculator module.CalculateScore) (module.Downloader, error) {
+	return NewWithOptions(mid, client, Options{RedirectPolicy: policy}, scoreCalculator)
+}
+
+// NewWithOptions 用于创建一个使用给定可选项的下载器实例
+func NewWithOptions(mid module.MID, client *http.Client, options Options,
+	scoreCalculator module.CalculateScore) (module.Downloader, error) {
 	moduleBase, err := stub.NewModuleInternal(mid, scoreCalculator)
 	if err != nil {
 		return nil, err
@@ -29,10 +82,27 @@ func New(mid module.MID, client *http.Client, scoreCalculator module.CalculateSc
 		return nil, genParameterError("无 http 客户端")
 	}
 
-	return &myDownloader{
-		ModuleInternal: moduleBase,
-		httpClient:     *client,
-	}, nil
+	if err := options.RedirectPolicy.Check(); err != nil {
+		return nil, err
+	}
+
+	if options.MaxCacheBodySize < 0 {
+		return nil, genParameterError(fmt.Sprintf("负的可缓存的响应体的最大字节数: %d", options.MaxCacheBodySize))
+	}
+	maxCacheBodySize := options.MaxCacheBodySize
+	if maxCacheBodySize == 0 {
+		maxCacheBodySize = defaultMaxCacheBodySize
+	}
+
+	downloader := &myDownloader{
+		ModuleInternal:   moduleBase,
+		httpClient:       *client,
+		cache:            options.Cache,
+		maxCacheBodySize: maxCacheBodySize,
+		sessions:         options.Sessions,
+	}
+	downloader.httpClient.CheckRedirect = options.RedirectPolicy.checkRedirect(client.CheckRedirect)
+	return downloader, nil
 }
 
 func (downloader *myDownloader) Download(req *module.Request) (*module.Response, error) {
@@ -44,18 +114,205 @@ func (downloader *myDownloader) Download(req *module.Request) (*module.Response,
 		return nil, genParameterError("无请求")
 	}
 
-	httpReq := req.HTTPReq()
+	httpReq := req.HTTPReqToSend()
 	if httpReq == nil {
 		return nil, genParameterError("无http请求")
 	}
 
+	httpClient, session, err := downloader.clientFor(req)
+	if err != nil {
+		return nil, err
+	}
+
 	downloader.ModuleInternal.IncrAcceptedCount()
+
+	// 缓存项仍然新鲜时直接由缓存提供响应，否则尝试带上条件请求头重新验证。
+	var cacheKey string
+	var entry *CacheEntry
+	if downloader.cache != nil && cacheableRequest(httpReq) {
+		cacheKey = module.Fingerprint(req, "")
+		// 不同会话的Cookie不同，响应也可能不同。
+		if session != "" {
+			cacheKey += "-" + session
+		}
+		entry = downloader.lookup(cacheKey, httpReq)
+		if entry != nil && entry.fresh(time.Now()) {
+			logger.Infof("使用缓存(URL: %s, depth: %d)... \n", httpReq.URL, req.Depth())
+			atomic.AddUint64(&downloader.cacheHits, 1)
+			downloader.ModuleInternal.IncrCompletedCount()
+			return downloader.cachedResponse(req, entry.httpResponse(httpReq), module.Timing{}), nil
+		}
+		if entry != nil {
+			httpReq = httpReq.Clone(httpReq.Context())
+			if !entry.setValidators(httpReq) {
+				entry = nil
+			}
+		}
+	}
+
 	logger.Infof("执行请求(URL: %s, depth: %d)... \n", httpReq.URL, req.Depth())
-	httpResp, err := downloader.httpClient.Do(httpReq)
+	tracer := newTimingTracer()
+	httpReq = httpReq.WithContext(httptrace.WithClientTrace(httpReq.Context(), tracer.clientTrace()))
+	if req.HandOffRedirects() {
+		httpReq = withHandOff(httpReq)
+	}
+	httpResp, err := httpClient.Do(httpReq)
 	if err != nil {
+		// 违反重定向策略时返回下载器的错误。
+		var urlErr *url.Error
+		if goerrors.As(err, &urlErr) {
+			if ce, ok := urlErr.Err.(errors.CrawlerError); ok {
+				return nil, ce
+			}
+		}
 		return nil, err
 	}
+	timing := tracer.finish()
+
+	if cacheKey != "" {
+		if entry != nil && httpResp.StatusCode == http.StatusNotModified {
+			atomic.AddUint64(&downloader.cacheHits, 1)
+			atomic.AddUint64(&downloader.cacheRevalidated, 1)
+			downloader.ModuleInternal.IncrCompletedCount()
+			return downloader.revalidate(req, cacheKey, entry, httpResp, timing), nil
+		}
+		atomic.AddUint64(&downloader.cacheMisses, 1)
+		if err := downloader.store(cacheKey, httpResp); err != nil {
+			return nil, err
+		}
+	}
 
 	downloader.ModuleInternal.IncrCompletedCount()
-	return module.NewResponse(httpResp, req.Depth()), nil
+	resp := module.NewResponseForRequest(httpResp, req)
+	resp.SetFetchInfo(module.FetchInfo{
+		Downloader: downloader.ID(),
+		Timing:     timing,
+		Redirects:  module.RedirectChain(httpResp),
+	})
+	resp.TrackBody()
+
+	// 未跟随的重定向会作为新的请求交给调度器。
+	if target := redirectTarget(httpResp); target != nil {
+		redirectReq, err := newRedirectRequest(req, httpResp, target)
+		if err != nil {
+			logger.Warnf("Couldn't generate redirect request: %s (URL: %s)", err, target)
+		} else {
+			resp.SetRedirectRequest(redirectReq)
+		}
+	}
+	return resp, nil
+}
+
+// clientFor 用于获取下载给定请求时使用的HTTP客户端及会话的名称
+// 下载器没有会话时，会话的名称为空
+func (downloader *myDownloader) clientFor(req *module.Request) (*http.Client, string, error) {
+	name, _ := req.Meta().GetString(module.META_SESSION)
+	if downloader.sessions == nil {
+		if name != "" {
+			return nil, "", genParameterError(fmt.Sprintf("下载器没有会话 (会话: %s)", name))
+		}
+		return &downloader.httpClient, "", nil
+	}
+
+	if name == "" {
+		name = DefaultSession
+	}
+	jar, err := downloader.sessions.Jar(name)
+	if err != nil {
+		return nil, "", err
+	}
+	httpClient := downloader.httpClient
+	httpClient.Jar = jar
+	return &httpClient, name, nil
+}
+
+// lookup 用于获取适用于给定请求的缓存项，读取失败时视为不存在
+func (downloader *myDownloader) lookup(key string, httpReq *http.Request) *CacheEntry {
+	entry, err := downloader.cache.Get(key)
+	if err != nil {
+		logger.Warnf("Couldn't read cache entry: %s (URL: %s)", err, httpReq.URL)
+		return nil
+	}
+	if entry == nil || !entry.matches(httpReq) {
+		return nil
+	}
+	return entry
+}
+
+// store 用于把可缓存的HTTP响应存入缓存，此时会读出整个响应体
+// 不可缓存的响应和响应体过大的响应会使已有的缓存项失效
+func (downloader *myDownloader) store(key string, httpResp *http.Response) error {
+	if !storable(httpResp) {
+		downloader.invalidate(key, httpResp)
+		return nil
+	}
+
+	// 最多多读出一个字节，以便判断响应体是否过大。
+	body, err := ioutil.ReadAll(io.LimitReader(httpResp.Body, downloader.maxCacheBodySize+1))
+	if err != nil {
+		httpResp.Body.Close()
+		return err
+	}
+	if int64(len(body)) > downloader.maxCacheBodySize {
+		// 已读出的部分和剩余的部分会被原样交给调用方。
+		httpResp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), httpResp.Body), httpResp.Body}
+		downloader.invalidate(key, httpResp)
+		return nil
+	}
+	httpResp.Body.Close()
+	httpResp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	entry := newCacheEntry(httpResp.Request, httpResp, body, time.Now())
+	if err := downloader.cache.Put(key, entry); err != nil {
+		logger.Warnf("Couldn't write cache entry: %s (URL: %s)", err, httpResp.Request.URL)
+	}
+	return nil
+}
+
+// invalidate 用于删除给定键对应的缓存项
+func (downloader *myDownloader) invalidate(key string, httpResp *http.Response) {
+	if err := downloader.cache.Delete(key); err != nil {
+		logger.Warnf("Couldn't delete cache entry: %s (URL: %s)", err, httpResp.Request.URL)
+	}
+}
+
+// revalidate 用于在收到304响应后更新缓存项，并生成由缓存提供的响应
+func (downloader *myDownloader) revalidate(req *module.Request, key string, entry *CacheEntry,
+	httpResp *http.Response, timing module.Timing) *module.Response {
+	io.Copy(ioutil.Discard, httpResp.Body)
+	httpResp.Body.Close()
+
+	entry.revalidated(httpResp, time.Now())
+	if err := downloader.cache.Put(key, entry); err != nil {
+		logger.Warnf("Couldn't write cache entry: %s (URL: %s)", err, httpResp.Request.URL)
+	}
+	return downloader.cachedResponse(req, entry.httpResponse(httpResp.Request), timing)
+}
+
+// cachedResponse 用于生成由缓存提供的响应
+func (downloader *myDownloader) cachedResponse(req *module.Request, httpResp *http.Response,
+	timing module.Timing) *module.Response {
+	resp := module.NewResponseForRequest(httpResp, req)
+	resp.SetFetchInfo(module.FetchInfo{
+		Downloader: downloader.ID(),
+		Timing:     timing,
+	})
+	resp.SetCached(true)
+	resp.TrackBody()
+	return resp
+}
+
+func (downloader *myDownloader) Summary() module.SummaryStruct {
+	summary := downloader.ModuleInternal.Summary()
+	if downloader.cache != nil {
+		summary.Extra = CacheSummaryStruct{
+			Hits:        atomic.LoadUint64(&downloader.cacheHits),
+			Revalidated: atomic.LoadUint64(&downloader.cacheRevalidated),
+			Misses:      atomic.LoadUint64(&downloader.cacheMisses),
+		}
+	}
+	return summary
 }
@@ -4,7 +4,10 @@ import (
 	"bufio"
 	"crawler/module"
 	"crawler/module/stub"
+	"fmt"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -206,3 +209,50 @@ func TestCount(t *testing.T) {
 		t.Fatalf("内部模块的处理编号不一致: expected: %d, actual: %d", 0, di.HandlingNumber())
 	}
 }
+
+func TestDownloadFetchInfo(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusFound)
+		case "/b":
+			http.Redirect(w, r, "/c", http.StatusMovedPermanently)
+		default:
+			fmt.Fprint(w, "hello")
+		}
+	}))
+	defer server.Close()
+
+	mid := module.MID("D1|127.0.0.1:8080")
+	d, _ := New(mid, server.Client(), nil)
+	httpReq, _ := http.NewRequest("GET", server.URL+"/a", nil)
+	resp, err := d.Download(module.NewRequest(httpReq, 0))
+	if err != nil {
+		t.Fatalf("下载内容时出错: %s", err)
+	}
+
+	info := resp.FetchInfo()
+	if info.Downloader != mid {
+		t.Fatalf("下载器的MID不一致。预期: %s, 实际: %s", mid, info.Downloader)
+	}
+	expectedRedirects := []string{server.URL + "/a", server.URL + "/b"}
+	if fmt.Sprint(info.Redirects) != fmt.Sprint(expectedRedirects) {
+		t.Fatalf("重定向链不一致。预期: %v, 实际: %v", expectedRedirects, info.Redirects)
+	}
+	if finalURL := resp.FinalURL(); finalURL == nil || finalURL.String() != server.URL+"/c" {
+		t.Fatalf("最终URL不一致。预期: %s, 实际: %v", server.URL+"/c", finalURL)
+	}
+
+	timing := info.Timing
+	if timing.Connect <= 0 || timing.TLS <= 0 || timing.TTFB <= 0 || timing.Total < timing.TTFB {
+		t.Fatalf("耗时不一致: %#v", timing)
+	}
+
+	if resp.BytesRead() != 0 {
+		t.Fatalf("读取字节数不一致。预期: %d, 实际: %d", 0, resp.BytesRead())
+	}
+	body, _ := ioutil.ReadAll(resp.HTTPResp().Body)
+	if resp.BytesRead() != uint64(len(body)) || string(body) != "hello" {
+		t.Fatalf("读取字节数不一致。预期: %d, 实际: %d", len(body), resp.BytesRead())
+	}
+}
@@ -0,0 +1,168 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"crawler/module"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// defaultMaxRedirectHops 代表默认的最多跟随的重定向次数
+const defaultMaxRedirectHops = 10
+
+// RedirectPolicy 代表下载器处理重定向的策略
+// 范围内的重定向会被直接跟随，经过的URL会被记录在响应的下载信息中；
+// 范围外的重定向不会被跟随，而是作为新的请求交给调度器，以便经过调度器的过滤和去重
+// 若请求的HandOffRedirects为true，则它的所有重定向都不会被跟随
+type RedirectPolicy struct {
+	// MaxHops 代表最多跟随的重定向次数，为0时使用10，超过后下载失败
+	MaxHops int
+	// InScope 用于判断是否直接跟随从from到to的重定向，为nil时使用SameHost
+	InScope func(from *url.URL, to *url.URL) bool
+}
+
+// Check 用于检查重定向策略的有效性
+func (policy RedirectPolicy) Check() error {
+	if policy.MaxHops < 0 {
+		return genParameterError(fmt.Sprintf("负的最多重定向次数: %d", policy.MaxHops))
+	}
+	return nil
+}
+
+// maxHops 用于获取实际使用的最多重定向次数
+func (policy RedirectPolicy) maxHops() int {
+	if policy.MaxHops == 0 {
+		return defaultMaxRedirectHops
+	}
+	return policy.MaxHops
+}
+
+// inScope 用于判断是否直接跟随给定的重定向
+func (policy RedirectPolicy) inScope(from *url.URL, to *url.URL) bool {
+	if policy.InScope == nil {
+		return SameHost(from, to)
+	}
+	return policy.InScope(from, to)
+}
+
+// SameHost 用于判断两个URL是否属于同一主机（包括端口），也是默认的范围判断函数
+func SameHost(from *url.URL, to *url.URL) bool {
+	return strings.EqualFold(from.Host, to.Host)
+}
+
+// handOffKey 代表HTTP请求的上下文中标记所有重定向都交给调度器的键
+type handOffKey struct{}
+
+// withHandOff 用于在HTTP请求的上下文中标记所有重定向都交给调度器
+func withHandOff(httpReq *http.Request) *http.Request {
+	return httpReq.WithContext(context.WithValue(httpReq.Context(), handOffKey{}, true))
+}
+
+// handOff 用于判断HTTP请求的所有重定向是否都交给调度器
+func handOff(httpReq *http.Request) bool {
+	handOff, _ := httpReq.Context().Value(handOffKey{}).(bool)
+	return handOff
+}
+
+// checkRedirect 用于生成按照重定向策略检查重定向的函数
+// 参数next代表HTTP客户端原有的检查函数，可以为nil
+func (policy RedirectPolicy) checkRedirect(next func(*http.Request, []*http.Request) error) func(*http.Request, []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if handOff(req) || !policy.inScope(via[len(via)-1].URL, req.URL) {
+			return http.ErrUseLastResponse
+		}
+
+		target := req.URL.String()
+		for _, r := range via {
+			if r.URL.String() == target {
+				return genError(fmt.Sprintf("重定向循环 (URL: %s)", target))
+			}
+		}
+
+		if len(via) > policy.maxHops() {
+			return genError(fmt.Sprintf("重定向次数超过 %d (URL: %s)", policy.maxHops(), target))
+		}
+
+		if next != nil {
+			return next(req, via)
+		}
+		return nil
+	}
+}
+
+// redirectTarget 用于获取重定向响应的目标URL，不是重定向响应时返回nil
+func redirectTarget(httpResp *http.Response) *url.URL {
+	switch httpResp.StatusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		return nil
+	}
+
+	location := httpResp.Header.Get("Location")
+	if location == "" {
+		return nil
+	}
+	target, err := url.Parse(location)
+	if err != nil {
+		return nil
+	}
+	if httpResp.Request != nil && httpResp.Request.URL != nil {
+		target = httpResp.Request.URL.ResolveReference(target)
+	}
+	return target
+}
+
+// sensitiveHeaders 代表重定向到范围外时不应被携带的请求头
+var sensitiveHeaders = []string{"Authorization", "Www-Authenticate", "Cookie", "Cookie2"}
+
+// newRedirectRequest 用于根据未跟随的重定向响应生成交给调度器的请求
+// 新请求是原请求的子请求，但深度与原请求相同
+// 新请求的重定向次数包括原请求的重定向次数、下载器已跟随的重定向次数和本次重定向，以便调度器限制跨主机的重定向链
+func newRedirectRequest(req *module.Request, httpResp *http.Response, target *url.URL) (*module.Request, error) {
+	method := http.MethodGet
+	var body []byte
+	lastReq := httpResp.Request
+	if lastReq == nil {
+		lastReq = req.HTTPReq()
+	}
+	switch httpResp.StatusCode {
+	case http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		method = lastReq.Method
+		body = req.Body()
+	default:
+		if lastReq.Method == http.MethodHead {
+			method = http.MethodHead
+		}
+	}
+
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+	httpReq, err := http.NewRequest(method, target.String(), bodyReader)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq.Header = req.HTTPReq().Header.Clone()
+	if httpReq.Header == nil {
+		httpReq.Header = http.Header{}
+	}
+	for _, key := range sensitiveHeaders {
+		httpReq.Header.Del(key)
+	}
+	if body == nil {
+		httpReq.Header.Del("Content-Type")
+	}
+
+	redirectReq := module.NewRequest(httpReq, req.Depth())
+	redirectReq.SetSource(module.SOURCE_REDIRECT)
+	redirectReq.SetPriority(req.Priority())
+	redirectReq.SetRedirects(req.Redirects() + uint32(len(module.RedirectChain(httpResp))) + 1)
+	return module.NewChildRequest(req, redirectReq, req.Depth()), nil
+}
@@ -0,0 +1,156 @@
+package downloader
+
+import (
+	"crawler/errors"
+	"crawler/module"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRedirectPolicyCheck(t *testing.T) {
+	if _, err := NewWithRedirectPolicy("D1", &http.Client{}, RedirectPolicy{MaxHops: -1}, nil); err == nil {
+		t.Fatal("No error when creating downloader with negative max hops!")
+	}
+
+	a, _ := url.Parse("http://Sogou.com/a")
+	b, _ := url.Parse("http://sogou.com/b")
+	c, _ := url.Parse("http://sogou.com:8080/b")
+	if !SameHost(a, b) || SameHost(a, c) {
+		t.Fatal("Inconsistent same host result!")
+	}
+}
+
+func TestDownloadRedirect(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("other"))
+	}))
+	defer other.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/in":
+			http.Redirect(w, r, "/final", http.StatusFound)
+		case r.URL.Path == "/out":
+			http.Redirect(w, r, other.URL+"/page", http.StatusFound)
+		case r.URL.Path == "/in-out":
+			http.Redirect(w, r, "/out", http.StatusFound)
+		case r.URL.Path == "/out307":
+			http.Redirect(w, r, other.URL+"/form", http.StatusTemporaryRedirect)
+		case r.URL.Path == "/loop":
+			http.Redirect(w, r, "/loop2", http.StatusFound)
+		case r.URL.Path == "/loop2":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		case strings.HasPrefix(r.URL.Path, "/hop"):
+			n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/hop"))
+			http.Redirect(w, r, "/hop"+strconv.Itoa(n+1), http.StatusFound)
+		default:
+			w.Write([]byte("final"))
+		}
+	}))
+	defer server.Close()
+
+	d, err := NewWithRedirectPolicy("D1", &http.Client{}, RedirectPolicy{MaxHops: 3}, nil)
+	if err != nil {
+		t.Fatalf("创建下载器时出错: %s", err)
+	}
+	download := func(method string, path string, body string) (*module.Response, error) {
+		var httpReq *http.Request
+		if body == "" {
+			httpReq, _ = http.NewRequest(method, server.URL+path, nil)
+		} else {
+			httpReq, _ = http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		}
+		httpReq.Header.Set("Cookie", "session=1")
+		httpReq.Header.Set("User-Agent", "crawler")
+		req := module.NewRequest(httpReq, 2)
+		req.SetMeta("category", "news")
+		return d.Download(req)
+	}
+
+	// 范围内的重定向会被跟随。
+	resp, err := download("GET", "/in", "")
+	if err != nil {
+		t.Fatalf("下载内容时出错: %s", err)
+	}
+	if body, _ := ioutil.ReadAll(resp.HTTPResp().Body); string(body) != "final" || resp.RedirectRequest() != nil {
+		t.Fatalf("范围内的重定向应被跟随: %q", body)
+	}
+	if redirects := resp.FetchInfo().Redirects; len(redirects) != 1 || redirects[0] != server.URL+"/in" {
+		t.Fatalf("重定向链不一致: %v", redirects)
+	}
+
+	// 标记了HandOffRedirects的请求的重定向都会被交给调度器。
+	httpReq, _ := http.NewRequest("GET", server.URL+"/in", nil)
+	req := module.NewRequest(httpReq, 2)
+	req.SetHandOffRedirects(true)
+	resp, err = d.Download(req)
+	if err != nil {
+		t.Fatalf("下载内容时出错: %s", err)
+	}
+	if redirectReq := resp.RedirectRequest(); redirectReq == nil || redirectReq.HTTPReq().URL.String() != server.URL+"/final" {
+		t.Fatalf("范围内的重定向应被交给调度器: %#v", redirectReq)
+	}
+	if redirects := resp.FetchInfo().Redirects; len(redirects) != 0 {
+		t.Fatalf("重定向链不一致: %v", redirects)
+	}
+
+	// 范围外的重定向会被交给调度器。
+	resp, err = download("POST", "/out", "a=1")
+	if err != nil {
+		t.Fatalf("下载内容时出错: %s", err)
+	}
+	if resp.HTTPResp().StatusCode != http.StatusFound {
+		t.Fatalf("状态码不一致。预期: %d, 实际: %d", http.StatusFound, resp.HTTPResp().StatusCode)
+	}
+	redirectReq := resp.RedirectRequest()
+	if redirectReq == nil {
+		t.Fatal("范围外的重定向应被交给调度器!")
+	}
+	httpReq = redirectReq.HTTPReq()
+	if httpReq.URL.String() != other.URL+"/page" || httpReq.Method != "GET" || redirectReq.Body() != nil {
+		t.Fatalf("重定向请求不一致: %s %s (body: %q)", httpReq.Method, httpReq.URL, redirectReq.Body())
+	}
+	if redirectReq.Source() != module.SOURCE_REDIRECT || redirectReq.Depth() != 2 ||
+		redirectReq.Referrer() != server.URL+"/out" || redirectReq.Meta()["category"] != "news" {
+		t.Fatalf("重定向请求不一致: source: %s, depth: %d, referrer: %s, meta: %v",
+			redirectReq.Source(), redirectReq.Depth(), redirectReq.Referrer(), redirectReq.Meta())
+	}
+	if httpReq.Header.Get("Cookie") != "" || httpReq.Header.Get("User-Agent") != "crawler" {
+		t.Fatalf("重定向请求的请求头不一致: %v", httpReq.Header)
+	}
+	if redirectReq.Redirects() != 1 {
+		t.Fatalf("重定向次数不一致。预期: %d, 实际: %d", 1, redirectReq.Redirects())
+	}
+
+	// 重定向次数包括已被跟随的重定向。
+	resp, err = download("GET", "/in-out", "")
+	if err != nil {
+		t.Fatalf("下载内容时出错: %s", err)
+	}
+	if redirectReq = resp.RedirectRequest(); redirectReq == nil || redirectReq.Redirects() != 2 {
+		t.Fatalf("重定向请求不一致: %#v", redirectReq)
+	}
+
+	// 307重定向会保留请求方法和请求体。
+	resp, err = download("POST", "/out307", "a=1")
+	if err != nil {
+		t.Fatalf("下载内容时出错: %s", err)
+	}
+	redirectReq = resp.RedirectRequest()
+	if redirectReq == nil || redirectReq.HTTPReq().Method != "POST" || string(redirectReq.Body()) != "a=1" {
+		t.Fatalf("307重定向请求不一致: %#v", redirectReq)
+	}
+
+	// 重定向循环和过多的重定向会导致下载失败。
+	for _, path := range []string{"/loop", "/hop0"} {
+		_, err := download("GET", path, "")
+		if ce, ok := err.(errors.CrawlerError); !ok || ce.Type() != errors.ERROR_TYPE_DOWNLOADER {
+			t.Fatalf("错误不一致。预期类型: %s, 实际: %v", errors.ERROR_TYPE_DOWNLOADER, err)
+		}
+	}
+}
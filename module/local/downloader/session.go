@@ -0,0 +1,295 @@
+package downloader
+
+import (
+	"crawler/toolkit/publicsuffix"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSession 代表请求的元数据中未指定会话时使用的会话的名称
+const DefaultSession = "default"
+
+// Sessions 代表按名称管理的下载会话的接口类型，实现类型必须是并发安全的
+// 每个会话都有自己的Cookie容器，同一个会话可以被多个下载器共享
+type Sessions interface {
+	// Jar 用于获取给定名称的会话的Cookie容器，会话不存在时会被创建
+	// 名称只能包含字母、数字、'-'和'_'
+	Jar(name string) (http.CookieJar, error)
+	// Names 用于获取所有会话的名称，按字典序排列
+	Names() []string
+	// Save 用于把所有会话的Cookie保存到目录中，未指定目录时不做任何事
+	Save() error
+}
+
+// mySessions 代表下载会话的实现类型
+type mySessions struct {
+	// dir 代表保存会话的目录，为空时不保存
+	dir string
+	// jars 代表会话名称与Cookie容器的映射
+	jars map[string]*persistentJar
+	// lock 代表互斥锁
+	lock sync.Mutex
+}
+
+// NewSessions 用于创建下载会话的管理器
+// 参数dir代表保存会话的目录，每个会话保存为其中的一个"<名称>.json"文件
+// 目录中已有的会话会被加载，目录不存在时会被创建，为空时会话只保存在内存中
+func NewSessions(dir string) (Sessions, error) {
+	sessions := &mySessions{
+		dir:  dir,
+		jars: map[string]*persistentJar{},
+	}
+	if dir == "" {
+		return sessions, nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".json")
+		if checkSessionName(name) != nil {
+			continue
+		}
+		jar := newPersistentJar()
+		if err := jar.load(path); err != nil {
+			return nil, err
+		}
+		sessions.jars[name] = jar
+	}
+	return sessions, nil
+}
+
+func (sessions *mySessions) Jar(name string) (http.CookieJar, error) {
+	if err := checkSessionName(name); err != nil {
+		return nil, err
+	}
+
+	sessions.lock.Lock()
+	defer sessions.lock.Unlock()
+	jar, ok := sessions.jars[name]
+	if !ok {
+		jar = newPersistentJar()
+		sessions.jars[name] = jar
+	}
+	return jar, nil
+}
+
+func (sessions *mySessions) Names() []string {
+	sessions.lock.Lock()
+	defer sessions.lock.Unlock()
+	names := make([]string, 0, len(sessions.jars))
+	for name := range sessions.jars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (sessions *mySessions) Save() error {
+	if sessions.dir == "" {
+		return nil
+	}
+
+	sessions.lock.Lock()
+	defer sessions.lock.Unlock()
+	for name, jar := range sessions.jars {
+		if err := jar.save(filepath.Join(sessions.dir, name+".json")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkSessionName 用于检查会话名称的有效性
+func checkSessionName(name string) error {
+	if name == "" {
+		return genParameterError("空的会话名称")
+	}
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+		default:
+			return genParameterError(fmt.Sprintf("无效的会话名称: %q", name))
+		}
+	}
+	return nil
+}
+
+// storedCookie 代表保存在文件中的Cookie
+type storedCookie struct {
+	// URL 代表设置该Cookie的响应对应的URL
+	URL string `json:"url"`
+	// Cookie 代表Cookie本身，其有效期已被换算为Expires
+	Cookie http.Cookie `json:"cookie"`
+}
+
+// persistentJar 代表可以保存到文件中的Cookie容器
+// 标准库的cookiejar.Jar无法导出其中的Cookie，因此这里同时记录每个被设置的Cookie，
+// 加载时按原样重新设置，由cookiejar.Jar负责域名和路径的匹配
+type persistentJar struct {
+	// jar 代表实际存取Cookie的容器
+	jar *cookiejar.Jar
+	// records 代表已设置的Cookie，键由域名、路径和名称组成
+	records map[string]storedCookie
+	// lock 代表互斥锁
+	lock sync.Mutex
+}
+
+// newPersistentJar 用于创建一个使用默认公共后缀列表的Cookie容器
+func newPersistentJar() *persistentJar {
+	// 参数中设置了公共后缀列表时不会返回错误。
+	jar, _ := cookiejar.New(&cookiejar.Options{
+		PublicSuffixList: publicSuffixList{list: publicsuffix.Default()},
+	})
+	return &persistentJar{
+		jar:     jar,
+		records: map[string]storedCookie{},
+	}
+}
+
+func (jar *persistentJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	jar.jar.SetCookies(u, cookies)
+
+	now := time.Now()
+	origin := (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}).String()
+	jar.lock.Lock()
+	defer jar.lock.Unlock()
+	for _, cookie := range cookies {
+		key := cookieKey(u, cookie)
+		stored := http.Cookie{
+			Name:     cookie.Name,
+			Value:    cookie.Value,
+			Path:     cookie.Path,
+			Domain:   cookie.Domain,
+			Expires:  cookie.Expires,
+			Secure:   cookie.Secure,
+			HttpOnly: cookie.HttpOnly,
+			SameSite: cookie.SameSite,
+		}
+		if cookie.MaxAge < 0 {
+			delete(jar.records, key)
+			continue
+		}
+		if cookie.MaxAge > 0 {
+			stored.Expires = now.Add(time.Duration(cookie.MaxAge) * time.Second)
+		}
+		if !stored.Expires.IsZero() && !stored.Expires.After(now) {
+			delete(jar.records, key)
+			continue
+		}
+		jar.records[key] = storedCookie{URL: origin, Cookie: stored}
+	}
+}
+
+func (jar *persistentJar) Cookies(u *url.URL) []*http.Cookie {
+	return jar.jar.Cookies(u)
+}
+
+// save 用于把未过期的Cookie保存到给定的文件中
+// 没有有效期的会话Cookie同样会被保存，以便下次爬取时沿用登录状态
+func (jar *persistentJar) save(path string) error {
+	now := time.Now()
+	jar.lock.Lock()
+	keys := make([]string, 0, len(jar.records))
+	for key, record := range jar.records {
+		if !record.Cookie.Expires.IsZero() && !record.Cookie.Expires.After(now) {
+			delete(jar.records, key)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	records := make([]storedCookie, 0, len(keys))
+	for _, key := range keys {
+		records = append(records, jar.records[key])
+	}
+	jar.lock.Unlock()
+
+	content, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	// 先写入临时文件再重命名，以免保存中断时损坏已有的文件。
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, content, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// load 用于从给定的文件中加载Cookie，已过期的Cookie会被忽略
+func (jar *persistentJar) load(path string) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var records []storedCookie
+	if err := json.Unmarshal(content, &records); err != nil {
+		return fmt.Errorf("invalid session file %s: %s", path, err)
+	}
+
+	for _, record := range records {
+		u, err := url.Parse(record.URL)
+		if err != nil {
+			continue
+		}
+		cookie := record.Cookie
+		jar.SetCookies(u, []*http.Cookie{&cookie})
+	}
+	return nil
+}
+
+// cookieKey 用于生成Cookie的键，同一个键的Cookie会相互覆盖
+func cookieKey(u *url.URL, cookie *http.Cookie) string {
+	domain := strings.TrimPrefix(strings.ToLower(cookie.Domain), ".")
+	if domain == "" {
+		// 未指定域名的Cookie只属于设置它的主机。
+		domain = "=" + strings.ToLower(u.Hostname())
+	}
+	path := cookie.Path
+	if path == "" || path[0] != '/' {
+		path = defaultCookiePath(u.Path)
+	}
+	return domain + ";" + path + ";" + cookie.Name
+}
+
+// defaultCookiePath 用于获取未指定路径的Cookie的默认路径，见RFC 6265第5.1.4节
+func defaultCookiePath(urlPath string) string {
+	if urlPath == "" || urlPath[0] != '/' {
+		return "/"
+	}
+	i := strings.LastIndex(urlPath, "/")
+	if i == 0 {
+		return "/"
+	}
+	return urlPath[:i]
+}
+
+// publicSuffixList 代表供Cookie容器使用的公共后缀列表
+type publicSuffixList struct {
+	list *publicsuffix.List
+}
+
+func (psl publicSuffixList) PublicSuffix(domain string) string {
+	suffix, _ := psl.list.PublicSuffix(domain)
+	return suffix
+}
+
+func (psl publicSuffixList) String() string {
+	return "crawler/toolkit/publicsuffix"
+}
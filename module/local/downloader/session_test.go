@@ -0,0 +1,155 @@
+package downloader
+
+import (
+	"crawler/module"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSessions(t *testing.T) {
+	sessions, err := NewSessions("")
+	if err != nil {
+		t.Fatalf("An error occurs when creating sessions: %s", err)
+	}
+	for _, name := range []string{"", "../a", "a.b", "a b"} {
+		if _, err := sessions.Jar(name); err == nil {
+			t.Fatalf("No error when getting jar with invalid name %q!", name)
+		}
+	}
+	jar1, _ := sessions.Jar("b")
+	sessions.Jar("a_1")
+	jar2, _ := sessions.Jar("b")
+	if jar1 != jar2 {
+		t.Fatal("The jars of the same session should be the same!")
+	}
+	if names := sessions.Names(); !reflect.DeepEqual(names, []string{"a_1", "b"}) {
+		t.Fatalf("Inconsistent session names: expected: %v, actual: %v", []string{"a_1", "b"}, names)
+	}
+	if err := sessions.Save(); err != nil {
+		t.Fatalf("An error occurs when saving sessions without directory: %s", err)
+	}
+}
+
+func TestSessionsPersistence(t *testing.T) {
+	dir := t.TempDir()
+	sessions, err := NewSessions(dir)
+	if err != nil {
+		t.Fatalf("An error occurs when creating sessions: %s", err)
+	}
+	jar, _ := sessions.Jar("login")
+	u, _ := url.Parse("http://www.sogou.com/account/login")
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "sid", Value: "1", Path: "/"},
+		{Name: "token", Value: "2", Domain: "sogou.com", MaxAge: 3600},
+		{Name: "path", Value: "3"},
+		{Name: "old", Value: "4", Expires: time.Now().Add(-time.Hour)},
+	})
+	// 同名的Cookie会覆盖之前的值，MaxAge为负数的Cookie会被删除。
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "sid", Value: "5", Path: "/"},
+		{Name: "token", Value: "", Domain: "sogou.com", MaxAge: -1},
+	})
+	if err := sessions.Save(); err != nil {
+		t.Fatalf("An error occurs when saving sessions: %s", err)
+	}
+
+	sessions, err = NewSessions(dir)
+	if err != nil {
+		t.Fatalf("An error occurs when loading sessions: %s", err)
+	}
+	if names := sessions.Names(); !reflect.DeepEqual(names, []string{"login"}) {
+		t.Fatalf("Inconsistent session names: expected: %v, actual: %v", []string{"login"}, names)
+	}
+	jar, _ = sessions.Jar("login")
+	check := func(rawURL string, expected map[string]string) {
+		u, _ := url.Parse(rawURL)
+		actual := map[string]string{}
+		for _, cookie := range jar.Cookies(u) {
+			actual[cookie.Name] = cookie.Value
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("Inconsistent cookies for %s: expected: %v, actual: %v", rawURL, expected, actual)
+		}
+	}
+	check("http://www.sogou.com/account/profile", map[string]string{"sid": "5", "path": "3"})
+	check("http://www.sogou.com/", map[string]string{"sid": "5"})
+	check("http://news.sogou.com/", map[string]string{})
+}
+
+func TestDownloadWithSessions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: "logged-in", Path: "/"})
+		default:
+			if cookie, err := r.Cookie("sid"); err == nil {
+				w.Write([]byte(cookie.Value))
+			}
+		}
+	}))
+	defer server.Close()
+
+	sessions, _ := NewSessions("")
+	d1, err := NewWithOptions("D1", &http.Client{}, Options{Sessions: sessions}, nil)
+	if err != nil {
+		t.Fatalf("创建下载器时出错: %s", err)
+	}
+	d2, _ := NewWithOptions("D2", &http.Client{}, Options{Sessions: sessions}, nil)
+	download := func(d module.Downloader, path string, session string) (string, error) {
+		httpReq, _ := http.NewRequest("GET", server.URL+path, nil)
+		req := module.NewRequest(httpReq, 0)
+		if session != "" {
+			req.SetMeta(module.META_SESSION, session)
+		}
+		resp, err := d.Download(req)
+		if err != nil {
+			return "", err
+		}
+		body, _ := ioutil.ReadAll(resp.HTTPResp().Body)
+		return string(body), nil
+	}
+
+	if _, err := download(d1, "/login", "user"); err != nil {
+		t.Fatalf("下载内容时出错: %s", err)
+	}
+	// 同一个会话的Cookie在下载器之间共享。
+	if body, _ := download(d2, "/private", "user"); body != "logged-in" {
+		t.Fatalf("不一致的Cookie: 期望: %q, 实际: %q", "logged-in", body)
+	}
+	// 子请求沿用父请求的会话。
+	httpReq, _ := http.NewRequest("GET", server.URL+"/login", nil)
+	parent := module.NewRequest(httpReq, 0)
+	parent.SetMeta(module.META_SESSION, "user")
+	childHTTPReq, _ := http.NewRequest("GET", server.URL+"/child", nil)
+	child := module.NewChildRequest(parent, module.NewRequest(childHTTPReq, 1), 1)
+	resp, err := d1.Download(child)
+	if err != nil {
+		t.Fatalf("下载内容时出错: %s", err)
+	}
+	if body, _ := ioutil.ReadAll(resp.HTTPResp().Body); string(body) != "logged-in" {
+		t.Fatalf("不一致的Cookie: 期望: %q, 实际: %q", "logged-in", body)
+	}
+	// 其他会话和默认会话中没有该Cookie。
+	if body, _ := download(d1, "/private", "guest"); body != "" {
+		t.Fatalf("其他会话中不应有Cookie: %q", body)
+	}
+	if body, _ := download(d1, "/private", ""); body != "" {
+		t.Fatalf("默认会话中不应有Cookie: %q", body)
+	}
+	if names := sessions.Names(); !reflect.DeepEqual(names, []string{DefaultSession, "guest", "user"}) {
+		t.Fatalf("不一致的会话名称: %v", names)
+	}
+
+	if _, err := download(d1, "/private", "a/b"); err == nil {
+		t.Fatal("使用无效的会话名称时应返回错误!")
+	}
+	d3, _ := New("D3", &http.Client{}, nil)
+	if _, err := download(d3, "/private", "user"); err == nil {
+		t.Fatal("没有会话的下载器处理指定会话的请求时应返回错误!")
+	}
+}
@@ -0,0 +1,93 @@
+package downloader
+
+import (
+	"crawler/module"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// timingTracer 代表用于记录下载的各阶段耗时的跟踪器
+type timingTracer struct {
+	// start 代表开始下载的时间
+	start time.Time
+	// dnsStart 代表最近一次开始DNS查询的时间
+	dnsStart time.Time
+	// connectStart 代表最近一次开始建立连接的时间
+	connectStart time.Time
+	// tlsStart 代表最近一次开始TLS握手的时间
+	tlsStart time.Time
+	// timing 代表已记录的耗时
+	timing module.Timing
+	// lock 代表互斥锁，建立连接的回调函数可能被并发调用
+	lock sync.Mutex
+}
+
+// newTimingTracer 用于创建一个从当前时间开始计时的跟踪器
+func newTimingTracer() *timingTracer {
+	return &timingTracer{start: time.Now()}
+}
+
+// clientTrace 用于生成HTTP请求的跟踪回调
+func (tracer *timingTracer) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			tracer.lock.Lock()
+			defer tracer.lock.Unlock()
+			tracer.dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			tracer.lock.Lock()
+			defer tracer.lock.Unlock()
+			tracer.timing.DNS += since(tracer.dnsStart)
+		},
+		ConnectStart: func(network, addr string) {
+			tracer.lock.Lock()
+			defer tracer.lock.Unlock()
+			if tracer.connectStart.IsZero() {
+				tracer.connectStart = time.Now()
+			}
+		},
+		ConnectDone: func(network, addr string, err error) {
+			tracer.lock.Lock()
+			defer tracer.lock.Unlock()
+			// 并发尝试多个地址时，只记录第一个完成的连接。
+			if !tracer.connectStart.IsZero() {
+				tracer.timing.Connect += since(tracer.connectStart)
+				tracer.connectStart = time.Time{}
+			}
+		},
+		TLSHandshakeStart: func() {
+			tracer.lock.Lock()
+			defer tracer.lock.Unlock()
+			tracer.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			tracer.lock.Lock()
+			defer tracer.lock.Unlock()
+			tracer.timing.TLS += since(tracer.tlsStart)
+		},
+		GotFirstResponseByte: func() {
+			tracer.lock.Lock()
+			defer tracer.lock.Unlock()
+			tracer.timing.TTFB = time.Since(tracer.start)
+		},
+	}
+}
+
+// finish 用于结束计时并返回各阶段的耗时
+func (tracer *timingTracer) finish() module.Timing {
+	tracer.lock.Lock()
+	defer tracer.lock.Unlock()
+	tracer.timing.Total = time.Since(tracer.start)
+	return tracer.timing
+}
+
+// since 用于计算从给定时间开始的耗时，给定时间为零值时返回0
+func since(t time.Time) time.Duration {
+	if t.IsZero() {
+		return 0
+	}
+	return time.Since(t)
+}
@@ -0,0 +1,113 @@
+package module
+
+import (
+	"context"
+	"math"
+)
+
+// Source 代表请求的发现来源
+type Source string
+
+const (
+	// SOURCE_SEED 代表请求是首次请求
+	SOURCE_SEED Source = "seed"
+	// SOURCE_LINK 代表请求是从超链接中发现的
+	SOURCE_LINK Source = "link"
+	// SOURCE_IMG 代表请求是从图片地址中发现的
+	SOURCE_IMG Source = "img"
+	// SOURCE_REDIRECT 代表请求是从重定向中发现的
+	SOURCE_REDIRECT Source = "redirect"
+	// SOURCE_SITEMAP 代表请求是从站点地图中发现的
+	SOURCE_SITEMAP Source = "sitemap"
+)
+
+// META_SESSION 代表请求元数据中用于选择下载会话的键，其值应为会话的名称
+// 子请求会继承父请求的元数据，因此登录后发现的请求会沿用同一个会话
+const META_SESSION = "session"
+
+// Meta 代表请求的元数据，用于在请求与其子请求之间传递上下文
+// 元数据应只包含可以被JSON编码的值，以便请求被保存到检查点或发送给远程组件
+type Meta map[string]interface{}
+
+// Get 用于获取指定键的值
+func (meta Meta) Get(key string) (interface{}, bool) {
+	value, ok := meta[key]
+	return value, ok
+}
+
+// GetString 用于获取指定键的字符串值
+// 若键不存在或值不是字符串，则第二个结果值为false
+func (meta Meta) GetString(key string) (string, bool) {
+	value, ok := meta[key].(string)
+	return value, ok
+}
+
+// GetInt 用于获取指定键的整数值
+// 经过JSON编解码的整数会变为float64，本方法同样接受这种值
+func (meta Meta) GetInt(key string) (int64, bool) {
+	switch value := meta[key].(type) {
+	case int:
+		return int64(value), true
+	case int32:
+		return int64(value), true
+	case int64:
+		return value, true
+	case uint32:
+		return int64(value), true
+	case float64:
+		if value == math.Trunc(value) {
+			return int64(value), true
+		}
+	}
+	return 0, false
+}
+
+// GetBool 用于获取指定键的布尔值
+func (meta Meta) GetBool(key string) (bool, bool) {
+	value, ok := meta[key].(bool)
+	return value, ok
+}
+
+// Copy 用于获取元数据的浅拷贝
+func (meta Meta) Copy() Meta {
+	if meta == nil {
+		return nil
+	}
+
+	copied := make(Meta, len(meta))
+	for key, value := range meta {
+		copied[key] = value
+	}
+	return copied
+}
+
+// requestContextKey 代表上下文中保存请求的键的类型
+type requestContextKey struct{}
+
+// responseContextKey 代表上下文中保存响应的键的类型
+type responseContextKey struct{}
+
+// ContextWithRequest 用于生成带有给定请求的上下文
+// 分析器会把响应对应的请求放入HTTP请求的上下文，以便响应解析函数获取
+func ContextWithRequest(ctx context.Context, req *Request) context.Context {
+	return context.WithValue(ctx, requestContextKey{}, req)
+}
+
+// RequestFromContext 用于从上下文中获取请求
+// 在响应解析函数中，可以通过RequestFromContext(httpResp.Request.Context())获取响应对应的请求
+func RequestFromContext(ctx context.Context) (*Request, bool) {
+	req, ok := ctx.Value(requestContextKey{}).(*Request)
+	return req, ok && req != nil
+}
+
+// ContextWithResponse 用于生成带有给定响应的上下文
+// 分析器会把正在解析的响应放入HTTP请求的上下文，以便响应解析函数获取其下载信息
+func ContextWithResponse(ctx context.Context, resp *Response) context.Context {
+	return context.WithValue(ctx, responseContextKey{}, resp)
+}
+
+// ResponseFromContext 用于从上下文中获取响应
+func ResponseFromContext(ctx context.Context) (*Response, bool) {
+	resp, ok := ctx.Value(responseContextKey{}).(*Response)
+	return resp, ok && resp != nil
+}
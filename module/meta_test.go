@@ -0,0 +1,67 @@
+package module
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestMetaGet(t *testing.T) {
+	var meta Meta
+	if _, ok := meta.Get("key"); ok {
+		t.Fatal("There should be no value in nil meta!")
+	}
+	if meta.Copy() != nil {
+		t.Fatal("The copy of nil meta should be nil!")
+	}
+
+	meta = Meta{"str": "a", "int": 3, "bool": true, "float": 1.5}
+	if value, ok := meta.GetString("str"); !ok || value != "a" {
+		t.Fatalf("Inconsistent string value: expected: %q, actual: %q", "a", value)
+	}
+	if _, ok := meta.GetString("int"); ok {
+		t.Fatal("The int value should not be a string!")
+	}
+	if value, ok := meta.GetInt("int"); !ok || value != 3 {
+		t.Fatalf("Inconsistent int value: expected: %d, actual: %d", 3, value)
+	}
+	if _, ok := meta.GetInt("float"); ok {
+		t.Fatal("The float value should not be an int!")
+	}
+	if value, ok := meta.GetBool("bool"); !ok || !value {
+		t.Fatalf("Inconsistent bool value: expected: %v, actual: %v", true, value)
+	}
+
+	// 经过JSON编解码的整数仍可以按整数获取。
+	b, _ := json.Marshal(meta)
+	var decoded Meta
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatalf("An error occurs when decoding meta: %s", err)
+	}
+	if value, ok := decoded.GetInt("int"); !ok || value != 3 {
+		t.Fatalf("Inconsistent int value: expected: %d, actual: %d", 3, value)
+	}
+}
+
+func TestRequestContext(t *testing.T) {
+	if _, ok := RequestFromContext(context.Background()); ok {
+		t.Fatal("There should be no request in background context!")
+	}
+
+	httpReq, _ := http.NewRequest("GET", "http://cn.bing.com/", nil)
+	req := NewRequest(httpReq, 0)
+	ctx := ContextWithRequest(context.Background(), req)
+	if actual, ok := RequestFromContext(ctx); !ok || actual != req {
+		t.Fatalf("Inconsistent request: expected: %p, actual: %p", req, actual)
+	}
+
+	if _, ok := ResponseFromContext(ctx); ok {
+		t.Fatal("There should be no response in the context!")
+	}
+	resp := NewResponseForRequest(&http.Response{}, req)
+	ctx = ContextWithResponse(ctx, resp)
+	if actual, ok := ResponseFromContext(ctx); !ok || actual != resp {
+		t.Fatalf("Inconsistent response: expected: %p, actual: %p", resp, actual)
+	}
+}
@@ -3,7 +3,9 @@ package module
 import (
 	"crawler/errors"
 	"fmt"
+	"sort"
 	"sync"
+	"time"
 )
 
 // Registrar 代表组件注册器的接口
@@ -13,8 +15,16 @@ type Registrar interface {
 	// Unregister 用于注释组件实例
 	Unregister(mid MID) (bool, error)
 	// Get 用于获取一个指定类型的组件的实例
-	// 本函数应该基于负载均衡策略返回实例
+	// 本函数应该基于负载均衡策略返回实例，并跳过熔断器已打开的实例
 	Get(moduleType Type) (Module, error)
+	// GetByKey 用于基于给定的键获取一个指定类型的组件的实例
+	// 参数key会被传给负载均衡策略，如一致性散列策略会据此把相同的键分配给同一个实例
+	GetByKey(moduleType Type, key string) (Module, error)
+	// Report 用于报告对组件的一次调用的结果，以更新组件的健康状态
+	Report(mid MID, success bool)
+	// Health 用于获取组件的健康状态的摘要
+	// 若组件未注册或健康检查已禁用，则第二个结果值为false
+	Health(mid MID) (HealthSummaryStruct, bool)
 	// GetAllByType 用于获取指定类型的所有组件类型
 	GetAllByType(moduleType Type) (map[MID]Module, error)
 	// GetAll 用于获取所有组件实例
@@ -27,15 +37,61 @@ type Registrar interface {
 type myRegistrar struct {
 	// moduleTypeMap 代表组件类型与对应组件实例的映射
 	moduleTypeMap map[Type]map[MID]Module
+	// healthArgs 代表已填充默认值的健康检查参数
+	healthArgs HealthArgs
+	// healthMap 代表组件ID与组件健康状态的映射
+	healthMap map[MID]*health
+	// selectors 代表组件类型与负载均衡策略的映射
+	selectors map[Type]Selector
 	// rwlock 代表组件注册专用读写锁
 	rwlock sync.RWMutex
 }
 
-// NewRegistrar 用于创建一个组件注册器的实例
+// RegistrarArgs 代表组件注册器的参数
+type RegistrarArgs struct {
+	// Health 代表组件健康检查的参数
+	Health HealthArgs
+	// Selectors 代表组件类型与负载均衡策略的映射
+	// 未指定策略的组件类型会使用选择评分最低的实例的策略
+	Selectors map[Type]Selector
+}
+
+// NewRegistrar 用于创建一个使用默认参数的组件注册器的实例
 func NewRegistrar() Registrar {
+	registrar, _ := NewRegistrarWithArgs(RegistrarArgs{})
+	return registrar
+}
+
+// NewRegistrarWithArgs 用于根据给定的参数创建一个组件注册器的实例
+func NewRegistrarWithArgs(args RegistrarArgs) (Registrar, error) {
+	if err := args.Health.Check(); err != nil {
+		return nil, err
+	}
+
+	selectors := map[Type]Selector{}
+	for moduleType, selector := range args.Selectors {
+		if !LegalType(moduleType) {
+			errMsg := fmt.Sprintf("非法 module 类型: %s", moduleType)
+			return nil, errors.NewIllegalParameterError(errMsg)
+		}
+		if selector == nil {
+			errMsg := fmt.Sprintf("无负载均衡策略实例: %s", moduleType)
+			return nil, errors.NewIllegalParameterError(errMsg)
+		}
+		selectors[moduleType] = selector
+	}
+	for _, moduleType := range []Type{TYPE_DOWNLOADER, TYPE_ANALYZER, TYPE_PIPELINE} {
+		if _, ok := selectors[moduleType]; !ok {
+			selectors[moduleType] = NewScoreSelector()
+		}
+	}
+
 	return &myRegistrar{
 		moduleTypeMap: map[Type]map[MID]Module{},
-	}
+		healthArgs:    args.Health.withDefaults(),
+		healthMap:     map[MID]*health{},
+		selectors:     selectors,
+	}, nil
 }
 
 func (registrar *myRegistrar) Register(module Module) (bool, error) {
@@ -69,6 +125,9 @@ func (registrar *myRegistrar) Register(module Module) (bool, error) {
 
 	modules[mid] = module
 	registrar.moduleTypeMap[moduleType] = modules
+	if !registrar.healthArgs.Disabled {
+		registrar.healthMap[mid] = newHealth(registrar.healthArgs)
+	}
 	return true, nil
 }
 
@@ -86,6 +145,7 @@ func (registrar *myRegistrar) Unregister(mid MID) (bool, error) {
 	if modules, ok := registrar.moduleTypeMap[moduleType]; ok {
 		if _, ok := modules[mid]; ok {
 			delete(modules, mid)
+			delete(registrar.healthMap, mid)
 			deleted = true
 		}
 	}
@@ -94,25 +154,87 @@ func (registrar *myRegistrar) Unregister(mid MID) (bool, error) {
 }
 
 // Get 用于获取一个指定类型的组件的实例
-// 本函数会基于负载均衡策略返回实例
 func (registrar *myRegistrar) Get(moduleType Type) (Module, error) {
+	return registrar.GetByKey(moduleType, "")
+}
+
+// GetByKey 用于基于给定的键获取一个指定类型的组件的实例
+// 本函数会基于该类型的负载均衡策略返回实例，熔断器已打开的实例会被跳过
+// 若所有实例的熔断器都已打开，则仍会从所有实例中选择，以免爬取流程停滞
+func (registrar *myRegistrar) GetByKey(moduleType Type, key string) (Module, error) {
 	modules, err := registrar.GetAllByType(moduleType)
 	if err != nil {
 		return nil, err
 	}
 
-	minScore := uint64(0)
-	var selectedModule Module
+	all := make([]Module, 0, len(modules))
+	for _, module := range modules {
+		all = append(all, module)
+	}
+	// 按组件ID排序，以使负载均衡策略的选择结果稳定。
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].ID() < all[j].ID()
+	})
+
+	selector := registrar.selectors[moduleType]
+	now := time.Now()
+	candidates := registrar.filterAvailable(all, now)
+	for len(candidates) > 0 {
+		selectedModule := selector.Select(candidates, key)
+		h := registrar.getHealth(selectedModule.ID())
+		if h == nil || h.acquire(now) {
+			return selectedModule, nil
+		}
+
+		// 实例在选择期间变得不可用，从候选中移除后重新选择。
+		candidates = removeModule(candidates, selectedModule.ID())
+	}
+
+	return selector.Select(all, key), nil
+}
+
+// filterAvailable 用于从候选实例中筛选出可以被获取的实例
+func (registrar *myRegistrar) filterAvailable(candidates []Module, now time.Time) []Module {
+	available := make([]Module, 0, len(candidates))
+	for _, module := range candidates {
+		if h := registrar.getHealth(module.ID()); h != nil && !h.available(now) {
+			continue
+		}
+		available = append(available, module)
+	}
+	return available
+}
+
+// removeModule 用于从实例列表中移除指定ID的实例
+func removeModule(modules []Module, mid MID) []Module {
+	result := make([]Module, 0, len(modules))
 	for _, module := range modules {
-		SetScore(module)
-		score := module.Score()
-		if minScore == 0 || score < minScore {
-			selectedModule = module
-			minScore = score
+		if module.ID() != mid {
+			result = append(result, module)
 		}
 	}
+	return result
+}
+
+// getHealth 用于获取组件的健康状态，健康检查已禁用或组件未注册时返回nil
+func (registrar *myRegistrar) getHealth(mid MID) *health {
+	registrar.rwlock.RLock()
+	defer registrar.rwlock.RUnlock()
+	return registrar.healthMap[mid]
+}
 
-	return selectedModule, nil
+func (registrar *myRegistrar) Report(mid MID, success bool) {
+	if h := registrar.getHealth(mid); h != nil {
+		h.report(success, time.Now())
+	}
+}
+
+func (registrar *myRegistrar) Health(mid MID) (HealthSummaryStruct, bool) {
+	h := registrar.getHealth(mid)
+	if h == nil {
+		return HealthSummaryStruct{}, false
+	}
+	return h.summary(), true
 }
 
 // GetAllByType 用于获取指定类型的所有组件实例
@@ -159,4 +281,5 @@ func (registrar *myRegistrar) Clear() {
 	defer registrar.rwlock.Unlock()
 
 	registrar.moduleTypeMap = map[Type]map[MID]Module{}
+	registrar.healthMap = map[MID]*health{}
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net"
 	"testing"
+	"time"
 )
 
 func TestRegNew(t *testing.T) {
@@ -273,3 +274,114 @@ func TestModuleAllInParallel(t *testing.T) {
 		})
 	})
 }
+
+func TestModuleGetWithHealth(t *testing.T) {
+	if _, err := NewRegistrarWithArgs(RegistrarArgs{Health: HealthArgs{MaxErrorRate: 2}}); err == nil {
+		t.Fatal("No error when creating registrar with invalid health args!")
+	}
+
+	registrar, err := NewRegistrarWithArgs(RegistrarArgs{
+		Health: HealthArgs{FailureThreshold: 1, OpenTimeout: time.Hour},
+	})
+	if err != nil {
+		t.Fatalf("创建 registrar 时出错: %s", err)
+	}
+
+	mt := TYPE_DOWNLOADER
+	for i := 0; i < 2; i++ {
+		mid := MID(fmt.Sprintf("%s%d", legalTypeLetterMap[mt], DefaultSNGen.Get()))
+		if _, err := registrar.Register(fakeModuleFuncMap[mt](mid)); err != nil {
+			t.Fatalf("注册模块实例时出错: %s (mid: %s)", err, mid)
+		}
+	}
+
+	broken, _ := registrar.Get(mt)
+	registrar.Report(broken.ID(), false)
+	health, ok := registrar.Health(broken.ID())
+	if !ok || health.State != BREAKER_STATE_OPEN || health.Failures != 1 {
+		t.Fatalf("Inconsistent health summary: %#v", health)
+	}
+
+	// 熔断器已打开的实例会被跳过。
+	for i := 0; i < 10; i++ {
+		m, err := registrar.Get(mt)
+		if err != nil || m == nil || m.ID() == broken.ID() {
+			t.Fatalf("The module %q with open breaker should be skipped! (err: %v)", broken.ID(), err)
+		}
+		registrar.Report(m.ID(), true)
+	}
+
+	// 所有实例的熔断器都已打开时，仍会返回实例。
+	healthy, _ := registrar.Get(mt)
+	registrar.Report(healthy.ID(), false)
+	if m, err := registrar.Get(mt); err != nil || m == nil {
+		t.Fatalf("Couldn't get module when all breakers are open: %v", err)
+	}
+
+	if _, ok := registrar.Health(MID("D0")); ok {
+		t.Fatal("There should be no health of an unregistered module!")
+	}
+	registrar.Unregister(broken.ID())
+	if _, ok := registrar.Health(broken.ID()); ok {
+		t.Fatal("There should be no health of an unregistered module!")
+	}
+
+	// 禁用健康检查后不再记录健康状态。
+	registrar, _ = NewRegistrarWithArgs(RegistrarArgs{Health: HealthArgs{Disabled: true}})
+	registrar.Register(broken)
+	registrar.Report(broken.ID(), false)
+	if _, ok := registrar.Health(broken.ID()); ok {
+		t.Fatal("There should be no health when health check is disabled!")
+	}
+}
+
+func TestModuleGetWithSelector(t *testing.T) {
+	invalidArgsList := []RegistrarArgs{
+		{Selectors: map[Type]Selector{Type("X"): NewRoundRobinSelector()}},
+		{Selectors: map[Type]Selector{TYPE_DOWNLOADER: nil}},
+	}
+	for _, args := range invalidArgsList {
+		if _, err := NewRegistrarWithArgs(args); err == nil {
+			t.Fatalf("No error when creating registrar with invalid selectors: %v", args.Selectors)
+		}
+	}
+
+	selector, _ := NewConsistentHashSelector(0)
+	registrar, err := NewRegistrarWithArgs(RegistrarArgs{
+		Health:    HealthArgs{FailureThreshold: 1, OpenTimeout: time.Hour},
+		Selectors: map[Type]Selector{TYPE_DOWNLOADER: selector},
+	})
+	if err != nil {
+		t.Fatalf("创建 registrar 时出错: %s", err)
+	}
+
+	mt := TYPE_DOWNLOADER
+	for i := 0; i < 3; i++ {
+		mid := MID(fmt.Sprintf("%s%d", legalTypeLetterMap[mt], DefaultSNGen.Get()))
+		if _, err := registrar.Register(fakeModuleFuncMap[mt](mid)); err != nil {
+			t.Fatalf("注册模块实例时出错: %s (mid: %s)", err, mid)
+		}
+	}
+
+	// 相同的键总会得到相同的实例。
+	m1, err := registrar.GetByKey(mt, "sogou.com")
+	if err != nil {
+		t.Fatalf("获取模块实例时出错: %s", err)
+	}
+	for i := 0; i < 10; i++ {
+		if m, _ := registrar.GetByKey(mt, "sogou.com"); m.ID() != m1.ID() {
+			t.Fatalf("Inconsistent MID: expected: %s, actual: %s", m1.ID(), m.ID())
+		}
+	}
+
+	// 熔断器已打开的实例会被跳过，其键会被重新分配。
+	registrar.Report(m1.ID(), false)
+	m2, err := registrar.GetByKey(mt, "sogou.com")
+	if err != nil || m2.ID() == m1.ID() {
+		t.Fatalf("The module %q with open breaker should be skipped! (err: %v)", m1.ID(), err)
+	}
+
+	if _, err := registrar.GetByKey(illegalTypes[0], "sogou.com"); err == nil {
+		t.Fatalf("获取具有非法类型 %q 的模块实例时没有错误!", illegalTypes[0])
+	}
+}
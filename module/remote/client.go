@@ -0,0 +1,270 @@
+package remote
+
+import (
+	"bytes"
+	"crawler/module"
+	"crawler/module/stub"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultTimeout 代表未给定HTTP客户端时调用远程组件的超时时间
+// 远程组件失去响应时，处理流程不会因此被一直阻塞，组件也能被正常移除
+const defaultTimeout = 60 * time.Second
+
+// client 代表远程组件的客户端
+type client struct {
+	// baseURL 代表远程组件的接口地址的前缀
+	baseURL string
+	// httpClient 代表用于调用远程组件的HTTP客户端
+	httpClient *http.Client
+}
+
+// newClient 用于根据组件ID中的网络地址创建远程组件的客户端
+func newClient(mid module.MID, httpClient *http.Client) (*client, error) {
+	parts, err := module.SplitMID(mid)
+	if err != nil {
+		return nil, err
+	}
+
+	if parts[2] == "" {
+		_, moduleType := module.GetType(mid)
+		errMsg := fmt.Sprintf("MID %q 中没有网络地址", mid)
+		return nil, genParameterError(moduleType, errMsg)
+	}
+
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: defaultTimeout}
+	}
+	return &client{baseURL: "http://" + parts[2], httpClient: httpClient}, nil
+}
+
+// call 用于调用远程组件的接口
+func (c *client) call(path string, args interface{}, result interface{}) error {
+	body, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+
+	httpResp, err := c.httpClient.Post(c.baseURL+path, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(httpResp.Body)
+		return fmt.Errorf("remote module error: %s: %s", httpResp.Status, bytes.TrimSpace(msg))
+	}
+	return json.NewDecoder(httpResp.Body).Decode(result)
+}
+
+// remoteDownloader 代表远程下载器的客户端的实现类型
+type remoteDownloader struct {
+	// stub.ModuleInternal 代表组件基础实例
+	stub.ModuleInternal
+	// client 代表远程组件的客户端
+	client *client
+}
+
+// NewDownloader 用于创建一个调用远程下载器的下载器实例
+// 远程下载器的网络地址取自组件ID，参数httpClient为nil时使用超时时间为defaultTimeout的HTTP客户端
+func NewDownloader(mid module.MID, httpClient *http.Client, scoreCalculator module.CalculateScore) (module.Downloader, error) {
+	moduleBase, err := stub.NewModuleInternal(mid, scoreCalculator)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := newClient(mid, httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	return &remoteDownloader{ModuleInternal: moduleBase, client: c}, nil
+}
+
+func (downloader *remoteDownloader) Download(req *module.Request) (*module.Response, error) {
+	downloader.ModuleInternal.IncrHandlingNumber()
+	defer downloader.ModuleInternal.DecrHandlingNumber()
+
+	downloader.ModuleInternal.IncrCalledCount()
+	args, err := encodeRequest(req)
+	if err != nil {
+		return nil, genParameterError(module.TYPE_DOWNLOADER, err.Error())
+	}
+
+	downloader.ModuleInternal.IncrAcceptedCount()
+	var result downloadResult
+	if err = downloader.client.call(pathDownload, args, &result); err != nil {
+		return nil, genError(module.TYPE_DOWNLOADER, err.Error())
+	}
+
+	var resp *module.Response
+	if result.Response != nil {
+		if resp, err = decodeResponse(result.Response); err != nil {
+			return nil, genError(module.TYPE_DOWNLOADER, err.Error())
+		}
+		// 响应对应的是本地的请求，以保留其父请求等无法传输的信息。
+		fetchInfo := resp.FetchInfo()
+		redirectReq := resp.RedirectRequest()
+		cached := resp.Cached()
+		resp = module.NewResponseForRequest(resp.HTTPResp(), req)
+		resp.SetFetchInfo(fetchInfo)
+		resp.SetCached(cached)
+		if redirectReq != nil {
+			resp.SetRedirectRequest(module.NewChildRequest(req, redirectReq, req.Depth()))
+		}
+		resp.TrackBody()
+	}
+	if result.Error != nil {
+		return resp, result.Error.decode()
+	}
+
+	downloader.ModuleInternal.IncrCompletedCount()
+	return resp, nil
+}
+
+// remoteAnalyzer 代表远程分析器的客户端的实现类型
+type remoteAnalyzer struct {
+	// stub.ModuleInternal 代表组件基础实例
+	stub.ModuleInternal
+	// client 代表远程组件的客户端
+	client *client
+}
+
+// NewAnalyzer 用于创建一个调用远程分析器的分析器实例
+// 远程分析器的网络地址取自组件ID，参数httpClient为nil时使用超时时间为defaultTimeout的HTTP客户端
+func NewAnalyzer(mid module.MID, httpClient *http.Client, scoreCalculator module.CalculateScore) (module.Analyzer, error) {
+	moduleBase, err := stub.NewModuleInternal(mid, scoreCalculator)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := newClient(mid, httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	return &remoteAnalyzer{ModuleInternal: moduleBase, client: c}, nil
+}
+
+// RespParsers 会返回nil，因为响应解析函数只存在于远程分析器中
+func (analyzer *remoteAnalyzer) RespParsers() []module.ParseResponse {
+	return nil
+}
+
+func (analyzer *remoteAnalyzer) Analyze(resp *module.Response) ([]module.Data, []error) {
+	analyzer.ModuleInternal.IncrHandlingNumber()
+	defer analyzer.ModuleInternal.DecrHandlingNumber()
+
+	analyzer.ModuleInternal.IncrCalledCount()
+	args, err := encodeResponse(resp)
+	if err != nil {
+		return nil, []error{genParameterError(module.TYPE_ANALYZER, err.Error())}
+	}
+
+	analyzer.ModuleInternal.IncrAcceptedCount()
+	var result analyzeResult
+	if err = analyzer.client.call(pathAnalyze, args, &result); err != nil {
+		return nil, []error{genError(module.TYPE_ANALYZER, err.Error())}
+	}
+
+	dataList, err := decodeDataList(result.Data)
+	errs := decodeErrors(result.Errors)
+	if err != nil {
+		errs = append(errs, genError(module.TYPE_ANALYZER, err.Error()))
+	}
+	// 父请求无法传输，所以需要把新请求重新关联到本地的请求。
+	if parent := resp.Request(); parent != nil {
+		for i, data := range dataList {
+			if req, ok := data.(*module.Request); ok {
+				dataList[i] = module.NewChildRequest(parent, req, req.Depth())
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		analyzer.ModuleInternal.IncrCompletedCount()
+	}
+	return dataList, errs
+}
+
+// remotePipeline 代表远程条目处理管道的客户端的实现类型
+type remotePipeline struct {
+	// stub.ModuleInternal 代表组件基础实例
+	stub.ModuleInternal
+	// client 代表远程组件的客户端
+	client *client
+	// failFast 代表最近一次设置的快速失败标志
+	failFast bool
+	// lock 代表专用于快速失败标志的互斥锁
+	lock sync.Mutex
+}
+
+// NewPipeline 用于创建一个调用远程条目处理管道的条目处理管道实例
+// 远程条目处理管道的网络地址取自组件ID，参数httpClient为nil时使用超时时间为defaultTimeout的HTTP客户端
+// 条目中的值会以JSON形式传输，因此远程条目处理管道收到的是与之对应的JSON类型的值
+func NewPipeline(mid module.MID, httpClient *http.Client, scoreCalculator module.CalculateScore) (module.Pipeline, error) {
+	moduleBase, err := stub.NewModuleInternal(mid, scoreCalculator)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := newClient(mid, httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	return &remotePipeline{ModuleInternal: moduleBase, client: c}, nil
+}
+
+// ItemProcessors 会返回nil，因为条目处理函数只存在于远程条目处理管道中
+func (pipeline *remotePipeline) ItemProcessors() []module.ProcessItem {
+	return nil
+}
+
+func (pipeline *remotePipeline) Send(item module.Item) []error {
+	pipeline.ModuleInternal.IncrHandlingNumber()
+	defer pipeline.ModuleInternal.DecrHandlingNumber()
+
+	pipeline.ModuleInternal.IncrCalledCount()
+	if item == nil {
+		return []error{genParameterError(module.TYPE_PIPELINE, "nil item")}
+	}
+
+	pipeline.ModuleInternal.IncrAcceptedCount()
+	var result sendResult
+	if err := pipeline.client.call(pathSend, sendArgs{Item: item}, &result); err != nil {
+		return []error{genError(module.TYPE_PIPELINE, err.Error())}
+	}
+
+	errs := decodeErrors(result.Errors)
+	if len(errs) == 0 {
+		pipeline.ModuleInternal.IncrCompletedCount()
+	}
+	return errs
+}
+
+// FailFast 会返回最近一次成功设置的快速失败标志
+func (pipeline *remotePipeline) FailFast() bool {
+	pipeline.lock.Lock()
+	defer pipeline.lock.Unlock()
+	return pipeline.failFast
+}
+
+// SetFailFast 会设置远程条目处理管道的快速失败标志，设置失败时只记录日志
+func (pipeline *remotePipeline) SetFailFast(failFast bool) {
+	pipeline.lock.Lock()
+	defer pipeline.lock.Unlock()
+
+	var result failFastArgs
+	if err := pipeline.client.call(pathFailFast, failFastArgs{FailFast: failFast}, &result); err != nil {
+		logger.Warnf("Couldn't set fail fast of remote pipeline %q: %s", pipeline.ID(), err)
+		return
+	}
+	pipeline.failFast = result.FailFast
+}
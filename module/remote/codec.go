@@ -0,0 +1,332 @@
+package remote
+
+import (
+	"bytes"
+	"crawler/errors"
+	"crawler/module"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+// wireRequest 代表请求在网络上传输时的形式
+type wireRequest struct {
+	Method    string        `json:"method"`
+	URL       string        `json:"url"`
+	Header    http.Header   `json:"header,omitempty"`
+	Body      []byte        `json:"body,omitempty"`
+	Depth     uint32        `json:"depth"`
+	Priority  int           `json:"priority,omitempty"`
+	Source    module.Source `json:"source,omitempty"`
+	Meta      module.Meta   `json:"meta,omitempty"`
+	Redirects uint32        `json:"redirects,omitempty"`
+	HandOff   bool          `json:"hand_off_redirects,omitempty"`
+}
+
+// wireResponse 代表响应在网络上传输时的形式
+// 响应体会被完整读出后传输
+type wireResponse struct {
+	Status     string       `json:"status"`
+	StatusCode int          `json:"status_code"`
+	Proto      string       `json:"proto"`
+	Header     http.Header  `json:"header,omitempty"`
+	Body       []byte       `json:"body,omitempty"`
+	Depth      uint32       `json:"depth"`
+	Request    *wireRequest `json:"request,omitempty"`
+	// Fetch 代表响应的下载信息
+	Fetch module.FetchInfo `json:"fetch"`
+	// Redirect 代表下载器未跟随的重定向请求
+	Redirect *wireRequest `json:"redirect,omitempty"`
+	// Cached 代表响应是否来自下载器的缓存
+	Cached bool `json:"cached,omitempty"`
+}
+
+// wireData 代表分析器产生的数据在网络上传输时的形式
+// 两个字段中有且仅有一个不为nil
+type wireData struct {
+	Request *wireRequest `json:"request,omitempty"`
+	Item    module.Item  `json:"item,omitempty"`
+}
+
+// wireError 代表错误在网络上传输时的形式
+type wireError struct {
+	Type    errors.ErrorType `json:"type,omitempty"`
+	Message string           `json:"message"`
+}
+
+// downloadResult 代表下载接口的结果
+type downloadResult struct {
+	Response *wireResponse `json:"response,omitempty"`
+	Error    *wireError    `json:"error,omitempty"`
+}
+
+// analyzeResult 代表分析接口的结果
+type analyzeResult struct {
+	Data   []wireData  `json:"data,omitempty"`
+	Errors []wireError `json:"errors,omitempty"`
+}
+
+// sendArgs 代表条目发送接口的参数
+type sendArgs struct {
+	Item module.Item `json:"item"`
+}
+
+// sendResult 代表条目发送接口的结果
+type sendResult struct {
+	Errors []wireError `json:"errors,omitempty"`
+}
+
+// failFastArgs 代表快速失败设置接口的参数
+type failFastArgs struct {
+	FailFast bool `json:"fail_fast"`
+}
+
+// encodeRequest 用于把请求转换为传输形式
+func encodeRequest(req *module.Request) (*wireRequest, error) {
+	if req == nil || !req.Valid() {
+		return nil, fmt.Errorf("invalid request")
+	}
+
+	httpReq := req.HTTPReq()
+	return &wireRequest{
+		Method:    httpReq.Method,
+		URL:       httpReq.URL.String(),
+		Header:    httpReq.Header,
+		Body:      req.Body(),
+		Depth:     req.Depth(),
+		Priority:  req.Priority(),
+		Source:    req.Source(),
+		Meta:      req.Meta(),
+		Redirects: req.Redirects(),
+		HandOff:   req.HandOffRedirects(),
+	}, nil
+}
+
+// decodeRequest 用于从传输形式还原请求
+func decodeRequest(wr *wireRequest) (*module.Request, error) {
+	if wr == nil {
+		return nil, fmt.Errorf("nil request")
+	}
+
+	var body io.Reader
+	if wr.Body != nil {
+		body = bytes.NewReader(wr.Body)
+	}
+	httpReq, err := http.NewRequest(wr.Method, wr.URL, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if wr.Header != nil {
+		httpReq.Header = wr.Header
+	}
+	req := module.NewRequest(httpReq, wr.Depth)
+	req.SetPriority(wr.Priority)
+	req.SetRedirects(wr.Redirects)
+	req.SetHandOffRedirects(wr.HandOff)
+	setRequestMeta(req, wr)
+	return req, nil
+}
+
+// setRequestMeta 用于把传输形式中的发现来源和元数据设置到请求
+func setRequestMeta(req *module.Request, wr *wireRequest) {
+	req.SetSource(wr.Source)
+	for key, value := range wr.Meta {
+		req.SetMeta(key, value)
+	}
+}
+
+// encodeResponse 用于把响应转换为传输形式，响应体会被读出并关闭
+func encodeResponse(resp *module.Response) (*wireResponse, error) {
+	if resp == nil || resp.HTTPResp() == nil {
+		return nil, fmt.Errorf("invalid response")
+	}
+
+	httpResp := resp.HTTPResp()
+	wr := &wireResponse{
+		Status:     httpResp.Status,
+		StatusCode: httpResp.StatusCode,
+		Proto:      httpResp.Proto,
+		Header:     httpResp.Header,
+		Depth:      resp.Depth(),
+		Fetch:      resp.FetchInfo(),
+		Cached:     resp.Cached(),
+	}
+
+	if redirectReq := resp.RedirectRequest(); redirectReq != nil {
+		redirect, err := encodeRequest(redirectReq)
+		if err != nil {
+			return nil, err
+		}
+		wr.Redirect = redirect
+	}
+
+	if httpResp.Body != nil {
+		defer httpResp.Body.Close()
+		body, err := ioutil.ReadAll(httpResp.Body)
+		if err != nil {
+			return nil, err
+		}
+		wr.Body = body
+	}
+
+	if httpReq := httpResp.Request; httpReq != nil && httpReq.URL != nil {
+		wr.Request = &wireRequest{
+			Method: httpReq.Method,
+			URL:    httpReq.URL.String(),
+			Header: httpReq.Header,
+			Depth:  resp.Depth(),
+		}
+		if req := resp.Request(); req != nil {
+			wr.Request.Source = req.Source()
+			wr.Request.Meta = req.Meta()
+		}
+	}
+	return wr, nil
+}
+
+// decodeResponse 用于从传输形式还原响应
+func decodeResponse(wr *wireResponse) (*module.Response, error) {
+	if wr == nil {
+		return nil, fmt.Errorf("nil response")
+	}
+
+	httpResp := &http.Response{
+		Status:        wr.Status,
+		StatusCode:    wr.StatusCode,
+		Proto:         wr.Proto,
+		Header:        wr.Header,
+		Body:          ioutil.NopCloser(bytes.NewReader(wr.Body)),
+		ContentLength: int64(len(wr.Body)),
+	}
+	if httpResp.Header == nil {
+		httpResp.Header = http.Header{}
+	}
+	httpResp.ProtoMajor, httpResp.ProtoMinor, _ = http.ParseHTTPVersion(wr.Proto)
+
+	var redirectReq *module.Request
+	if wr.Redirect != nil {
+		var err error
+		if redirectReq, err = decodeRequest(wr.Redirect); err != nil {
+			return nil, err
+		}
+	}
+
+	if wr.Request != nil {
+		reqURL, err := url.Parse(wr.Request.URL)
+		if err != nil {
+			return nil, err
+		}
+		httpResp.Request = &http.Request{
+			Method: wr.Request.Method,
+			URL:    reqURL,
+			Host:   reqURL.Host,
+			Header: wr.Request.Header,
+		}
+		req := module.NewRequest(httpResp.Request, wr.Depth)
+		setRequestMeta(req, wr.Request)
+		resp := module.NewResponseForRequest(httpResp, req)
+		resp.SetFetchInfo(wr.Fetch)
+		resp.SetRedirectRequest(redirectReq)
+		resp.SetCached(wr.Cached)
+		return resp, nil
+	}
+	resp := module.NewResponse(httpResp, wr.Depth)
+	resp.SetFetchInfo(wr.Fetch)
+	resp.SetRedirectRequest(redirectReq)
+	resp.SetCached(wr.Cached)
+	return resp, nil
+}
+
+// encodeDataList 用于把分析器产生的数据转换为传输形式
+func encodeDataList(dataList []module.Data) ([]wireData, error) {
+	result := make([]wireData, 0, len(dataList))
+	for _, data := range dataList {
+		switch d := data.(type) {
+		case *module.Request:
+			wr, err := encodeRequest(d)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, wireData{Request: wr})
+		case module.Item:
+			result = append(result, wireData{Item: d})
+		case nil:
+		default:
+			return nil, fmt.Errorf("unsupported data type %T", data)
+		}
+	}
+	return result, nil
+}
+
+// decodeDataList 用于从传输形式还原分析器产生的数据
+func decodeDataList(wds []wireData) ([]module.Data, error) {
+	result := make([]module.Data, 0, len(wds))
+	for _, wd := range wds {
+		switch {
+		case wd.Request != nil:
+			req, err := decodeRequest(wd.Request)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, req)
+		case wd.Item != nil:
+			result = append(result, wd.Item)
+		}
+	}
+	return result, nil
+}
+
+// encodeError 用于把错误转换为传输形式
+func encodeError(err error) wireError {
+	we := wireError{Message: err.Error()}
+	if ce, ok := err.(errors.CrawlerError); ok {
+		we.Type = ce.Type()
+	}
+	return we
+}
+
+// encodeErrors 用于把错误列表转换为传输形式
+func encodeErrors(errs []error) []wireError {
+	var result []wireError
+	for _, err := range errs {
+		if err != nil {
+			result = append(result, encodeError(err))
+		}
+	}
+	return result
+}
+
+// decodeErrors 用于从传输形式还原错误列表
+func decodeErrors(wes []wireError) []error {
+	var result []error
+	for _, we := range wes {
+		result = append(result, we.decode())
+	}
+	return result
+}
+
+// decode 用于从传输形式还原错误
+// 带有类型的错误会被还原为爬虫错误，且错误信息保持不变
+func (we wireError) decode() error {
+	if we.Type != "" {
+		return &remoteError{errType: we.Type, errMsg: we.Message}
+	}
+	return fmt.Errorf("%s", we.Message)
+}
+
+// remoteError 代表由远程组件返回的爬虫错误
+type remoteError struct {
+	errType errors.ErrorType
+	errMsg  string
+}
+
+func (re *remoteError) Type() errors.ErrorType {
+	return re.errType
+}
+
+func (re *remoteError) Error() string {
+	return re.errMsg
+}
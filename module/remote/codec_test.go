@@ -0,0 +1,140 @@
+package remote
+
+import (
+	"crawler/module"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCodecRequest(t *testing.T) {
+	httpReq, _ := http.NewRequest("POST", "http://sogou.com/search?q=go", strings.NewReader("a=1"))
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req := module.NewRequest(httpReq, 2)
+	req.SetPriority(5)
+	req.SetRedirects(1)
+	req.SetHandOffRedirects(true)
+	req.SetSource(module.SOURCE_LINK)
+	req.SetMeta("category", "news")
+
+	wr, err := encodeRequest(req)
+	if err != nil {
+		t.Fatalf("An error occurs when encoding request: %s", err)
+	}
+	data, _ := json.Marshal(wr)
+	var decodedWire wireRequest
+	if err := json.Unmarshal(data, &decodedWire); err != nil {
+		t.Fatalf("An error occurs when unmarshaling request: %s", err)
+	}
+
+	decoded, err := decodeRequest(&decodedWire)
+	if err != nil {
+		t.Fatalf("An error occurs when decoding request: %s", err)
+	}
+	decodedHTTPReq := decoded.HTTPReq()
+	if decodedHTTPReq.Method != "POST" || decodedHTTPReq.URL.String() != httpReq.URL.String() {
+		t.Fatalf("Inconsistent request: expected: %s %s, actual: %s %s",
+			"POST", httpReq.URL, decodedHTTPReq.Method, decodedHTTPReq.URL)
+	}
+	if decodedHTTPReq.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+		t.Fatalf("Inconsistent header: %v", decodedHTTPReq.Header)
+	}
+	if string(decoded.Body()) != "a=1" || decoded.Depth() != 2 || decoded.Priority() != 5 || decoded.Redirects() != 1 ||
+		!decoded.HandOffRedirects() {
+		t.Fatalf("Inconsistent request: body: %q, depth: %d, priority: %d, redirects: %d",
+			decoded.Body(), decoded.Depth(), decoded.Priority(), decoded.Redirects())
+	}
+	if category, _ := decoded.Meta().GetString("category"); category != "news" || decoded.Source() != module.SOURCE_LINK {
+		t.Fatalf("Inconsistent meta: source: %s, meta: %v", decoded.Source(), decoded.Meta())
+	}
+
+	if _, err := encodeRequest(nil); err == nil {
+		t.Fatal("No error when encoding nil request!")
+	}
+}
+
+func TestCodecResponse(t *testing.T) {
+	reqURL, _ := url.Parse("http://sogou.com/index.html")
+	httpResp := &http.Response{
+		Status:     "200 OK",
+		StatusCode: 200,
+		Proto:      "HTTP/1.1",
+		Header:     http.Header{"Content-Type": {"text/html"}},
+		Body:       ioutil.NopCloser(strings.NewReader("<html></html>")),
+		Request:    &http.Request{Method: "GET", URL: reqURL},
+	}
+
+	req := module.NewRequest(httpResp.Request, 1)
+	req.SetMeta("category", "news")
+	resp := module.NewResponseForRequest(httpResp, req)
+	redirectHTTPReq, _ := http.NewRequest("GET", "http://www.sogou.com/", nil)
+	redirectReq := module.NewRequest(redirectHTTPReq, 1)
+	redirectReq.SetSource(module.SOURCE_REDIRECT)
+	resp.SetRedirectRequest(redirectReq)
+	resp.SetCached(true)
+	wr, err := encodeResponse(resp)
+	if err != nil {
+		t.Fatalf("An error occurs when encoding response: %s", err)
+	}
+	resp, err = decodeResponse(wr)
+	if err != nil {
+		t.Fatalf("An error occurs when decoding response: %s", err)
+	}
+
+	decoded := resp.HTTPResp()
+	if decoded.StatusCode != 200 || decoded.ProtoMajor != 1 || decoded.ProtoMinor != 1 || resp.Depth() != 1 {
+		t.Fatalf("Inconsistent response: %#v", decoded)
+	}
+	if decoded.Request == nil || decoded.Request.URL.String() != reqURL.String() {
+		t.Fatalf("Inconsistent request URL: expected: %s, actual: %v", reqURL, decoded.Request)
+	}
+	if resp.Request() == nil || resp.Request().Meta()["category"] != "news" {
+		t.Fatalf("The meta of request should be kept: %v", resp.Request())
+	}
+	if r := resp.RedirectRequest(); r == nil || r.HTTPReq().URL.String() != "http://www.sogou.com/" ||
+		r.Source() != module.SOURCE_REDIRECT {
+		t.Fatalf("Inconsistent redirect request: %v", r)
+	}
+	if !resp.Cached() {
+		t.Fatal("The cached flag of response should be kept!")
+	}
+	body, _ := ioutil.ReadAll(decoded.Body)
+	if string(body) != "<html></html>" {
+		t.Fatalf("Inconsistent body: expected: %q, actual: %q", "<html></html>", body)
+	}
+}
+
+func TestCodecDataList(t *testing.T) {
+	httpReq, _ := http.NewRequest("GET", "http://sogou.com/a", nil)
+	dataList := []module.Data{
+		module.NewRequest(httpReq, 1),
+		module.Item{"title": "sogou", "count": 1},
+		nil,
+	}
+
+	wds, err := encodeDataList(dataList)
+	if err != nil {
+		t.Fatalf("An error occurs when encoding data list: %s", err)
+	}
+	data, _ := json.Marshal(wds)
+	var decodedWire []wireData
+	json.Unmarshal(data, &decodedWire)
+
+	decoded, err := decodeDataList(decodedWire)
+	if err != nil {
+		t.Fatalf("An error occurs when decoding data list: %s", err)
+	}
+	if len(decoded) != 2 {
+		t.Fatalf("Inconsistent data list length: expected: %d, actual: %d", 2, len(decoded))
+	}
+	if req, ok := decoded[0].(*module.Request); !ok || req.HTTPReq().URL.String() != "http://sogou.com/a" {
+		t.Fatalf("Inconsistent request: %#v", decoded[0])
+	}
+	// 条目中的值以JSON形式传输，数字会被还原为float64。
+	if item, ok := decoded[1].(module.Item); !ok || item["title"] != "sogou" || item["count"] != float64(1) {
+		t.Fatalf("Inconsistent item: %#v", decoded[1])
+	}
+}
@@ -0,0 +1,27 @@
+package remote
+
+import (
+	"crawler/errors"
+	"crawler/module"
+)
+
+// errorType 用于获取与组件类型对应的错误类型
+func errorType(moduleType module.Type) errors.ErrorType {
+	switch moduleType {
+	case module.TYPE_ANALYZER:
+		return errors.ERROR_TYPE_ANALYZER
+	case module.TYPE_PIPELINE:
+		return errors.ERROR_TYPE_PIPELINE
+	}
+	return errors.ERROR_TYPE_DOWNLOADER
+}
+
+// genError 用于生成爬虫错误值
+func genError(moduleType module.Type, errMsg string) error {
+	return errors.NewCrawlerError(errorType(moduleType), errMsg)
+}
+
+// genParameterError 用于生成爬虫参数错误值
+func genParameterError(moduleType module.Type, errMsg string) error {
+	return errors.NewCrawlerErrorBy(errorType(moduleType), errors.NewIllegalParameterError(errMsg))
+}
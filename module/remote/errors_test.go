@@ -0,0 +1,49 @@
+package remote
+
+import (
+	"crawler/errors"
+	"crawler/module"
+	"testing"
+)
+
+func TestErrorGenError(t *testing.T) {
+	simpleErrMsg := "testing error"
+	expectedErrTypes := map[module.Type]errors.ErrorType{
+		module.TYPE_DOWNLOADER: errors.ERROR_TYPE_DOWNLOADER,
+		module.TYPE_ANALYZER:   errors.ERROR_TYPE_ANALYZER,
+		module.TYPE_PIPELINE:   errors.ERROR_TYPE_PIPELINE,
+	}
+	for moduleType, expectedErrType := range expectedErrTypes {
+		err := genError(moduleType, simpleErrMsg)
+		ce, ok := err.(errors.CrawlerError)
+		if !ok {
+			t.Fatalf("非法错误类型. 预期: %T, 实际: %T", errors.NewCrawlerError("", ""), err)
+		}
+
+		if ce.Type() != expectedErrType {
+			t.Fatalf("非法错误类型字符. 预期: %q, 实际: %q", expectedErrType, ce.Type())
+		}
+
+		expectedErrMsg := "crawler error: " + string(expectedErrType) + ": " + simpleErrMsg
+		if ce.Error() != expectedErrMsg {
+			t.Fatalf("非法错误类型信息: 预期: %q, 实际: %q", expectedErrMsg, ce.Error())
+		}
+	}
+}
+
+func TestErrorWire(t *testing.T) {
+	origin := genError(module.TYPE_ANALYZER, "testing error")
+	err := encodeError(origin).decode()
+	ce, ok := err.(errors.CrawlerError)
+	if !ok {
+		t.Fatalf("非法错误类型. 预期: %T, 实际: %T", errors.NewCrawlerError("", ""), err)
+	}
+	if ce.Type() != errors.ERROR_TYPE_ANALYZER || ce.Error() != origin.Error() {
+		t.Fatalf("Inconsistent error: expected: %q, actual: %q", origin, ce)
+	}
+
+	err = wireError{Message: "plain error"}.decode()
+	if _, ok := err.(errors.CrawlerError); ok || err.Error() != "plain error" {
+		t.Fatalf("Inconsistent error: expected: %q, actual: %#v", "plain error", err)
+	}
+}
@@ -0,0 +1,211 @@
+package remote
+
+import (
+	"crawler/errors"
+	"crawler/module"
+	"crawler/module/local/analyzer"
+	"crawler/module/local/downloader"
+	"crawler/module/local/pipeline"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// startServer 用于启动暴露本地组件的服务端
+// 参数newModule会以带有服务端网络地址的组件ID被调用
+func startServer(t *testing.T, letter string, newModule func(mid module.MID) (module.Module, error)) (*httptest.Server, module.MID) {
+	server := httptest.NewUnstartedServer(nil)
+	mid := module.MID(fmt.Sprintf("%s1|%s", letter, server.Listener.Addr()))
+	m, err := newModule(mid)
+	if err != nil {
+		t.Fatalf("An error occurs when creating module: %s (mid: %s)", err, mid)
+	}
+
+	handler, err := NewServer(m)
+	if err != nil {
+		t.Fatalf("An error occurs when creating server: %s (mid: %s)", err, mid)
+	}
+	server.Config.Handler = handler
+	server.Start()
+	return server, mid
+}
+
+func TestNewServer(t *testing.T) {
+	if _, err := NewServer(nil); err == nil {
+		t.Fatal("No error when creating server with nil module!")
+	}
+
+	// 组件ID与组件类型不符。
+	d, _ := downloader.New("A1|127.0.0.1:8080", &http.Client{}, nil)
+	if _, err := NewServer(d); err == nil {
+		t.Fatal("No error when creating server with mismatched module!")
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	if _, err := NewDownloader("D1", nil, nil); err == nil {
+		t.Fatal("No error when creating remote downloader without address!")
+	}
+	if _, err := NewAnalyzer("A127.0.0.1", nil, nil); err == nil {
+		t.Fatal("No error when creating remote analyzer with illegal MID!")
+	}
+
+	c, err := newClient("D1|127.0.0.1:8080", nil)
+	if err != nil {
+		t.Fatalf("An error occurs when creating client: %s", err)
+	}
+	if c.httpClient.Timeout != defaultTimeout {
+		t.Fatalf("Inconsistent timeout: expected: %s, actual: %s", defaultTimeout, c.httpClient.Timeout)
+	}
+}
+
+func TestRemoteDownloader(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("X-Method", r.Method)
+		fmt.Fprintf(w, "%s %s", r.URL.Path, body)
+	}))
+	defer target.Close()
+
+	server, mid := startServer(t, "D", func(mid module.MID) (module.Module, error) {
+		return downloader.New(mid, &http.Client{}, nil)
+	})
+	defer server.Close()
+
+	d, err := NewDownloader(mid, nil, nil)
+	if err != nil {
+		t.Fatalf("An error occurs when creating remote downloader: %s", err)
+	}
+	registrar := module.NewRegistrar()
+	if ok, err := registrar.Register(d); !ok || err != nil {
+		t.Fatalf("Couldn't register remote downloader: %v", err)
+	}
+
+	httpReq, _ := http.NewRequest("PUT", target.URL+"/page", strings.NewReader("data"))
+	resp, err := d.Download(module.NewRequest(httpReq, 3))
+	if err != nil {
+		t.Fatalf("An error occurs when downloading: %s", err)
+	}
+	if resp.Depth() != 3 || resp.HTTPResp().Header.Get("X-Method") != "PUT" {
+		t.Fatalf("Inconsistent response: depth: %d, header: %v", resp.Depth(), resp.HTTPResp().Header)
+	}
+	if info := resp.FetchInfo(); info.Downloader != mid || info.Timing.Total <= 0 {
+		t.Fatalf("Inconsistent fetch info: %#v", info)
+	}
+	if resp.Request() == nil || resp.Request().HTTPReq() != httpReq {
+		t.Fatal("The response should be linked to the local request!")
+	}
+	body, _ := ioutil.ReadAll(resp.HTTPResp().Body)
+	if string(body) != "/page data" {
+		t.Fatalf("Inconsistent body: expected: %q, actual: %q", "/page data", body)
+	}
+	if resp.HTTPResp().Request == nil || resp.HTTPResp().Request.URL.Path != "/page" {
+		t.Fatalf("Inconsistent request of response: %v", resp.HTTPResp().Request)
+	}
+
+	expectedCounts := module.Counts{CalledCount: 1, AcceptedCount: 1, CompletedCount: 1}
+	if counts := d.Counts(); counts != expectedCounts {
+		t.Fatalf("Inconsistent counts: expected: %#v, actual: %#v", expectedCounts, counts)
+	}
+
+	// 远程下载器的错误会被原样返回。
+	httpReq, _ = http.NewRequest("GET", "http://127.0.0.1:0/", nil)
+	if _, err = d.Download(module.NewRequest(httpReq, 0)); err == nil {
+		t.Fatal("No error when downloading from an unreachable host!")
+	}
+
+	// 远程组件不可用。
+	server.Close()
+	httpReq, _ = http.NewRequest("GET", target.URL, nil)
+	_, err = d.Download(module.NewRequest(httpReq, 0))
+	if ce, ok := err.(errors.CrawlerError); !ok || ce.Type() != errors.ERROR_TYPE_DOWNLOADER {
+		t.Fatalf("Inconsistent error: expected type: %s, actual: %v", errors.ERROR_TYPE_DOWNLOADER, err)
+	}
+}
+
+func TestRemoteAnalyzer(t *testing.T) {
+	parser := func(httpResp *http.Response, respDepth uint32) ([]module.Data, []error) {
+		body, _ := ioutil.ReadAll(httpResp.Body)
+		next, _ := http.NewRequest("GET", httpResp.Request.URL.String()+"/next", nil)
+		return []module.Data{
+			module.NewRequest(next, respDepth),
+			module.Item{"body": string(body)},
+		}, []error{fmt.Errorf("parse warning")}
+	}
+	server, mid := startServer(t, "A", func(mid module.MID) (module.Module, error) {
+		return analyzer.New(mid, []module.ParseResponse{parser}, nil)
+	})
+	defer server.Close()
+
+	a, err := NewAnalyzer(mid, nil, nil)
+	if err != nil {
+		t.Fatalf("An error occurs when creating remote analyzer: %s", err)
+	}
+
+	httpReq, _ := http.NewRequest("GET", "http://sogou.com/a", nil)
+	httpResp := &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(strings.NewReader("hello")),
+		Request:    httpReq,
+	}
+	dataList, errs := a.Analyze(module.NewResponseForRequest(httpResp, module.NewRequest(httpReq, 1)))
+	if len(errs) != 1 || errs[0].Error() != "parse warning" {
+		t.Fatalf("Inconsistent errors: %v", errs)
+	}
+	if len(dataList) != 2 {
+		t.Fatalf("Inconsistent data list length: expected: %d, actual: %d", 2, len(dataList))
+	}
+	req, ok := dataList[0].(*module.Request)
+	if !ok || req.HTTPReq().URL.String() != "http://sogou.com/a/next" || req.Depth() != 2 {
+		t.Fatalf("Inconsistent request: %#v", dataList[0])
+	}
+	if req.Referrer() != "http://sogou.com/a" {
+		t.Fatalf("Inconsistent referrer: expected: %s, actual: %s", "http://sogou.com/a", req.Referrer())
+	}
+	if item, ok := dataList[1].(module.Item); !ok || item["body"] != "hello" {
+		t.Fatalf("Inconsistent item: %#v", dataList[1])
+	}
+}
+
+func TestRemotePipeline(t *testing.T) {
+	var local module.Pipeline
+	processor := func(item module.Item) (module.Item, error) {
+		if _, ok := item["fail"]; ok {
+			return nil, fmt.Errorf("failed item")
+		}
+		return item, nil
+	}
+	server, mid := startServer(t, "P", func(mid module.MID) (module.Module, error) {
+		p, err := pipeline.New(mid, []module.ProcessItem{processor, processor}, nil)
+		local = p
+		return p, err
+	})
+	defer server.Close()
+
+	p, err := NewPipeline(mid, nil, nil)
+	if err != nil {
+		t.Fatalf("An error occurs when creating remote pipeline: %s", err)
+	}
+
+	if errs := p.Send(module.Item{"ok": true}); len(errs) != 0 {
+		t.Fatalf("Inconsistent errors: expected: %v, actual: %v", nil, errs)
+	}
+	if errs := p.Send(module.Item{"fail": true}); len(errs) != 2 {
+		t.Fatalf("Inconsistent error number: expected: %d, actual: %d", 2, len(errs))
+	}
+
+	p.SetFailFast(true)
+	if !p.FailFast() || !local.FailFast() {
+		t.Fatalf("Inconsistent fail fast: expected: %v, actual: %v, %v", true, p.FailFast(), local.FailFast())
+	}
+	if errs := p.Send(module.Item{"fail": true}); len(errs) != 1 {
+		t.Fatalf("Inconsistent error number: expected: %d, actual: %d", 1, len(errs))
+	}
+	if errs := p.Send(nil); len(errs) != 1 {
+		t.Fatalf("Inconsistent error number: expected: %d, actual: %d", 1, len(errs))
+	}
+}
@@ -0,0 +1,171 @@
+package remote
+
+import (
+	"crawler/errors"
+	log "crawler/logger"
+	"crawler/module"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// logger 代表日志记录器
+var logger = log.DLogger()
+
+// 远程组件的接口路径
+const (
+	// pathDownload 代表下载接口的路径
+	pathDownload = "/download"
+	// pathAnalyze 代表分析接口的路径
+	pathAnalyze = "/analyze"
+	// pathSend 代表条目发送接口的路径
+	pathSend = "/send"
+	// pathFailFast 代表快速失败设置接口的路径
+	pathFailFast = "/fail_fast"
+	// pathSummary 代表摘要接口的路径
+	pathSummary = "/summary"
+)
+
+// server 代表远程组件服务端的实现类型
+type server struct {
+	// m 代表被暴露的本地组件
+	m module.Module
+	// moduleType 代表组件的类型
+	moduleType module.Type
+	// mux 代表请求路由
+	mux *http.ServeMux
+}
+
+// NewServer 用于创建一个通过HTTP/JSON暴露给定本地组件的服务端
+// 组件必须是下载器、分析器或条目处理管道之一
+// 服务端应监听在组件ID中的网络地址上，以便客户端根据组件ID找到它
+func NewServer(m module.Module) (http.Handler, error) {
+	if m == nil {
+		return nil, errors.NewIllegalParameterError("无 module 实例")
+	}
+
+	ok, moduleType := module.GetType(m.ID())
+	if !ok || !module.CheckType(moduleType, m) {
+		errMsg := fmt.Sprintf("不正确的 module 类型: %T (MID: %s)", m, m.ID())
+		return nil, errors.NewIllegalParameterError(errMsg)
+	}
+
+	s := &server{m: m, moduleType: moduleType, mux: http.NewServeMux()}
+	switch moduleType {
+	case module.TYPE_DOWNLOADER:
+		s.mux.HandleFunc(pathDownload, s.handleDownload)
+	case module.TYPE_ANALYZER:
+		s.mux.HandleFunc(pathAnalyze, s.handleAnalyze)
+	case module.TYPE_PIPELINE:
+		s.mux.HandleFunc(pathSend, s.handleSend)
+		s.mux.HandleFunc(pathFailFast, s.handleFailFast)
+	}
+	s.mux.HandleFunc(pathSummary, s.handleSummary)
+	return s, nil
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// decodeArgs 用于检查请求方法并解码请求参数
+// 若结果值为false，则说明已向客户端返回了错误
+func decodeArgs(w http.ResponseWriter, r *http.Request, args interface{}) bool {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(args); err != nil {
+		http.Error(w, fmt.Sprintf("invalid arguments: %s", err), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// writeResult 用于把结果编码后返回给客户端
+func writeResult(w http.ResponseWriter, result interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		logger.Warnf("Couldn't write the result of remote module: %s", err)
+	}
+}
+
+func (s *server) handleDownload(w http.ResponseWriter, r *http.Request) {
+	var args wireRequest
+	if !decodeArgs(w, r, &args) {
+		return
+	}
+
+	var result downloadResult
+	req, err := decodeRequest(&args)
+	if err == nil {
+		var resp *module.Response
+		resp, err = s.m.(module.Downloader).Download(req)
+		if resp != nil && resp.HTTPResp() != nil {
+			wr, encodeErr := encodeResponse(resp)
+			if encodeErr != nil && err == nil {
+				err = genError(s.moduleType, encodeErr.Error())
+			}
+			result.Response = wr
+		}
+	} else {
+		err = genParameterError(s.moduleType, err.Error())
+	}
+
+	if err != nil {
+		we := encodeError(err)
+		result.Error = &we
+	}
+	writeResult(w, result)
+}
+
+func (s *server) handleAnalyze(w http.ResponseWriter, r *http.Request) {
+	var args wireResponse
+	if !decodeArgs(w, r, &args) {
+		return
+	}
+
+	resp, err := decodeResponse(&args)
+	if err != nil {
+		writeResult(w, analyzeResult{Errors: encodeErrors([]error{genParameterError(s.moduleType, err.Error())})})
+		return
+	}
+
+	dataList, errs := s.m.(module.Analyzer).Analyze(resp)
+	wds, err := encodeDataList(dataList)
+	if err != nil {
+		errs = append(errs, genError(s.moduleType, err.Error()))
+	}
+	writeResult(w, analyzeResult{Data: wds, Errors: encodeErrors(errs)})
+}
+
+func (s *server) handleSend(w http.ResponseWriter, r *http.Request) {
+	var args sendArgs
+	if !decodeArgs(w, r, &args) {
+		return
+	}
+
+	errs := s.m.(module.Pipeline).Send(args.Item)
+	writeResult(w, sendResult{Errors: encodeErrors(errs)})
+}
+
+func (s *server) handleFailFast(w http.ResponseWriter, r *http.Request) {
+	pipeline := s.m.(module.Pipeline)
+	if r.Method == http.MethodGet {
+		writeResult(w, failFastArgs{FailFast: pipeline.FailFast()})
+		return
+	}
+
+	var args failFastArgs
+	if !decodeArgs(w, r, &args) {
+		return
+	}
+	pipeline.SetFailFast(args.FailFast)
+	writeResult(w, args)
+}
+
+func (s *server) handleSummary(w http.ResponseWriter, r *http.Request) {
+	writeResult(w, s.m.Summary())
+}
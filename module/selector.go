@@ -0,0 +1,272 @@
+package module
+
+import (
+	"crawler/errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// 负载均衡策略的名称
+const (
+	// SELECTOR_SCORE 代表选择评分最低的实例的策略
+	SELECTOR_SCORE = "score"
+	// SELECTOR_ROUND_ROBIN 代表轮流选择实例的策略
+	SELECTOR_ROUND_ROBIN = "round_robin"
+	// SELECTOR_WEIGHTED_RANDOM 代表按权重随机选择实例的策略
+	SELECTOR_WEIGHTED_RANDOM = "weighted_random"
+	// SELECTOR_LEAST_IN_FLIGHT 代表选择实时处理数最少的实例的策略
+	SELECTOR_LEAST_IN_FLIGHT = "least_in_flight"
+	// SELECTOR_CONSISTENT_HASH 代表按键的一致性散列选择实例的策略
+	SELECTOR_CONSISTENT_HASH = "consistent_hash"
+)
+
+// defaultHashReplicas 代表一致性散列环上每个实例的默认虚拟节点数量
+const defaultHashReplicas = 64
+
+// Selector 代表组件实例的负载均衡策略的接口类型
+// 该接口的实现类型必须是并发安全的
+type Selector interface {
+	// Name 用于获取策略的名称
+	Name() string
+	// Select 用于从候选实例中选出一个
+	// 参数candidates不为空且已按组件ID排序，参数key代表用于选择的键（如请求的主机名），可以为空
+	Select(candidates []Module, key string) Module
+}
+
+// scoreSelector 代表选择评分最低的实例的策略的实现类型
+type scoreSelector struct {
+	// lock 代表用于串行化评分计算的互斥锁
+	lock sync.Mutex
+}
+
+// NewScoreSelector 用于创建选择评分最低的实例的策略
+// 每次选择前都会用组件的评分计算器重新计算各候选实例的评分，这也是组件注册器的默认策略
+func NewScoreSelector() Selector {
+	return &scoreSelector{}
+}
+
+func (selector *scoreSelector) Name() string {
+	return SELECTOR_SCORE
+}
+
+func (selector *scoreSelector) Select(candidates []Module, key string) Module {
+	selector.lock.Lock()
+	defer selector.lock.Unlock()
+
+	var selected Module
+	minScore := uint64(0)
+	for _, module := range candidates {
+		SetScore(module)
+		score := module.Score()
+		if selected == nil || score < minScore {
+			selected = module
+			minScore = score
+		}
+	}
+	return selected
+}
+
+// roundRobinSelector 代表轮流选择实例的策略的实现类型
+type roundRobinSelector struct {
+	// next 代表下一次选择的序号
+	next uint64
+}
+
+// NewRoundRobinSelector 用于创建轮流选择实例的策略
+func NewRoundRobinSelector() Selector {
+	return &roundRobinSelector{}
+}
+
+func (selector *roundRobinSelector) Name() string {
+	return SELECTOR_ROUND_ROBIN
+}
+
+func (selector *roundRobinSelector) Select(candidates []Module, key string) Module {
+	n := atomic.AddUint64(&selector.next, 1) - 1
+	return candidates[n%uint64(len(candidates))]
+}
+
+// weightedRandomSelector 代表按权重随机选择实例的策略的实现类型
+type weightedRandomSelector struct {
+	// weights 代表组件ID与权重的映射
+	weights map[MID]uint32
+}
+
+// NewWeightedRandomSelector 用于创建按权重随机选择实例的策略
+// 参数weights中没有的实例的权重为1，权重为0的实例只在所有候选实例的权重都为0时才会被选择
+func NewWeightedRandomSelector(weights map[MID]uint32) Selector {
+	copied := make(map[MID]uint32, len(weights))
+	for mid, weight := range weights {
+		copied[mid] = weight
+	}
+	return &weightedRandomSelector{weights: copied}
+}
+
+func (selector *weightedRandomSelector) Name() string {
+	return SELECTOR_WEIGHTED_RANDOM
+}
+
+// weight 用于获取实例的权重
+func (selector *weightedRandomSelector) weight(mid MID) int64 {
+	if weight, ok := selector.weights[mid]; ok {
+		return int64(weight)
+	}
+	return 1
+}
+
+func (selector *weightedRandomSelector) Select(candidates []Module, key string) Module {
+	var total int64
+	for _, module := range candidates {
+		total += selector.weight(module.ID())
+	}
+	if total == 0 {
+		return candidates[rand.Intn(len(candidates))]
+	}
+
+	n := rand.Int63n(total)
+	for _, module := range candidates {
+		n -= selector.weight(module.ID())
+		if n < 0 {
+			return module
+		}
+	}
+	return candidates[len(candidates)-1]
+}
+
+// leastInFlightSelector 代表选择实时处理数最少的实例的策略的实现类型
+type leastInFlightSelector struct {
+	// next 代表下一次选择的序号，用于在实时处理数相同的实例间轮流选择
+	next uint64
+}
+
+// NewLeastInFlightSelector 用于创建选择实时处理数最少的实例的策略
+func NewLeastInFlightSelector() Selector {
+	return &leastInFlightSelector{}
+}
+
+func (selector *leastInFlightSelector) Name() string {
+	return SELECTOR_LEAST_IN_FLIGHT
+}
+
+func (selector *leastInFlightSelector) Select(candidates []Module, key string) Module {
+	var least []Module
+	minHandling := uint64(0)
+	for _, module := range candidates {
+		handling := module.HandlingNumber()
+		switch {
+		case len(least) == 0 || handling < minHandling:
+			least = append(least[:0], module)
+			minHandling = handling
+		case handling == minHandling:
+			least = append(least, module)
+		}
+	}
+	n := atomic.AddUint64(&selector.next, 1) - 1
+	return least[n%uint64(len(least))]
+}
+
+// hashRing 代表一致性散列环
+type hashRing struct {
+	// signature 代表构建该环时的候选实例的组件ID列表
+	signature string
+	// hashes 代表已排序的虚拟节点的散列值
+	hashes []uint32
+	// owners 代表虚拟节点的散列值与实例的映射
+	owners map[uint32]Module
+}
+
+// consistentHashSelector 代表按键的一致性散列选择实例的策略的实现类型
+type consistentHashSelector struct {
+	// replicas 代表每个实例的虚拟节点数量
+	replicas int
+	// ring 代表最近构建的一致性散列环
+	ring *hashRing
+	// fallback 代表键为空时使用的策略
+	fallback Selector
+	// lock 代表专用于散列环的互斥锁
+	lock sync.Mutex
+}
+
+// NewConsistentHashSelector 用于创建按键的一致性散列选择实例的策略
+// 相同的键（如相同的主机名）总会被分配给同一个实例，实例增减时只有少部分键会被重新分配
+// 参数replicas代表每个实例的虚拟节点数量，为0时使用64；键为空时会轮流选择实例
+func NewConsistentHashSelector(replicas int) (Selector, error) {
+	if replicas < 0 {
+		errMsg := fmt.Sprintf("非法虚拟节点数量: %d", replicas)
+		return nil, errors.NewIllegalParameterError(errMsg)
+	}
+
+	if replicas == 0 {
+		replicas = defaultHashReplicas
+	}
+	return &consistentHashSelector{replicas: replicas, fallback: NewRoundRobinSelector()}, nil
+}
+
+func (selector *consistentHashSelector) Name() string {
+	return SELECTOR_CONSISTENT_HASH
+}
+
+func (selector *consistentHashSelector) Select(candidates []Module, key string) Module {
+	if key == "" {
+		return selector.fallback.Select(candidates, key)
+	}
+
+	ring := selector.getRing(candidates)
+	h := hashKey(key)
+	i := sort.Search(len(ring.hashes), func(i int) bool {
+		return ring.hashes[i] >= h
+	})
+	if i == len(ring.hashes) {
+		i = 0
+	}
+	return ring.owners[ring.hashes[i]]
+}
+
+// getRing 用于获取与候选实例对应的散列环，候选实例变化时会重新构建
+func (selector *consistentHashSelector) getRing(candidates []Module) *hashRing {
+	mids := make([]string, len(candidates))
+	for i, module := range candidates {
+		mids[i] = string(module.ID())
+	}
+	signature := strings.Join(mids, "\n")
+
+	selector.lock.Lock()
+	defer selector.lock.Unlock()
+	if selector.ring != nil && selector.ring.signature == signature {
+		return selector.ring
+	}
+
+	ring := &hashRing{
+		signature: signature,
+		hashes:    make([]uint32, 0, len(candidates)*selector.replicas),
+		owners:    make(map[uint32]Module, len(candidates)*selector.replicas),
+	}
+	for _, module := range candidates {
+		for i := 0; i < selector.replicas; i++ {
+			h := hashKey(string(module.ID()) + "#" + strconv.Itoa(i))
+			if _, ok := ring.owners[h]; ok {
+				continue
+			}
+			ring.owners[h] = module
+			ring.hashes = append(ring.hashes, h)
+		}
+	}
+	sort.Slice(ring.hashes, func(i, j int) bool {
+		return ring.hashes[i] < ring.hashes[j]
+	})
+	selector.ring = ring
+	return ring
+}
+
+// hashKey 用于计算键的散列值
+func hashKey(key string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return h.Sum32()
+}
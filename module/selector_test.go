@@ -0,0 +1,126 @@
+package module
+
+import (
+	"fmt"
+	"testing"
+)
+
+// genSelectorCandidates 用于生成指定数量的按组件ID排序的仿造下载器
+func genSelectorCandidates(number int) []Module {
+	candidates := make([]Module, number)
+	for i := range candidates {
+		candidates[i] = fakeModuleFuncMap[TYPE_DOWNLOADER](MID(fmt.Sprintf("D%d", i+1)))
+	}
+	return candidates
+}
+
+func TestScoreSelector(t *testing.T) {
+	candidates := genSelectorCandidates(3)
+	candidates[0].(*fakeDownloader).count = 2
+	candidates[2].(*fakeDownloader).count = 1
+	selector := NewScoreSelector()
+	if name := selector.Name(); name != SELECTOR_SCORE {
+		t.Fatalf("Inconsistent selector name: expected: %s, actual: %s", SELECTOR_SCORE, name)
+	}
+	if m := selector.Select(candidates, ""); m.ID() != "D2" {
+		t.Fatalf("Inconsistent selected module: expected: %s, actual: %s", "D2", m.ID())
+	}
+	if score := candidates[0].Score(); score != CalculateScoreSimple(candidates[0].Counts()) {
+		t.Fatalf("Inconsistent score: expected: %d, actual: %d", CalculateScoreSimple(candidates[0].Counts()), score)
+	}
+}
+
+func TestRoundRobinSelector(t *testing.T) {
+	candidates := genSelectorCandidates(3)
+	selector := NewRoundRobinSelector()
+	for i := 0; i < 7; i++ {
+		expected := candidates[i%len(candidates)].ID()
+		if m := selector.Select(candidates, "host"); m.ID() != expected {
+			t.Fatalf("Inconsistent selected module: expected: %s, actual: %s", expected, m.ID())
+		}
+	}
+}
+
+func TestWeightedRandomSelector(t *testing.T) {
+	candidates := genSelectorCandidates(3)
+	selector := NewWeightedRandomSelector(map[MID]uint32{"D1": 0, "D2": 3})
+	counts := map[MID]int{}
+	total := 4000
+	for i := 0; i < total; i++ {
+		counts[selector.Select(candidates, "").ID()]++
+	}
+	if counts["D1"] != 0 {
+		t.Fatalf("The module with zero weight should not be selected: %v", counts)
+	}
+	// D2和D3的权重比为3:1。
+	if counts["D2"] < total*6/10 || counts["D2"] > total*9/10 || counts["D2"]+counts["D3"] != total {
+		t.Fatalf("Inconsistent distribution: %v", counts)
+	}
+
+	// 所有候选实例的权重都为0时仍会选出实例。
+	selector = NewWeightedRandomSelector(map[MID]uint32{"D1": 0})
+	if m := selector.Select(candidates[:1], ""); m.ID() != "D1" {
+		t.Fatalf("Inconsistent selected module: expected: %s, actual: %s", "D1", m.ID())
+	}
+}
+
+func TestLeastInFlightSelector(t *testing.T) {
+	candidates := genSelectorCandidates(3)
+	candidates[0].(*fakeDownloader).count = 3
+	selector := NewLeastInFlightSelector()
+	counts := map[MID]int{}
+	for i := 0; i < 10; i++ {
+		counts[selector.Select(candidates, "").ID()]++
+	}
+	// 实时处理数相同的实例会被轮流选择。
+	if counts["D1"] != 0 || counts["D2"] != 5 || counts["D3"] != 5 {
+		t.Fatalf("Inconsistent distribution: %v", counts)
+	}
+}
+
+func TestConsistentHashSelector(t *testing.T) {
+	if _, err := NewConsistentHashSelector(-1); err == nil {
+		t.Fatal("No error when creating consistent hash selector with negative replicas!")
+	}
+	selector, err := NewConsistentHashSelector(0)
+	if err != nil {
+		t.Fatalf("An error occurs when creating consistent hash selector: %s", err)
+	}
+
+	candidates := genSelectorCandidates(4)
+	hosts := make([]string, 100)
+	assigned := map[string]MID{}
+	used := map[MID]bool{}
+	for i := range hosts {
+		hosts[i] = fmt.Sprintf("host%d.com", i)
+		m := selector.Select(candidates, hosts[i])
+		assigned[hosts[i]] = m.ID()
+		used[m.ID()] = true
+	}
+	if len(used) != len(candidates) {
+		t.Fatalf("Inconsistent used module number: expected: %d, actual: %d", len(candidates), len(used))
+	}
+	for _, host := range hosts {
+		if m := selector.Select(candidates, host); m.ID() != assigned[host] {
+			t.Fatalf("Inconsistent selected module for %q: expected: %s, actual: %s", host, assigned[host], m.ID())
+		}
+	}
+
+	// 移除一个实例后，只有原先分配给它的主机会被重新分配。
+	for _, host := range hosts {
+		m := selector.Select(candidates[1:], host)
+		if assigned[host] != "D1" && m.ID() != assigned[host] {
+			t.Fatalf("The host %q should not be reassigned: expected: %s, actual: %s", host, assigned[host], m.ID())
+		}
+		if m.ID() == "D1" {
+			t.Fatalf("The removed module should not be selected for %q!", host)
+		}
+	}
+
+	// 键为空时会轮流选择实例。
+	for i := 0; i < 4; i++ {
+		if m := selector.Select(candidates, ""); m.ID() != candidates[i].ID() {
+			t.Fatalf("Inconsistent selected module: expected: %s, actual: %s", candidates[i].ID(), m.ID())
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package scheduler
 
-import "crawler/module"
+import (
+	"crawler/module"
+	"fmt"
+	"time"
+)
 
 // Args 代表参数容器的接口类型
 type Args interface {
@@ -17,12 +21,81 @@ type RequestArgs struct {
 	// maxDepth 代表了需要被爬取的最大深度
 	// 实际深度大于此值的请求都会被忽略
 	MaxDepth uint32 `json:"max_depth"`
+	// MaxRedirects 代表下载器交给调度器的重定向请求最多可以经过的重定向次数，为0时使用10
+	// 重定向请求的深度与原请求相同，因此需要单独限制重定向链的长度
+	MaxRedirects uint32 `json:"max_redirects"`
+	// IgnoreSeedDomains 代表是否不把种子请求的主域名添加到可接受的主域名列表
+	IgnoreSeedDomains bool `json:"ignore_seed_domains"`
+	// HostDelay 代表对同一主机的两次请求之间的最小间隔
+	HostDelay time.Duration `json:"host_delay"`
+	// HostDelayJitter 代表在最小间隔之上随机附加的时长的上限
+	HostDelayJitter time.Duration `json:"host_delay_jitter"`
+	// HostMaxInFlight 代表对同一主机的最大并发请求数，为0时不限制
+	HostMaxInFlight uint32 `json:"host_max_in_flight"`
+	// IgnoreRobots 代表是否忽略robots.txt的限制
+	IgnoreRobots bool `json:"ignore_robots"`
+	// RobotsUserAgent 代表匹配robots.txt规则时使用的user-agent，为空时使用"*"
+	RobotsUserAgent string `json:"robots_user_agent"`
+	// RobotsExpiry 代表robots.txt规则的缓存时长，为0时使用24小时
+	RobotsExpiry time.Duration `json:"robots_expiry"`
+	// Canonical 代表判断请求是否重复前对URL进行规范化的规则
+	Canonical CanonicalRules `json:"canonical"`
+	// FingerprintHeaders 代表需要计入请求指纹的请求头的名称的列表
+	// 默认情况下，请求指纹只由请求方法、规范化后的URL和请求体决定
+	FingerprintHeaders []string `json:"fingerprint_headers"`
+	// Budget 代表爬取预算，预算用尽时调度器会自动停止
+	Budget Budget `json:"budget"`
+	// Dedupe 代表用于判断请求是否重复的去重器的参数
+	Dedupe DedupeArgs `json:"dedupe"`
+	// ScopeRules 代表URL范围规则的列表，按顺序求值
+	// 没有规则匹配时，若存在允许规则则忽略该URL，否则接受
+	ScopeRules []ScopeRule `json:"scope_rules"`
+	// Retry 代表下载失败时的重试策略
+	Retry RetryPolicy `json:"retry"`
+	// Prioritizer 代表用于计算请求优先级的函数，为nil时按先进先出的顺序下载
+	// 该字段不参与相同性的判断
+	Prioritizer Prioritizer `json:"-"`
 }
 
 func (args *RequestArgs) Check() error {
 	if args.AcceptedDomains == nil {
 		return genError("无接受的域名列表")
 	}
+
+	if args.HostDelay < 0 {
+		return genError(fmt.Sprintf("负的主机请求间隔: %s", args.HostDelay))
+	}
+
+	if args.HostDelayJitter < 0 {
+		return genError(fmt.Sprintf("负的主机请求间隔抖动: %s", args.HostDelayJitter))
+	}
+
+	if args.RobotsExpiry < 0 {
+		return genError(fmt.Sprintf("负的robots.txt缓存时长: %s", args.RobotsExpiry))
+	}
+
+	if err := args.Canonical.Check(); err != nil {
+		return err
+	}
+
+	if err := args.Budget.Check(); err != nil {
+		return err
+	}
+
+	if err := args.Dedupe.Check(); err != nil {
+		return err
+	}
+
+	if err := args.Retry.Check(); err != nil {
+		return err
+	}
+
+	for _, rule := range args.ScopeRules {
+		if _, err := rule.compile(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -36,6 +109,62 @@ func (args *RequestArgs) Same(another *RequestArgs) bool {
 		return false
 	}
 
+	if another.MaxRedirects != args.MaxRedirects {
+		return false
+	}
+
+	if another.IgnoreSeedDomains != args.IgnoreSeedDomains {
+		return false
+	}
+
+	if another.HostDelay != args.HostDelay ||
+		another.HostDelayJitter != args.HostDelayJitter ||
+		another.HostMaxInFlight != args.HostMaxInFlight {
+		return false
+	}
+
+	if another.IgnoreRobots != args.IgnoreRobots ||
+		another.RobotsUserAgent != args.RobotsUserAgent ||
+		another.RobotsExpiry != args.RobotsExpiry {
+		return false
+	}
+
+	if !another.Canonical.Same(&args.Canonical) {
+		return false
+	}
+
+	if len(another.FingerprintHeaders) != len(args.FingerprintHeaders) {
+		return false
+	}
+
+	for i, header := range another.FingerprintHeaders {
+		if header != args.FingerprintHeaders[i] {
+			return false
+		}
+	}
+
+	if !another.Budget.Same(&args.Budget) {
+		return false
+	}
+
+	if !another.Dedupe.Same(&args.Dedupe) {
+		return false
+	}
+
+	if !another.Retry.Same(&args.Retry) {
+		return false
+	}
+
+	if len(another.ScopeRules) != len(args.ScopeRules) {
+		return false
+	}
+
+	for i, rule := range another.ScopeRules {
+		if rule != args.ScopeRules[i] {
+			return false
+		}
+	}
+
 	anotherDomains := another.AcceptedDomains
 	anotherDomainsLen := len(anotherDomains)
 
@@ -72,6 +201,12 @@ type DataArgs struct {
 	ErrorBufferCap uint32 `json:"error_buffer_cap"`
 	// ErrorMaxBufferNumber 代表错误缓冲器的最大数量
 	ErrorMaxBufferNumber uint32 `json:"error_max_buffer_number"`
+	// DownloadWorkerNumber 代表并发执行下载的工作协程的数量，为0时使用1
+	DownloadWorkerNumber uint32 `json:"download_worker_number"`
+	// AnalyzeWorkerNumber 代表并发执行分析的工作协程的数量，为0时使用1
+	AnalyzeWorkerNumber uint32 `json:"analyze_worker_number"`
+	// PickWorkerNumber 代表并发处理条目的工作协程的数量，为0时使用1
+	PickWorkerNumber uint32 `json:"pick_worker_number"`
 }
 
 func (args *DataArgs) Check() error {
@@ -107,14 +242,45 @@ func (args *DataArgs) Check() error {
 		return genError("零最大错误缓冲区数")
 	}
 
+	if args.DownloadWorkerNumber > maxWorkerNumber {
+		return genError(fmt.Sprintf("下载工作协程数过大: %d (最大值: %d)", args.DownloadWorkerNumber, maxWorkerNumber))
+	}
+
+	if args.AnalyzeWorkerNumber > maxWorkerNumber {
+		return genError(fmt.Sprintf("分析工作协程数过大: %d (最大值: %d)", args.AnalyzeWorkerNumber, maxWorkerNumber))
+	}
+
+	if args.PickWorkerNumber > maxWorkerNumber {
+		return genError(fmt.Sprintf("条目处理工作协程数过大: %d (最大值: %d)", args.PickWorkerNumber, maxWorkerNumber))
+	}
+
 	return nil
 }
 
+// maxWorkerNumber 代表每个处理阶段的工作协程的最大数量
+const maxWorkerNumber = 1024
+
+// workerNumber 用于获取实际使用的工作协程数量
+func workerNumber(number uint32) int {
+	if number == 0 {
+		return 1
+	}
+	return int(number)
+}
+
 // ModuleArgsSummary 代表组件相关的参数容器的摘要类型
 type ModuleArgsSummary struct {
 	DownloaderListSize int `json:"downloader_list_size"`
 	AnalyzerListSize   int `json:"analyzer_list_size"`
 	PipelineListSize   int `json:"pipeline_list_size"`
+	// Health 代表组件健康检查的参数
+	Health module.HealthArgs `json:"health"`
+	// DownloaderSelector 代表下载器的负载均衡策略的名称
+	DownloaderSelector string `json:"downloader_selector"`
+	// AnalyzerSelector 代表分析器的负载均衡策略的名称
+	AnalyzerSelector string `json:"analyzer_selector"`
+	// PipelineSelector 代表条目处理管道的负载均衡策略的名称
+	PipelineSelector string `json:"pipeline_selector"`
 }
 
 // ModuleArgs 代表组件相关的参数容器的类型
@@ -125,6 +291,12 @@ type ModuleArgs struct {
 	Analyzers []module.Analyzer
 	// Pipelines 代表条目处理管道管道列表
 	Pipelines []module.Pipeline
+	// Health 代表组件健康检查的参数
+	// 调用失败过多的组件的熔断器会被打开，在一段时间内不会再被分配工作
+	Health module.HealthArgs
+	// Selectors 代表组件类型与负载均衡策略的映射
+	// 下载器和分析器的策略会以请求的主机名为键，未指定策略的组件类型会选择评分最低的实例
+	Selectors map[module.Type]module.Selector
 }
 
 // Check 用于当前参数容器的有效性
@@ -141,13 +313,38 @@ func (args *ModuleArgs) Check() error {
 		return genError("空条目列表")
 	}
 
+	if err := args.Health.Check(); err != nil {
+		return genErrorByError(err)
+	}
+
+	for moduleType, selector := range args.Selectors {
+		if !module.LegalType(moduleType) {
+			return genError(fmt.Sprintf("非法组件类型: %s", moduleType))
+		}
+		if selector == nil {
+			return genError(fmt.Sprintf("空负载均衡策略: %s", moduleType))
+		}
+	}
+
 	return nil
 }
 
+// selectorName 用于获取指定组件类型的负载均衡策略的名称
+func (args *ModuleArgs) selectorName(moduleType module.Type) string {
+	if selector := args.Selectors[moduleType]; selector != nil {
+		return selector.Name()
+	}
+	return module.SELECTOR_SCORE
+}
+
 func (args *ModuleArgs) Summary() ModuleArgsSummary {
 	return ModuleArgsSummary{
 		DownloaderListSize: len(args.Downloaders),
 		AnalyzerListSize:   len(args.Analyzers),
 		PipelineListSize:   len(args.Pipelines),
+		Health:             args.Health,
+		DownloaderSelector: args.selectorName(module.TYPE_DOWNLOADER),
+		AnalyzerSelector:   args.selectorName(module.TYPE_ANALYZER),
+		PipelineSelector:   args.selectorName(module.TYPE_PIPELINE),
 	}
 }
@@ -65,6 +65,20 @@ func TestArgsRequest(t *testing.T) {
 		t.Fatalf("不同最大深度的不一致请求参数相同性。预期: %v, 实际: %v", false, same)
 	}
 
+	another = genRequestArgs([]string{
+		"bing.com",
+	}, 0)
+	another.HostDelay = time.Second
+	same = one.Same(&another)
+	if same {
+		t.Fatalf("不同主机请求间隔的不一致请求参数相同性。预期: %v, 实际: %v", false, same)
+	}
+
+	another.HostDelay = -time.Second
+	if err := another.Check(); err == nil {
+		t.Fatalf("检查结果不一致: 预期: 非nil, 实际: %v", err)
+	}
+
 	another = genRequestArgs(nil, 0)
 	same = one.Same(&another)
 
@@ -142,6 +156,27 @@ func TestArgsData(t *testing.T) {
 			t.Fatalf("检查数据参数时没有错误! (dataArgs: %#v)", dataArgs)
 		}
 	}
+
+	// 测试工作协程数量。
+	dataArgs = genDataArgs(10, 2, 1)
+	dataArgs.DownloadWorkerNumber = 8
+	dataArgs.AnalyzeWorkerNumber = 4
+	if err := dataArgs.Check(); err != nil {
+		t.Fatalf("检查结果不一致。预期: %v, 实际: %v", nil, err)
+	}
+
+	dataArgs.PickWorkerNumber = maxWorkerNumber + 1
+	if err := dataArgs.Check(); err == nil {
+		t.Fatalf("检查数据参数时没有错误! (dataArgs: %#v)", dataArgs)
+	}
+
+	if n := workerNumber(0); n != 1 {
+		t.Fatalf("工作协程数不一致。预期: %d, 实际: %d", 1, n)
+	}
+
+	if n := workerNumber(8); n != 8 {
+		t.Fatalf("工作协程数不一致。预期: %d, 实际: %d", 8, n)
+	}
 }
 
 // genSimpleDownloaders 用于生成一定数量的简易下载器
@@ -180,7 +215,7 @@ func genSimpleDownloaders(number int8, reuseMID bool, snGen module.SNGenertor, t
 func parseATag(httpResp *http.Response, respDepth uint32) ([]module.Data, []error) {
 	// TODO: 支持更多的HTTP响应状态
 	if httpResp.StatusCode != 200 {
-		err := fmt.Errorf(fmt.Sprintf("不支持的状态码 %d! (httpResponse: %v)", httpResp.StatusCode, httpResp))
+		err := fmt.Errorf("不支持的状态码 %d! (httpResponse: %v)", httpResp.StatusCode, httpResp)
 		return nil, []error{err}
 	}
 
@@ -312,16 +347,36 @@ func TestArgsModule(t *testing.T) {
 		DownloaderListSize: 3,
 		AnalyzerListSize:   2,
 		PipelineListSize:   1,
+		DownloaderSelector: module.SELECTOR_SCORE,
+		AnalyzerSelector:   module.SELECTOR_SCORE,
+		PipelineSelector:   module.SELECTOR_SCORE,
 	}
 	summary := moduleArgs.Summary()
 	if summary != expectedSummary {
 		t.Fatalf("Inconsistent module args summary: expected: %#v, actual: %#v",
 			expectedSummary, summary)
 	}
+
+	moduleArgs.Selectors = map[module.Type]module.Selector{
+		module.TYPE_DOWNLOADER: module.NewLeastInFlightSelector(),
+	}
+	if err := moduleArgs.Check(); err != nil {
+		t.Fatalf("Inconsistent check result: expected: %v, actual: %v",
+			nil, err)
+	}
+	expectedSummary.DownloaderSelector = module.SELECTOR_LEAST_IN_FLIGHT
+	if summary := moduleArgs.Summary(); summary != expectedSummary {
+		t.Fatalf("Inconsistent module args summary: expected: %#v, actual: %#v",
+			expectedSummary, summary)
+	}
+
+	invalidSelectorArgs := genSimpleModuleArgs(3, 2, 1, t)
+	invalidSelectorArgs.Selectors = map[module.Type]module.Selector{module.TYPE_ANALYZER: nil}
 	moduleArgsList := []ModuleArgs{
 		genSimpleModuleArgs(0, 2, 1, t),
 		genSimpleModuleArgs(3, 0, 1, t),
 		genSimpleModuleArgs(3, 2, 0, t),
+		invalidSelectorArgs,
 		ModuleArgs{},
 	}
 	for _, moduleArgs := range moduleArgsList {
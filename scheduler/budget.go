@@ -0,0 +1,297 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// StopReason 代表调度器停止的原因。
+type StopReason string
+
+// 调度器停止的原因常量。
+const (
+	// STOP_REASON_MANUAL 代表调度器是由Stop方法停止的。
+	STOP_REASON_MANUAL StopReason = "manual"
+	// STOP_REASON_MAX_PAGES 代表请求总数的预算已用尽，且已发送的请求都已处理完毕。
+	STOP_REASON_MAX_PAGES StopReason = "max_pages"
+	// STOP_REASON_MAX_BYTES 代表下载字节数的预算已用尽。
+	STOP_REASON_MAX_BYTES StopReason = "max_bytes"
+	// STOP_REASON_MAX_DURATION 代表爬取时长的预算已用尽。
+	STOP_REASON_MAX_DURATION StopReason = "max_duration"
+)
+
+// Budget 代表爬取预算，为0的字段代表不限制。
+// 请求总数的预算用尽后，新的请求会被忽略，调度器会在已发送的请求都处理完毕后自动停止。
+// 下载字节数和爬取时长的预算用尽后，调度器会立即自动停止。
+type Budget struct {
+	// MaxPages 代表最多发送的请求的数量，不包括重试。
+	MaxPages uint64 `json:"max_pages"`
+	// MaxBytes 代表最多下载的响应体的字节数，由下载器的缓存提供的响应体不计入。
+	MaxBytes uint64 `json:"max_bytes"`
+	// MaxDuration 代表从调度器启动开始计算的最长爬取时长，暂停的时间也会被计入。
+	MaxDuration time.Duration `json:"max_duration"`
+	// MaxPagesPerDomain 代表每个主域名最多发送的请求的数量。
+	// 达到上限的主域名的新请求会被忽略，但不会导致调度器停止。
+	MaxPagesPerDomain uint64 `json:"max_pages_per_domain"`
+	// DomainMaxPages 代表针对特定主域名的请求数量上限，会覆盖MaxPagesPerDomain。
+	DomainMaxPages map[string]uint64 `json:"domain_max_pages"`
+}
+
+// Check 用于检查爬取预算的有效性。
+func (b *Budget) Check() error {
+	if b.MaxDuration < 0 {
+		return genError(fmt.Sprintf("负的最长爬取时长: %s", b.MaxDuration))
+	}
+
+	for domain := range b.DomainMaxPages {
+		if domain == "" {
+			return genError("空的预算主域名")
+		}
+		if _, err := normalizeDomain(domain); err != nil {
+			return genError(fmt.Sprintf("无效的预算主域名 %q: %s", domain, err))
+		}
+	}
+	return nil
+}
+
+// Same 用于判断两个爬取预算是否相同。
+func (b *Budget) Same(another *Budget) bool {
+	if another == nil {
+		return false
+	}
+
+	if another.MaxPages != b.MaxPages ||
+		another.MaxBytes != b.MaxBytes ||
+		another.MaxDuration != b.MaxDuration ||
+		another.MaxPagesPerDomain != b.MaxPagesPerDomain ||
+		len(another.DomainMaxPages) != len(b.DomainMaxPages) {
+		return false
+	}
+
+	for domain, max := range another.DomainMaxPages {
+		if v, ok := b.DomainMaxPages[domain]; !ok || v != max {
+			return false
+		}
+	}
+	return true
+}
+
+// BudgetSummaryStruct 代表爬取预算的使用情况的摘要类型。
+type BudgetSummaryStruct struct {
+	// Pages 代表已发送的请求的数量。
+	Pages uint64 `json:"pages"`
+	// Bytes 代表已下载的响应体的字节数。
+	Bytes uint64 `json:"bytes"`
+	// CappedDomains 代表请求数量已达上限的主域名的数量。
+	CappedDomains int `json:"capped_domains"`
+}
+
+// budget 代表爬取预算的计量器。
+type budget struct {
+	// limits 代表爬取预算。
+	limits Budget
+	// domainMaxPages 代表以规范形式的主域名为键的请求数量上限。
+	domainMaxPages map[string]uint64
+	// pages 代表已发送的请求的数量。
+	pages uint64
+	// domainPages 代表每个主域名已发送的请求的数量。
+	domainPages map[string]uint64
+	// bytes 代表已下载的响应体的字节数。
+	bytes uint64
+	// exhausted 代表已用尽的预算，用于保证每种预算只触发一次停止。
+	exhausted map[StopReason]bool
+	// onExhausted 代表预算用尽时的处理函数。
+	onExhausted func(reason StopReason)
+	// lock 代表互斥锁。
+	lock sync.Mutex
+}
+
+// newBudget 用于创建爬取预算的计量器。
+func newBudget(limits Budget, onExhausted func(reason StopReason)) *budget {
+	b := &budget{
+		limits:      limits,
+		domainPages: make(map[string]uint64),
+		exhausted:   make(map[StopReason]bool),
+		onExhausted: onExhausted,
+	}
+	if len(limits.DomainMaxPages) > 0 {
+		b.domainMaxPages = make(map[string]uint64, len(limits.DomainMaxPages))
+		for domain, max := range limits.DomainMaxPages {
+			if normalized, err := normalizeDomain(domain); err == nil {
+				domain = normalized
+			}
+			b.domainMaxPages[domain] = max
+		}
+	}
+	return b
+}
+
+// takePage 用于为属于给定主域名的请求申请预算。
+// 若结果值为false，则说明预算已用尽，该请求应被忽略。
+func (b *budget) takePage(primaryDomain string) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.limits.MaxPages > 0 && b.pages >= b.limits.MaxPages {
+		return false
+	}
+
+	max := b.limits.MaxPagesPerDomain
+	if v, ok := b.domainMaxPages[primaryDomain]; ok {
+		max = v
+	}
+	if max > 0 && b.domainPages[primaryDomain] >= max {
+		return false
+	}
+
+	b.pages++
+	b.domainPages[primaryDomain]++
+	if b.limits.MaxPages > 0 && b.pages == b.limits.MaxPages {
+		b.exhaust(STOP_REASON_MAX_PAGES)
+	}
+	return true
+}
+
+// refundPage 用于退还为属于给定主域名的请求申请的预算，用于请求在申请预算之后被过滤掉的情况。
+// 页面数量的预算用尽时的处理只会在所有进行中的工作完成后停止调度器，因此退还时不必撤销它。
+func (b *budget) refundPage(primaryDomain string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.pages > 0 {
+		b.pages--
+	}
+	if b.domainPages[primaryDomain] > 0 {
+		b.domainPages[primaryDomain]--
+	}
+}
+
+// addBytes 用于计入已下载的字节数。
+func (b *budget) addBytes(n int) {
+	if n <= 0 {
+		return
+	}
+
+	total := atomic.AddUint64(&b.bytes, uint64(n))
+	if b.limits.MaxBytes > 0 && total >= b.limits.MaxBytes {
+		b.lock.Lock()
+		defer b.lock.Unlock()
+		b.exhaust(STOP_REASON_MAX_BYTES)
+	}
+}
+
+// exhaust 用于标记预算已用尽，并在首次标记时调用处理函数，调用方需持有锁。
+func (b *budget) exhaust(reason StopReason) {
+	if b.exhausted[reason] {
+		return
+	}
+
+	b.exhausted[reason] = true
+	logger.Infof("The crawl budget %q has been exhausted.", reason)
+	if b.onExhausted != nil {
+		go b.onExhausted(reason)
+	}
+}
+
+// start 用于开始计量爬取时长，上下文被取消时停止计量。
+func (b *budget) start(ctx context.Context) {
+	if b.limits.MaxDuration <= 0 {
+		return
+	}
+
+	// 每次启动都会重新计量爬取时长。
+	b.lock.Lock()
+	delete(b.exhausted, STOP_REASON_MAX_DURATION)
+	b.lock.Unlock()
+
+	go func() {
+		timer := time.NewTimer(b.limits.MaxDuration)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+		case <-timer.C:
+			b.lock.Lock()
+			defer b.lock.Unlock()
+			b.exhaust(STOP_REASON_MAX_DURATION)
+		}
+	}()
+}
+
+// countBody 用于包装响应体，以便计入从中读出的字节数。
+func (b *budget) countBody(body io.ReadCloser) io.ReadCloser {
+	if body == nil {
+		return nil
+	}
+	return &countingBody{ReadCloser: body, budget: b}
+}
+
+// summary 用于获取爬取预算的使用情况的摘要。
+func (b *budget) summary() BudgetSummaryStruct {
+	if b == nil {
+		return BudgetSummaryStruct{}
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	summary := BudgetSummaryStruct{
+		Pages: b.pages,
+		Bytes: atomic.LoadUint64(&b.bytes),
+	}
+	for domain, pages := range b.domainPages {
+		max := b.limits.MaxPagesPerDomain
+		if v, ok := b.domainMaxPages[domain]; ok {
+			max = v
+		}
+		if max > 0 && pages >= max {
+			summary.CappedDomains++
+		}
+	}
+	return summary
+}
+
+// countingBody 代表会计入读出的字节数的响应体。
+type countingBody struct {
+	io.ReadCloser
+	budget *budget
+}
+
+func (body *countingBody) Read(p []byte) (int, error) {
+	n, err := body.ReadCloser.Read(p)
+	body.budget.addBytes(n)
+	return n, err
+}
+
+// onBudgetExhausted 会在爬取预算用尽时自动停止调度器。
+// 请求总数的预算用尽时，会先等待已发送的请求都处理完毕。
+func (sched *myScheduler) onBudgetExhausted(reason StopReason) {
+	if reason == STOP_REASON_MAX_PAGES {
+		ctx := sched.ctx
+		select {
+		case <-ctx.Done():
+			return
+		case <-sched.inFlight.done():
+		}
+	}
+
+	if err := sched.stop(reason); err != nil {
+		logger.Warnf("Couldn't stop the scheduler when the crawl budget %q is exhausted: %s", reason, err)
+	}
+}
+
+// StopReason 用于获取调度器停止的原因，调度器未停止时返回空字符串。
+func (sched *myScheduler) StopReason() StopReason {
+	sched.stopReasonLock.RLock()
+	defer sched.stopReasonLock.RUnlock()
+	return sched.stopReason
+}
+
+// setStopReason 用于设置调度器停止的原因。
+func (sched *myScheduler) setStopReason(reason StopReason) {
+	sched.stopReasonLock.Lock()
+	defer sched.stopReasonLock.Unlock()
+	sched.stopReason = reason
+}
@@ -0,0 +1,221 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBudgetCheck(t *testing.T) {
+	invalidBudgets := []Budget{
+		{MaxDuration: -time.Second},
+		{DomainMaxPages: map[string]uint64{"": 1}},
+	}
+	for _, b := range invalidBudgets {
+		if err := b.Check(); err == nil {
+			t.Fatalf("No error when checking invalid budget: %#v", b)
+		}
+	}
+
+	one := Budget{MaxPages: 10, DomainMaxPages: map[string]uint64{"bing.com": 1}}
+	another := Budget{MaxPages: 10, DomainMaxPages: map[string]uint64{"bing.com": 1}}
+	if !one.Same(&another) {
+		t.Fatalf("Inconsistent budget sameness: expected: %v, actual: %v", true, false)
+	}
+	another.DomainMaxPages["bing.com"] = 2
+	if one.Same(&another) {
+		t.Fatalf("Inconsistent budget sameness: expected: %v, actual: %v", false, true)
+	}
+}
+
+func TestBudgetPages(t *testing.T) {
+	var reasons []StopReason
+	exhausted := make(chan StopReason, 10)
+	b := newBudget(Budget{
+		MaxPages:          5,
+		MaxPagesPerDomain: 2,
+		DomainMaxPages:    map[string]uint64{"BING.com": 3},
+	}, func(reason StopReason) { exhausted <- reason })
+
+	cases := []struct {
+		domain string
+		taken  bool
+	}{
+		{"sogou.com", true},
+		{"sogou.com", true},
+		{"sogou.com", false},
+		{"bing.com", true},
+		{"bing.com", true},
+		{"bing.com", true},
+		{"bing.com", false},
+		{"baidu.com", false},
+	}
+	for i, c := range cases {
+		if taken := b.takePage(c.domain); taken != c.taken {
+			t.Fatalf("Inconsistent result for case %d (%s): expected: %v, actual: %v", i, c.domain, c.taken, taken)
+		}
+	}
+
+	select {
+	case reason := <-exhausted:
+		reasons = append(reasons, reason)
+	case <-time.After(time.Second):
+	}
+	if len(reasons) != 1 || reasons[0] != STOP_REASON_MAX_PAGES {
+		t.Fatalf("Inconsistent exhausted reasons: expected: %v, actual: %v", []StopReason{STOP_REASON_MAX_PAGES}, reasons)
+	}
+
+	expected := BudgetSummaryStruct{Pages: 5, CappedDomains: 2}
+	if summary := b.summary(); summary != expected {
+		t.Fatalf("Inconsistent budget summary: expected: %#v, actual: %#v", expected, summary)
+	}
+
+	// 退还的预算可以被再次申请。
+	b.refundPage("sogou.com")
+	expected = BudgetSummaryStruct{Pages: 4, CappedDomains: 1}
+	if summary := b.summary(); summary != expected {
+		t.Fatalf("Inconsistent budget summary after refund: expected: %#v, actual: %#v", expected, summary)
+	}
+	if !b.takePage("sogou.com") {
+		t.Fatal("Couldn't take the refunded page!")
+	}
+}
+
+func TestBudgetBytesAndDuration(t *testing.T) {
+	exhausted := make(chan StopReason, 10)
+	b := newBudget(Budget{MaxBytes: 10, MaxDuration: time.Millisecond * 50},
+		func(reason StopReason) { exhausted <- reason })
+
+	body := b.countBody(ioutil.NopCloser(strings.NewReader("0123456789abcdef")))
+	if data, _ := ioutil.ReadAll(body); len(data) != 16 {
+		t.Fatalf("Inconsistent body length: expected: %d, actual: %d", 16, len(data))
+	}
+	if summary := b.summary(); summary.Bytes != 16 {
+		t.Fatalf("Inconsistent bytes: expected: %d, actual: %d", 16, summary.Bytes)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	b.start(ctx)
+
+	for _, expected := range []StopReason{STOP_REASON_MAX_BYTES, STOP_REASON_MAX_DURATION} {
+		select {
+		case reason := <-exhausted:
+			if reason != expected {
+				t.Fatalf("Inconsistent exhausted reason: expected: %s, actual: %s", expected, reason)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("The budget %q should be exhausted!", expected)
+		}
+	}
+
+	// 被取消的上下文会停止计量爬取时长。
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	b.start(ctx)
+	select {
+	case reason := <-exhausted:
+		t.Fatalf("Unexpected exhausted reason: %s", reason)
+	case <-time.After(time.Millisecond * 100):
+	}
+}
+
+// startBudgetSched 用于以给定的预算启动调度器，并等待它停止。
+func startBudgetSched(t *testing.T, serverURL string, b Budget) Scheduler {
+	requestArgs := genRequestArgs([]string{}, 1)
+	requestArgs.Budget = b
+	sched := NewScheduler()
+	if err := sched.Init(requestArgs, genDataArgs(10, 2, 1), genSimpleModuleArgs(1, 1, 1, t)); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+
+	firstHTTPReq, _ := http.NewRequest("GET", serverURL+"/", nil)
+	if err := sched.Start(firstHTTPReq); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+
+	deadline := time.Now().Add(time.Second * 5)
+	for sched.Status() != SCHED_STATUS_STOPPED {
+		if time.Now().After(deadline) {
+			sched.Stop()
+			t.Fatal("The scheduler should be stopped automatically!")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	return sched
+}
+
+func TestSchedBudget(t *testing.T) {
+	var count int32
+	block := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			w.WriteHeader(http.StatusNotFound)
+		case "/":
+			atomic.AddInt32(&count, 1)
+			w.Header().Set("Content-Type", "text/html")
+			for i := 0; i < 5; i++ {
+				fmt.Fprintf(w, `<a href="/page%d">page</a>`, i)
+			}
+			w.Write([]byte(strings.Repeat(" ", 1024)))
+		case "/page0", "/page1":
+			atomic.AddInt32(&count, 1)
+		default:
+			<-block
+		}
+	}))
+	defer server.Close()
+	defer close(block)
+
+	// 请求总数的预算用尽后，调度器会在已发送的请求处理完毕后停止。
+	sched := startBudgetSched(t, server.URL, Budget{MaxPages: 3})
+	if reason := sched.StopReason(); reason != STOP_REASON_MAX_PAGES {
+		t.Fatalf("Inconsistent stop reason: expected: %s, actual: %s", STOP_REASON_MAX_PAGES, reason)
+	}
+	if n := atomic.LoadInt32(&count); n != 3 {
+		t.Fatalf("Inconsistent download count: expected: %d, actual: %d", 3, n)
+	}
+	if err := sched.Wait(context.Background()); err != nil {
+		t.Fatalf("The crawl should be finished: %s", err)
+	}
+	summary := sched.Summary().Struct()
+	if summary.StopReason != STOP_REASON_MAX_PAGES || summary.Budget.Pages != 3 {
+		t.Fatalf("Inconsistent summary: stop reason: %s, budget: %#v", summary.StopReason, summary.Budget)
+	}
+	// 因预算用尽而被忽略的请求不会被记为已处理。
+	if n := sched.(*myScheduler).deduper.Len(); n != 3 {
+		t.Fatalf("Inconsistent deduper length: expected: %d, actual: %d", 3, n)
+	}
+
+	// 下载字节数的预算用尽后，调度器会立即停止。
+	sched = startBudgetSched(t, server.URL, Budget{MaxBytes: 512})
+	if reason := sched.StopReason(); reason != STOP_REASON_MAX_BYTES {
+		t.Fatalf("Inconsistent stop reason: expected: %s, actual: %s", STOP_REASON_MAX_BYTES, reason)
+	}
+
+	// 爬取时长的预算用尽后，调度器会立即停止，即使还有未完成的请求。
+	sched = startBudgetSched(t, server.URL, Budget{MaxDuration: time.Millisecond * 200})
+	if reason := sched.StopReason(); reason != STOP_REASON_MAX_DURATION {
+		t.Fatalf("Inconsistent stop reason: expected: %s, actual: %s", STOP_REASON_MAX_DURATION, reason)
+	}
+	if err := sched.Wait(context.Background()); err == nil || !strings.Contains(err.Error(), string(STOP_REASON_MAX_DURATION)) {
+		t.Fatalf("The error should contain the stop reason: %v", err)
+	}
+
+	// 手动停止时也会记录原因。
+	sched = NewScheduler()
+	sched.Init(genRequestArgs([]string{}, 1), genDataArgs(10, 2, 1), genSimpleModuleArgs(1, 1, 1, t))
+	firstHTTPReq, _ := http.NewRequest("GET", server.URL+"/", nil)
+	sched.Start(firstHTTPReq)
+	sched.Stop()
+	if reason := sched.StopReason(); reason != STOP_REASON_MANUAL {
+		t.Fatalf("Inconsistent stop reason: expected: %s, actual: %s", STOP_REASON_MANUAL, reason)
+	}
+}
@@ -0,0 +1,292 @@
+package scheduler
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultTrackingParams 代表常见的跟踪参数名称的模式列表。
+var DefaultTrackingParams = []string{
+	"utm_*", "gclid", "fbclid", "msclkid", "dclid", "yclid", "mc_cid", "mc_eid", "_ga", "_hsenc", "_hsmi",
+}
+
+// CanonicalRules 代表URL规范化的规则。
+// 规范化后的URL只用于判断请求是否重复，不会改变请求本身。
+// 无论规则如何，协议和主机名都会被转为小写，默认端口会被去除，
+// 路径中的"."和".."会被解析，百分号编码也会被规范化。
+type CanonicalRules struct {
+	// KeepFragment 代表是否保留片段（"#"之后的部分）。
+	KeepFragment bool `json:"keep_fragment"`
+	// KeepQueryOrder 代表是否保留查询参数的原有顺序，否则按名称和值排序。
+	KeepQueryOrder bool `json:"keep_query_order"`
+	// RemoveParams 代表需要移除的查询参数名称的模式列表。
+	// 模式使用通配符语法，且不区分大小写，如"utm_*"。
+	RemoveParams []string `json:"remove_params"`
+}
+
+// Check 用于检查规范化规则的有效性。
+func (rules *CanonicalRules) Check() error {
+	for _, pattern := range rules.RemoveParams {
+		if pattern == "" {
+			return genError("空的查询参数模式")
+		}
+	}
+	return nil
+}
+
+// Same 用于判断两个规范化规则是否相同。
+func (rules *CanonicalRules) Same(another *CanonicalRules) bool {
+	if another == nil {
+		return false
+	}
+
+	if another.KeepFragment != rules.KeepFragment ||
+		another.KeepQueryOrder != rules.KeepQueryOrder ||
+		len(another.RemoveParams) != len(rules.RemoveParams) {
+		return false
+	}
+
+	for i, pattern := range another.RemoveParams {
+		if pattern != rules.RemoveParams[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// canonicalizer 代表URL规范化器。
+type canonicalizer struct {
+	// rules 代表规范化规则。
+	rules CanonicalRules
+	// removeParams 代表需要移除的查询参数名称的模式。
+	removeParams []*regexp.Regexp
+}
+
+// newCanonicalizer 用于创建URL规范化器。
+func newCanonicalizer(rules CanonicalRules) (*canonicalizer, error) {
+	if err := rules.Check(); err != nil {
+		return nil, err
+	}
+
+	c := &canonicalizer{rules: rules}
+	for _, pattern := range rules.RemoveParams {
+		re, err := regexp.Compile(globToRegexp(strings.ToLower(pattern)))
+		if err != nil {
+			return nil, genError(fmt.Sprintf("无效的查询参数模式 %q: %s", pattern, err))
+		}
+		c.removeParams = append(c.removeParams, re)
+	}
+	return c, nil
+}
+
+// canonicalize 用于获取给定URL的规范形式。
+func (c *canonicalizer) canonicalize(u *url.URL) string {
+	if u.Opaque != "" {
+		return u.String()
+	}
+
+	var b strings.Builder
+	scheme := strings.ToLower(u.Scheme)
+	if scheme != "" {
+		b.WriteString(scheme)
+		b.WriteString(":")
+	}
+
+	if u.Host != "" || u.User != nil || scheme == "http" || scheme == "https" {
+		b.WriteString("//")
+		if u.User != nil {
+			b.WriteString(u.User.String())
+			b.WriteString("@")
+		}
+		b.WriteString(canonicalHost(scheme, u))
+	}
+
+	path := removeDotSegments(normalizePercentEncoding(u.EscapedPath()))
+	if path == "" {
+		path = "/"
+	}
+	b.WriteString(path)
+
+	if query := c.canonicalQuery(u.RawQuery); query != "" {
+		b.WriteString("?")
+		b.WriteString(query)
+	}
+
+	if c.rules.KeepFragment && u.Fragment != "" {
+		b.WriteString("#")
+		b.WriteString(u.EscapedFragment())
+	}
+	return b.String()
+}
+
+// canonicalQuery 用于获取查询字符串的规范形式。
+func (c *canonicalizer) canonicalQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+
+	type param struct {
+		name  string
+		value string
+	}
+	params := make([]param, 0, strings.Count(rawQuery, "&")+1)
+	for _, pair := range strings.Split(rawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		pair = normalizePercentEncoding(pair)
+		name := pair
+		if i := strings.IndexByte(pair, '='); i >= 0 {
+			name = pair[:i]
+		}
+		if c.removed(name) {
+			continue
+		}
+		params = append(params, param{name: name, value: pair[len(name):]})
+	}
+
+	if !c.rules.KeepQueryOrder {
+		sort.SliceStable(params, func(i, j int) bool {
+			if params[i].name != params[j].name {
+				return params[i].name < params[j].name
+			}
+			return params[i].value < params[j].value
+		})
+	}
+
+	pairs := make([]string, len(params))
+	for i, p := range params {
+		pairs[i] = p.name + p.value
+	}
+	return strings.Join(pairs, "&")
+}
+
+// removed 用于判断给定名称的查询参数是否需要移除。
+func (c *canonicalizer) removed(rawName string) bool {
+	if len(c.removeParams) == 0 {
+		return false
+	}
+
+	name, err := url.QueryUnescape(rawName)
+	if err != nil {
+		name = rawName
+	}
+	name = strings.ToLower(name)
+	for _, re := range c.removeParams {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// canonicalHost 用于获取URL中的主机部分的规范形式。
+// 主机名会被转为小写并去掉末尾的"."，与协议对应的默认端口会被去除。
+func canonicalHost(scheme string, u *url.URL) string {
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	port := u.Port()
+	if (scheme == "http" && port == "80") || (scheme == "https" && port == "443") {
+		port = ""
+	}
+
+	if port != "" {
+		return net.JoinHostPort(host, port)
+	}
+	if strings.Contains(host, ":") {
+		return "[" + host + "]"
+	}
+	return host
+}
+
+// removeDotSegments 用于解析路径中的"."和".."，见RFC 3986的5.2.4节。
+func removeDotSegments(path string) string {
+	if !strings.Contains(path, ".") {
+		return path
+	}
+
+	segments := strings.Split(path, "/")
+	output := make([]string, 0, len(segments))
+	for i, segment := range segments {
+		last := i == len(segments)-1
+		switch segment {
+		case ".":
+			if last {
+				output = append(output, "")
+			}
+		case "..":
+			// 第一个元素是绝对路径开头的空字符串，不能被移除。
+			if len(output) > 1 {
+				output = output[:len(output)-1]
+			}
+			if last {
+				output = append(output, "")
+			}
+		default:
+			output = append(output, segment)
+		}
+	}
+
+	result := strings.Join(output, "/")
+	if strings.HasPrefix(path, "/") && !strings.HasPrefix(result, "/") {
+		result = "/" + result
+	}
+	return result
+}
+
+// normalizePercentEncoding 用于规范化百分号编码，见RFC 3986的6.2.2节。
+// 非保留字符的编码会被解码，其余编码中的十六进制数字会被转为大写。
+func normalizePercentEncoding(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+
+	b := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+			b = append(b, s[i])
+			continue
+		}
+		c := unhex(s[i+1])<<4 | unhex(s[i+2])
+		if isUnreserved(c) {
+			b = append(b, c)
+		} else {
+			b = append(b, '%', upperHex(s[i+1]), upperHex(s[i+2]))
+		}
+		i += 2
+	}
+	return string(b)
+}
+
+// isUnreserved 用于判断给定字节是否为URL中的非保留字符。
+func isUnreserved(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+// unhex 用于获取十六进制数字的值。
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	}
+	return c - 'A' + 10
+}
+
+// isHex 用于判断给定字节是否为十六进制数字。
+func isHex(c byte) bool {
+	return ('0' <= c && c <= '9') || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
+
+// upperHex 用于把十六进制数字转为大写。
+func upperHex(c byte) byte {
+	if 'a' <= c && c <= 'f' {
+		return c - 'a' + 'A'
+	}
+	return c
+}
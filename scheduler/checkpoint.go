@@ -0,0 +1,277 @@
+package scheduler
+
+import (
+	"bufio"
+	"bytes"
+	"crawler/module"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// checkpointVersion 代表检查点文件格式的版本。
+// 版本2中的请求带有请求体，已处理的请求以指纹而不是URL表示，
+// 可遍历的去重器中的指纹被保存在单独的指纹文件中。
+const checkpointVersion = 2
+
+// CheckpointRequest 代表检查点中的待处理请求的结构。
+type CheckpointRequest struct {
+	URL      string        `json:"url"`
+	Method   string        `json:"method"`
+	Header   http.Header   `json:"header,omitempty"`
+	Depth    uint32        `json:"depth"`
+	Priority int           `json:"priority,omitempty"`
+	Body     []byte        `json:"body,omitempty"`
+	Source   module.Source `json:"source,omitempty"`
+	Meta     module.Meta   `json:"meta,omitempty"`
+	// Attempt 代表请求已被重试的次数。
+	Attempt uint32 `json:"attempt,omitempty"`
+	// NotBefore 代表请求可被下载的最早时间，为nil时代表不限。
+	NotBefore *time.Time `json:"not_before,omitempty"`
+	// Redirects 代表得到该请求之前经过的重定向的次数。
+	Redirects uint32 `json:"redirects,omitempty"`
+}
+
+// Checkpoint 代表爬取进度检查点的结构。
+type Checkpoint struct {
+	// Version 代表检查点文件格式的版本。
+	Version int `json:"version"`
+	// CreatedAt 代表检查点的创建时间。
+	CreatedAt time.Time `json:"created_at"`
+	// AcceptedDomains 代表可以接受的主域名的列表，包括由首次请求添加的主域名。
+	AcceptedDomains []string `json:"accepted_primary_domains"`
+	// Requests 代表待处理的请求的列表，包括正在等待robots.txt规则的请求。
+	// 已下载但其响应尚未被分析、或其条目尚未被全部处理的请求同样属于待处理的请求，
+	// 恢复后会被重新下载，因此其中已被处理的条目可能会被再次处理。
+	Requests []CheckpointRequest `json:"requests"`
+	// FingerprintsFile 代表保存已处理的请求的指纹的文件的名称，该文件与检查点文件位于同一目录中。
+	// 文件中每行一个指纹。为空时代表去重器无法遍历，此时应使用DedupeState。
+	FingerprintsFile string `json:"fingerprints_file,omitempty"`
+	// DedupeState 代表无法遍历的去重器的状态的二进制编码，如布隆过滤器。
+	// 只有使用同种去重器时才能从中恢复。
+	DedupeState []byte `json:"dedupe_state,omitempty"`
+	// Summary 代表保存检查点时的调度器摘要。
+	// 摘要仅供查看，不会在恢复时被使用。
+	Summary SummaryStruct `json:"summary"`
+	// dir 代表检查点文件所在的目录。
+	dir string
+}
+
+// RangeFingerprints 用于遍历指纹文件中的已处理的请求的指纹。
+// 指纹会被逐个读出，不会被一次性加载到内存中。若fn返回false，则停止遍历。
+func (cp *Checkpoint) RangeFingerprints(fn func(fp string) bool) error {
+	if cp.FingerprintsFile == "" {
+		return nil
+	}
+
+	file, err := os.Open(filepath.Join(cp.dir, cp.FingerprintsFile))
+	if err != nil {
+		return genErrorByError(err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if fp := scanner.Text(); fp != "" && !fn(fp) {
+			return nil
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return genErrorByError(err)
+	}
+	return nil
+}
+
+// newCheckpointRequest 用于根据请求生成检查点中的请求。
+func newCheckpointRequest(req *module.Request) (CheckpointRequest, bool) {
+	if req == nil || !req.Valid() {
+		return CheckpointRequest{}, false
+	}
+
+	httpReq := req.HTTPReq()
+	cpReq := CheckpointRequest{
+		URL:       httpReq.URL.String(),
+		Method:    httpReq.Method,
+		Header:    httpReq.Header,
+		Depth:     req.Depth(),
+		Priority:  req.Priority(),
+		Body:      req.Body(),
+		Source:    req.Source(),
+		Meta:      req.Meta(),
+		Attempt:   req.Attempt(),
+		Redirects: req.Redirects(),
+	}
+	if notBefore := req.NotBefore(); !notBefore.IsZero() {
+		cpReq.NotBefore = &notBefore
+	}
+	return cpReq, true
+}
+
+// toRequest 用于根据检查点中的请求重新生成请求。
+func (cpReq CheckpointRequest) toRequest() (*module.Request, error) {
+	method := cpReq.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	var body io.Reader
+	if cpReq.Body != nil {
+		body = bytes.NewReader(cpReq.Body)
+	}
+
+	httpReq, err := http.NewRequest(method, cpReq.URL, body)
+	if err != nil {
+		return nil, genErrorByError(err)
+	}
+
+	for key, values := range cpReq.Header {
+		for _, value := range values {
+			httpReq.Header.Add(key, value)
+		}
+	}
+
+	req := module.NewRequest(httpReq, cpReq.Depth)
+	req.SetPriority(cpReq.Priority)
+	req.SetSource(cpReq.Source)
+	for key, value := range cpReq.Meta {
+		req.SetMeta(key, value)
+	}
+	var notBefore time.Time
+	if cpReq.NotBefore != nil {
+		notBefore = *cpReq.NotBefore
+	}
+	req.SetRetryState(cpReq.Attempt, notBefore)
+	req.SetRedirects(cpReq.Redirects)
+	return req, nil
+}
+
+// LoadCheckpoint 用于从给定的文件中加载检查点。
+func LoadCheckpoint(filePath string) (*Checkpoint, error) {
+	b, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, genErrorByError(err)
+	}
+
+	var cp Checkpoint
+	if err = json.Unmarshal(b, &cp); err != nil {
+		return nil, genError(fmt.Sprintf("invalid checkpoint file %s: %s", filePath, err))
+	}
+
+	if cp.Version != checkpointVersion {
+		return nil, genError(fmt.Sprintf("unsupported checkpoint version: %d (file: %s)", cp.Version, filePath))
+	}
+
+	cp.dir = filepath.Dir(filePath)
+	return &cp, nil
+}
+
+// saveCheckpoint 用于把检查点保存到给定的文件中。
+// 检查点会先被写入临时文件再替换目标文件，以避免留下不完整的文件。
+func saveCheckpoint(cp *Checkpoint, filePath string) error {
+	b, err := json.MarshalIndent(cp, "", "    ")
+	if err != nil {
+		return genErrorByError(err)
+	}
+
+	tmpFile, err := ioutil.TempFile(filepath.Dir(filePath), filepath.Base(filePath)+".tmp")
+	if err != nil {
+		return genErrorByError(err)
+	}
+
+	tmpPath := tmpFile.Name()
+	defer os.Remove(tmpPath)
+	if _, err = tmpFile.Write(b); err != nil {
+		tmpFile.Close()
+		return genErrorByError(err)
+	}
+
+	if err = tmpFile.Close(); err != nil {
+		return genErrorByError(err)
+	}
+
+	if err = os.Rename(tmpPath, filePath); err != nil {
+		return genErrorByError(err)
+	}
+
+	return nil
+}
+
+func (sched *myScheduler) Checkpoint(filePath string) (err error) {
+	logger.Infof("Save checkpoint to %s...", filePath)
+	status := sched.Status()
+	if status != SCHED_STATUS_PAUSED && status != SCHED_STATUS_STOPPED {
+		errMsg := fmt.Sprintf("couldn't save checkpoint in status %q", GetStatusDescription(status))
+		return genError(errMsg)
+	}
+
+	if filePath == "" {
+		return genParameterError("empty checkpoint file path")
+	}
+
+	cp := &Checkpoint{
+		Version:         checkpointVersion,
+		CreatedAt:       time.Now(),
+		AcceptedDomains: []string{},
+		Requests:        []CheckpointRequest{},
+		Summary:         sched.summary.Struct(),
+	}
+
+	sched.acceptedDomainMap.Range(func(key string, element interface{}) bool {
+		cp.AcceptedDomains = append(cp.AcceptedDomains, key)
+		return true
+	})
+
+	sched.pendingReqMap.Range(func(key string, element interface{}) bool {
+		req, ok := element.(*module.Request)
+		if !ok {
+			return true
+		}
+		if cpReq, ok := newCheckpointRequest(req); ok {
+			cp.Requests = append(cp.Requests, cpReq)
+		}
+		return true
+	})
+
+	// 正在等待robots.txt规则的请求尚未被记为已处理，恢复时会被重新检查。
+	for _, req := range sched.parkedReqs.list() {
+		if exists, err := sched.deduper.Contains(sched.fingerprint(req)); err == nil && exists {
+			continue
+		}
+		if cpReq, ok := newCheckpointRequest(req); ok {
+			cp.Requests = append(cp.Requests, cpReq)
+		}
+	}
+
+	if err = saveDedupeState(sched.deduper, cp, filePath); err != nil {
+		return
+	}
+
+	sort.Strings(cp.AcceptedDomains)
+	sort.Slice(cp.Requests, func(i, j int) bool {
+		if cp.Requests[i].Depth != cp.Requests[j].Depth {
+			return cp.Requests[i].Depth < cp.Requests[j].Depth
+		}
+		if cp.Requests[i].URL != cp.Requests[j].URL {
+			return cp.Requests[i].URL < cp.Requests[j].URL
+		}
+		return cp.Requests[i].Method < cp.Requests[j].Method
+	})
+
+	if err = saveCheckpoint(cp, filePath); err != nil {
+		if cp.FingerprintsFile != "" {
+			os.Remove(filepath.Join(cp.dir, cp.FingerprintsFile))
+		}
+		return
+	}
+	removeStaleFingerprints(filePath, cp.FingerprintsFile)
+
+	logger.Infof("-- Checkpoint: accepted domains: %d, requests: %d, pending requests: %d",
+		len(cp.AcceptedDomains), sched.deduper.Len(), len(cp.Requests))
+	return nil
+}
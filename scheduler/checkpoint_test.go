@@ -0,0 +1,296 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"crawler/module"
+)
+
+func TestCheckpointRequest(t *testing.T) {
+	url := "http://cn.bing.com/search?q=golang"
+	httpReq, err := http.NewRequest("POST", url, strings.NewReader("page=2"))
+	if err != nil {
+		t.Fatalf("An error occurs when creating a HTTP request: %s (url: %s)", err, url)
+	}
+	httpReq.Header.Set("User-Agent", "crawler")
+
+	origReq := module.NewRequest(httpReq, 2)
+	origReq.SetSource(module.SOURCE_LINK)
+	origReq.SetMeta("category", "news")
+	cpReq, ok := newCheckpointRequest(origReq)
+	if !ok {
+		t.Fatalf("Couldn't generate checkpoint request!")
+	}
+
+	req, err := cpReq.toRequest()
+	if err != nil {
+		t.Fatalf("An error occurs when restoring request: %s (cpReq: %#v)", err, cpReq)
+	}
+
+	if req.HTTPReq().URL.String() != url {
+		t.Fatalf("Inconsistent URL: expected: %s, actual: %s", url, req.HTTPReq().URL)
+	}
+
+	if req.HTTPReq().Method != "POST" {
+		t.Fatalf("Inconsistent method: expected: %s, actual: %s", "POST", req.HTTPReq().Method)
+	}
+
+	if ua := req.HTTPReq().Header.Get("User-Agent"); ua != "crawler" {
+		t.Fatalf("Inconsistent header: expected: %s, actual: %s", "crawler", ua)
+	}
+
+	if req.Depth() != 2 {
+		t.Fatalf("Inconsistent depth: expected: %d, actual: %d", 2, req.Depth())
+	}
+
+	if body := string(req.Body()); body != "page=2" {
+		t.Fatalf("Inconsistent body: expected: %s, actual: %s", "page=2", body)
+	}
+
+	if category, _ := req.Meta().GetString("category"); category != "news" || req.Source() != module.SOURCE_LINK {
+		t.Fatalf("Inconsistent meta: source: %s, meta: %v", req.Source(), req.Meta())
+	}
+
+	if _, ok := newCheckpointRequest(nil); ok {
+		t.Fatalf("It still can generate checkpoint request with nil request!")
+	}
+}
+
+// countFingerprints 用于统计检查点中的指纹的数量。
+func countFingerprints(t *testing.T, cp *Checkpoint) int {
+	var n int
+	if err := cp.RangeFingerprints(func(fp string) bool {
+		n++
+		return true
+	}); err != nil {
+		t.Fatalf("An error occurs when reading fingerprints: %s", err)
+	}
+	return n
+}
+
+func TestSchedCheckpoint(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "crawler.checkpoint")
+	requestArgs := genRequestArgs([]string{}, 1)
+	dataArgs := genDataArgs(10, 2, 1)
+	moduleArgs := genSimpleModuleArgs(3, 2, 1, t)
+	sched := NewScheduler()
+	if err := sched.Init(requestArgs, dataArgs, moduleArgs); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+
+	// 测试未暂停状态下的保存。
+	if err := sched.Checkpoint(filePath); err == nil {
+		t.Fatal("No error when save checkpoint before start!")
+	}
+
+	url := "http://cn.bing.com/search?q=golang"
+	firstHTTPReq, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		t.Fatalf("An error occurs when creating a HTTP request: %s (url: %s)", err, url)
+	}
+
+	if err = sched.Start(firstHTTPReq); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	waitForParkedReqs(sched.(*myScheduler))
+
+	if err = sched.Pause(); err != nil {
+		t.Fatalf("An error occurs when pausing scheduler: %s", err)
+	}
+
+	mySched := sched.(*myScheduler)
+	urls := []string{
+		"http://cn.bing.com/images/search?q=golang",
+		"http://cn.bing.com/videos/search?q=golang",
+	}
+	for _, url := range urls {
+		httpReq, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			t.Fatalf("An error occurs when creating a HTTP request: %s (url: %s)", err, url)
+		}
+		if !mySched.sendReq(module.NewRequest(httpReq, 1)) {
+			t.Fatalf("Couldn't send request! (url: %s)", url)
+		}
+	}
+
+	if err = sched.Checkpoint(filePath); err != nil {
+		t.Fatalf("An error occurs when saving checkpoint: %s", err)
+	}
+
+	// 再次保存时之前的指纹文件会被删除。
+	if err = sched.Checkpoint(filePath); err != nil {
+		t.Fatalf("An error occurs when saving checkpoint: %s", err)
+	}
+	if matches, _ := filepath.Glob(filePath + ".*" + fingerprintsFileSuffix); len(matches) != 1 {
+		t.Fatalf("Inconsistent fingerprints files: %v", matches)
+	}
+
+	if err = sched.Stop(); err != nil {
+		t.Fatalf("An error occurs when stopping scheduler: %s", err)
+	}
+
+	cp, err := LoadCheckpoint(filePath)
+	if err != nil {
+		t.Fatalf("An error occurs when loading checkpoint: %s", err)
+	}
+
+	if n := countFingerprints(t, cp); n != 3 {
+		t.Fatalf("Inconsistent fingerprint number: expected: %d, actual: %d", 3, n)
+	}
+
+	if len(cp.Requests) < len(urls) {
+		t.Fatalf("Inconsistent pending request number: expected: >=%d, actual: %d", len(urls), len(cp.Requests))
+	}
+
+	if len(cp.AcceptedDomains) != 1 || cp.AcceptedDomains[0] != "bing.com" {
+		t.Fatalf("Inconsistent accepted domains: expected: %v, actual: %v", []string{"bing.com"}, cp.AcceptedDomains)
+	}
+
+	if cp.Summary.NumURL != 3 {
+		t.Fatalf("Inconsistent URL number in summary: expected: %d, actual: %d", 3, cp.Summary.NumURL)
+	}
+
+	// 测试从检查点启动。
+	sched = NewScheduler()
+	if err = sched.Init(requestArgs, dataArgs, moduleArgs); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+
+	if err = sched.StartFrom(filePath); err != nil {
+		t.Fatalf("An error occurs when starting scheduler from checkpoint: %s", err)
+	}
+	defer sched.Stop()
+
+	mySched = sched.(*myScheduler)
+	if n := mySched.deduper.Len(); n != 3 {
+		t.Fatalf("Inconsistent deduper length: expected: %d, actual: %d", 3, n)
+	}
+
+	if mySched.acceptedDomainMap.Get("bing.com") == nil {
+		t.Fatalf("The primary domain %q has not been restored!", "bing.com")
+	}
+
+	time.Sleep(time.Millisecond * 100)
+	// 已恢复的URL不能被重复发送。
+	httpReq, _ := http.NewRequest("GET", urls[0], nil)
+	if mySched.sendReq(module.NewRequest(httpReq, 1)) {
+		t.Fatalf("It still can send repeated request after restore!")
+	}
+}
+
+func TestSchedCheckpointUnfinishedWork(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	}))
+	defer server.Close()
+
+	requestArgs := genRequestArgs([]string{}, 1)
+	requestArgs.IgnoreRobots = true
+	sched := NewScheduler()
+	if err := sched.Init(requestArgs, genDataArgs(10, 2, 1), genSimpleModuleArgs(1, 1, 1, t)); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	defer sched.Stop()
+
+	seed, _ := http.NewRequest("GET", server.URL+"/", nil)
+	if err := sched.Start(seed); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	if err := sched.Wait(ctx); err != nil {
+		t.Fatalf("An error occurs when waiting for scheduler: %s", err)
+	}
+	if err := sched.Pause(); err != nil {
+		t.Fatalf("An error occurs when pausing scheduler: %s", err)
+	}
+
+	mySched := sched.(*myScheduler)
+	newReq := func(path string) *module.Request {
+		httpReq, _ := http.NewRequest("GET", server.URL+path, nil)
+		req := module.NewRequest(httpReq, 1)
+		mySched.pendingReqMap.Put(mySched.fingerprint(req), req)
+		return req
+	}
+
+	// 已下载但其响应尚未被分析的请求。
+	mySched.downloadOne(newReq("/resp"))
+	// 已分析但其条目尚未被处理的请求。
+	itemReq := newReq("/item")
+	group := mySched.newItemGroup(itemReq, []module.Data{module.Item{"name": "item"}})
+	mySched.putItem(&pendingItem{item: module.Item{"name": "item"}, group: group})
+	// 等待重试的请求。
+	notBefore := time.Now().Add(time.Millisecond * 200).Round(0)
+	mySched.enqueueReq(module.NewRetryRequest(newReq("/retry"), notBefore))
+	time.Sleep(time.Millisecond * 50)
+
+	filePath := filepath.Join(t.TempDir(), "crawler.checkpoint")
+	if err := sched.Checkpoint(filePath); err != nil {
+		t.Fatalf("An error occurs when saving checkpoint: %s", err)
+	}
+	cp, err := LoadCheckpoint(filePath)
+	if err != nil {
+		t.Fatalf("An error occurs when loading checkpoint: %s", err)
+	}
+	cpReqs := map[string]CheckpointRequest{}
+	for _, cpReq := range cp.Requests {
+		cpReqs[strings.TrimPrefix(cpReq.URL, server.URL)] = cpReq
+	}
+	for _, path := range []string{"/resp", "/item", "/retry"} {
+		if _, ok := cpReqs[path]; !ok {
+			t.Fatalf("The unfinished request %s should be saved: %v", path, cp.Requests)
+		}
+	}
+	retryReq, err := cpReqs["/retry"].toRequest()
+	if err != nil {
+		t.Fatalf("An error occurs when restoring request: %s", err)
+	}
+	if retryReq.Attempt() != 1 || !retryReq.NotBefore().Equal(notBefore) {
+		t.Fatalf("Inconsistent retry state: attempt: %d, not before: %s (expected: %s)",
+			retryReq.Attempt(), retryReq.NotBefore(), notBefore)
+	}
+
+	// 恢复后所有工作都会完成，不再有待处理的请求。
+	if err := sched.Resume(); err != nil {
+		t.Fatalf("An error occurs when resuming scheduler: %s", err)
+	}
+	for i := 0; i < 100 && mySched.pendingReqMap.Len() > 0; i++ {
+		time.Sleep(time.Millisecond * 20)
+	}
+	if n := mySched.pendingReqMap.Len(); n != 0 {
+		t.Fatalf("Inconsistent pending request number: expected: %d, actual: %d", 0, n)
+	}
+}
+
+func TestLoadCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := LoadCheckpoint(filepath.Join(dir, "missing")); err == nil {
+		t.Fatal("No error when load a missing checkpoint file!")
+	}
+
+	filePath := filepath.Join(dir, "invalid")
+	if err := os.WriteFile(filePath, []byte("{"), 0600); err != nil {
+		t.Fatalf("An error occurs when writing file: %s", err)
+	}
+
+	if _, err := LoadCheckpoint(filePath); err == nil {
+		t.Fatal("No error when load an invalid checkpoint file!")
+	}
+
+	filePath = filepath.Join(dir, "version")
+	if err := os.WriteFile(filePath, []byte(`{"version": 1}`), 0600); err != nil {
+		t.Fatalf("An error occurs when writing file: %s", err)
+	}
+
+	if _, err := LoadCheckpoint(filePath); err == nil {
+		t.Fatal("No error when load a checkpoint file with unsupported version!")
+	}
+}
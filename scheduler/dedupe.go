@@ -0,0 +1,208 @@
+package scheduler
+
+import (
+	"bufio"
+	"crawler/toolkit/dedupe"
+	"encoding"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 去重器参数的默认值。
+const (
+	// defaultBloomCapacity 代表布隆过滤器去重器的第一个过滤器的默认容量。
+	defaultBloomCapacity = 1 << 20
+	// defaultBloomFalsePositiveRate 代表布隆过滤器去重器的默认误判率上限。
+	defaultBloomFalsePositiveRate = 0.001
+	// defaultDiskMemoryKeys 代表磁盘去重器的内存中的键的默认数量上限。
+	defaultDiskMemoryKeys = 1 << 16
+)
+
+// DedupeArgs 代表用于判断请求是否重复的去重器的参数。
+type DedupeArgs struct {
+	// Kind 代表去重器的种类，为空时使用dedupe.KIND_MAP。
+	Kind dedupe.Kind `json:"kind"`
+	// BloomCapacity 代表布隆过滤器去重器的第一个过滤器的容量，为0时使用1048576。
+	BloomCapacity uint64 `json:"bloom_capacity"`
+	// BloomFalsePositiveRate 代表布隆过滤器去重器的误判率上限，为0时使用0.001。
+	// 被误判为重复的请求会被忽略。
+	BloomFalsePositiveRate float64 `json:"bloom_false_positive_rate"`
+	// DiskDir 代表磁盘去重器的工作目录的父目录，为空时使用系统的临时目录。
+	DiskDir string `json:"disk_dir"`
+	// DiskMemoryKeys 代表磁盘去重器的内存中的键的数量上限，为0时使用65536。
+	DiskMemoryKeys uint32 `json:"disk_memory_keys"`
+	// Deduper 代表自定义的去重器，不为nil时会忽略其他字段。
+	// 该字段不参与相同性的判断。
+	Deduper dedupe.Deduper `json:"-"`
+}
+
+// Check 用于检查去重器参数的有效性。
+func (args *DedupeArgs) Check() error {
+	switch args.Kind {
+	case "", dedupe.KIND_MAP, dedupe.KIND_BLOOM, dedupe.KIND_DISK:
+	default:
+		return genError(fmt.Sprintf("未知的去重器种类: %q", args.Kind))
+	}
+
+	if args.BloomFalsePositiveRate < 0 || args.BloomFalsePositiveRate >= 1 {
+		return genError(fmt.Sprintf("非法的布隆过滤器误判率: %v", args.BloomFalsePositiveRate))
+	}
+	return nil
+}
+
+// Same 用于判断两个去重器参数是否相同。
+func (args *DedupeArgs) Same(another *DedupeArgs) bool {
+	if another == nil {
+		return false
+	}
+
+	return another.Kind == args.Kind &&
+		another.BloomCapacity == args.BloomCapacity &&
+		another.BloomFalsePositiveRate == args.BloomFalsePositiveRate &&
+		another.DiskDir == args.DiskDir &&
+		another.DiskMemoryKeys == args.DiskMemoryKeys
+}
+
+// newDeduper 用于根据参数创建去重器。
+func newDeduper(args DedupeArgs) (dedupe.Deduper, error) {
+	if args.Deduper != nil {
+		return args.Deduper, nil
+	}
+
+	switch args.Kind {
+	case dedupe.KIND_BLOOM:
+		capacity := args.BloomCapacity
+		if capacity == 0 {
+			capacity = defaultBloomCapacity
+		}
+		rate := args.BloomFalsePositiveRate
+		if rate == 0 {
+			rate = defaultBloomFalsePositiveRate
+		}
+		return dedupe.NewBloomDeduper(capacity, rate)
+	case dedupe.KIND_DISK:
+		memoryKeys := args.DiskMemoryKeys
+		if memoryKeys == 0 {
+			memoryKeys = defaultDiskMemoryKeys
+		}
+		return dedupe.NewDiskDeduper(args.DiskDir, int(memoryKeys))
+	}
+	return dedupe.NewMapDeduper(), nil
+}
+
+// fingerprintsFileSuffix 代表检查点的指纹文件的名称后缀。
+const fingerprintsFileSuffix = ".fingerprints"
+
+// saveDedupeState 用于把去重器的状态保存到检查点中。
+// 若去重器可以遍历，则把所有的键逐行写入检查点文件所在目录中的指纹文件，
+// 以免保存检查点时占用与键的数量成正比的内存，否则把其二进制编码保存在检查点中。
+func saveDedupeState(deduper dedupe.Deduper, cp *Checkpoint, filePath string) error {
+	if ranger, ok := deduper.(dedupe.Ranger); ok {
+		// 每个检查点使用不同的指纹文件，以免替换检查点文件之前的意外中断导致两者不一致。
+		name := fmt.Sprintf("%s.%d%s", filepath.Base(filePath), cp.CreatedAt.UnixNano(), fingerprintsFileSuffix)
+		cp.dir = filepath.Dir(filePath)
+		if err := saveFingerprints(ranger, filepath.Join(cp.dir, name)); err != nil {
+			return err
+		}
+		cp.FingerprintsFile = name
+		return nil
+	}
+
+	if marshaler, ok := deduper.(encoding.BinaryMarshaler); ok {
+		state, err := marshaler.MarshalBinary()
+		if err != nil {
+			return genErrorByError(err)
+		}
+		cp.DedupeState = state
+		return nil
+	}
+	return genError(fmt.Sprintf("无法保存去重器的状态: %s", deduper.Summary().Kind))
+}
+
+// saveFingerprints 用于把可遍历的去重器中的键逐行写入给定的文件。
+func saveFingerprints(ranger dedupe.Ranger, filePath string) error {
+	tmpFile, err := ioutil.TempFile(filepath.Dir(filePath), filepath.Base(filePath)+".tmp")
+	if err != nil {
+		return genErrorByError(err)
+	}
+
+	tmpPath := tmpFile.Name()
+	defer os.Remove(tmpPath)
+	w := bufio.NewWriter(tmpFile)
+	var writeErr error
+	err = ranger.Range(func(key string) bool {
+		if _, writeErr = w.WriteString(key + "\n"); writeErr != nil {
+			return false
+		}
+		return true
+	})
+	if err == nil {
+		err = writeErr
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		tmpFile.Close()
+		return genErrorByError(err)
+	}
+
+	if err = tmpFile.Close(); err != nil {
+		return genErrorByError(err)
+	}
+	if err = os.Rename(tmpPath, filePath); err != nil {
+		return genErrorByError(err)
+	}
+	return nil
+}
+
+// removeStaleFingerprints 用于删除给定检查点文件的除keep以外的指纹文件。
+func removeStaleFingerprints(filePath string, keep string) {
+	dir := filepath.Dir(filePath)
+	prefix := filepath.Base(filePath) + "."
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		logger.Warnf("Couldn't list the stale fingerprint files: %s", err)
+		return
+	}
+
+	for _, info := range infos {
+		name := info.Name()
+		if name == keep || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, fingerprintsFileSuffix) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			logger.Warnf("Couldn't remove the stale fingerprint file: %s", err)
+		}
+	}
+}
+
+// restoreDedupeState 用于从检查点中恢复去重器的状态。
+func restoreDedupeState(deduper dedupe.Deduper, cp *Checkpoint) error {
+	if cp.DedupeState != nil {
+		unmarshaler, ok := deduper.(encoding.BinaryUnmarshaler)
+		if !ok {
+			return genError(fmt.Sprintf("无法恢复去重器的状态: %s", deduper.Summary().Kind))
+		}
+
+		if err := unmarshaler.UnmarshalBinary(cp.DedupeState); err != nil {
+			return genErrorByError(err)
+		}
+	}
+
+	var addErr error
+	err := cp.RangeFingerprints(func(fp string) bool {
+		_, addErr = deduper.Add(fp)
+		return addErr == nil
+	})
+	if err == nil {
+		err = addErr
+	}
+	if err != nil {
+		return genErrorByError(err)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package scheduler
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"crawler/module"
+	"crawler/toolkit/dedupe"
+)
+
+func TestDedupeArgs(t *testing.T) {
+	invalidArgs := []DedupeArgs{
+		{Kind: "redis"},
+		{Kind: dedupe.KIND_BLOOM, BloomFalsePositiveRate: -0.1},
+		{Kind: dedupe.KIND_BLOOM, BloomFalsePositiveRate: 1},
+	}
+	for _, args := range invalidArgs {
+		if err := args.Check(); err == nil {
+			t.Fatalf("No error when checking invalid dedupe args: %#v", args)
+		}
+	}
+
+	one := DedupeArgs{Kind: dedupe.KIND_BLOOM, BloomFalsePositiveRate: 0.01}
+	another := DedupeArgs{Kind: dedupe.KIND_BLOOM, BloomFalsePositiveRate: 0.01, Deduper: dedupe.NewMapDeduper()}
+	if !one.Same(&another) {
+		t.Fatalf("Inconsistent dedupe args sameness: expected: %v, actual: %v", true, false)
+	}
+	another.DiskMemoryKeys = 10
+	if one.Same(&another) {
+		t.Fatalf("Inconsistent dedupe args sameness: expected: %v, actual: %v", false, true)
+	}
+
+	custom := dedupe.NewMapDeduper()
+	if deduper, err := newDeduper(DedupeArgs{Kind: dedupe.KIND_DISK, Deduper: custom}); err != nil || deduper != custom {
+		t.Fatalf("The custom deduper should be used! (error: %v)", err)
+	}
+}
+
+func TestSchedDedupe(t *testing.T) {
+	for _, kind := range []dedupe.Kind{"", dedupe.KIND_MAP, dedupe.KIND_BLOOM, dedupe.KIND_DISK} {
+		requestArgs := genRequestArgs([]string{"example.com"}, 0)
+		requestArgs.IgnoreRobots = true
+		requestArgs.Dedupe = DedupeArgs{Kind: kind, BloomCapacity: 100, DiskDir: t.TempDir(), DiskMemoryKeys: 4}
+		sched := NewScheduler()
+		if err := sched.Init(requestArgs, genDataArgs(10, 2, 1), genSimpleModuleArgs(1, 1, 1, t)); err != nil {
+			t.Fatalf("An error occurs when initializing scheduler: %s (kind: %q)", err, kind)
+		}
+
+		mySched := sched.(*myScheduler)
+		for i := 0; i < 20; i++ {
+			for j := 0; j < 2; j++ {
+				httpReq, _ := http.NewRequest("GET", fmt.Sprintf("http://example.com/%d", i), nil)
+				if sent := mySched.sendReq(module.NewRequest(httpReq, 0)); sent != (j == 0) {
+					t.Fatalf("Inconsistent send result for %s: expected: %v, actual: %v (kind: %q)",
+						httpReq.URL, j == 0, sent, kind)
+				}
+			}
+		}
+
+		summary := sched.Summary().Struct()
+		expectedKind := kind
+		if expectedKind == "" {
+			expectedKind = dedupe.KIND_MAP
+		}
+		if summary.Dedupe.Kind != expectedKind || summary.Dedupe.Keys != 20 || summary.NumURL != 20 {
+			t.Fatalf("Inconsistent dedupe summary: %#v (URL number: %d)", summary.Dedupe, summary.NumURL)
+		}
+
+		// 去重器的状态可以被保存并恢复到同种去重器中。
+		cp := &Checkpoint{}
+		if err := saveDedupeState(mySched.deduper, cp, filepath.Join(t.TempDir(), "crawler.checkpoint")); err != nil {
+			t.Fatalf("An error occurs when saving dedupe state: %s (kind: %q)", err, kind)
+		}
+		if (cp.FingerprintsFile == "") == (kind != dedupe.KIND_BLOOM) {
+			t.Fatalf("Inconsistent fingerprints file: %q (kind: %q)", cp.FingerprintsFile, kind)
+		}
+		restored, _ := newDeduper(requestArgs.Dedupe)
+		if err := restoreDedupeState(restored, cp); err != nil {
+			t.Fatalf("An error occurs when restoring dedupe state: %s (kind: %q)", err, kind)
+		}
+		if restored.Len() != 20 {
+			t.Fatalf("Inconsistent restored deduper length: expected: %d, actual: %d (kind: %q)",
+				20, restored.Len(), kind)
+		}
+		restored.Close()
+		if err := sched.Close(); err != nil {
+			t.Fatalf("An error occurs when closing scheduler: %s (kind: %q)", err, kind)
+		}
+	}
+
+	bloom, _ := newDeduper(DedupeArgs{Kind: dedupe.KIND_BLOOM})
+	if err := restoreDedupeState(dedupe.NewMapDeduper(), &Checkpoint{DedupeState: []byte("state")}); err == nil {
+		t.Fatal("No error when restoring bloom state to a map deduper!")
+	}
+	if err := restoreDedupeState(bloom, &Checkpoint{DedupeState: []byte("state")}); err == nil {
+		t.Fatal("No error when restoring invalid state!")
+	}
+}
+
+func TestSchedClose(t *testing.T) {
+	diskDir := t.TempDir()
+	requestArgs := genRequestArgs([]string{"example.com"}, 0)
+	requestArgs.IgnoreRobots = true
+	requestArgs.Dedupe = DedupeArgs{Kind: dedupe.KIND_DISK, DiskDir: diskDir}
+	sched := NewScheduler()
+	if err := sched.Init(requestArgs, genDataArgs(10, 2, 1), genSimpleModuleArgs(1, 1, 1, t)); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+
+	httpReq, _ := http.NewRequest("GET", "http://example.com/", nil)
+	if err := sched.Start(httpReq); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	if err := sched.Close(); err == nil {
+		t.Fatal("No error when closing a started scheduler!")
+	}
+
+	// 停止后仍然可以保存检查点，关闭后磁盘去重器的工作目录会被删除。
+	sched.Stop()
+	if err := sched.Checkpoint(filepath.Join(t.TempDir(), "crawler.checkpoint")); err != nil {
+		t.Fatalf("An error occurs when saving checkpoint: %s", err)
+	}
+	if err := sched.Close(); err != nil {
+		t.Fatalf("An error occurs when closing scheduler: %s", err)
+	}
+	if infos, _ := ioutil.ReadDir(diskDir); len(infos) != 0 {
+		t.Fatalf("The working directory of the disk deduper was not removed: %d file(s) left", len(infos))
+	}
+}
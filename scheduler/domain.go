@@ -1,60 +1,81 @@
 package scheduler
 
 import (
-	"regexp"
+	"crawler/toolkit/publicsuffix"
+	"fmt"
+	"net"
 	"strings"
 )
 
-var regexpForIP = regexp.MustCompile(`((?:(?:25[0-5]|2[0-4]\d|[01]?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|[01]?\d?\d))`)
-
-var regexpForDomains = []*regexp.Regexp{
-	// *.xx or *.xxx.xx
-	regexp.MustCompile(`\.(com|com\.\w{2})$`),
-	regexp.MustCompile(`\.(gov|gov\.\w{2})$`),
-	regexp.MustCompile(`\.(net|net\.\w{2})$`),
-	regexp.MustCompile(`\.(org|org\.\w{2})$`),
-	// *.xx
-	regexp.MustCompile(`\.me$`),
-	regexp.MustCompile(`\.biz$`),
-	regexp.MustCompile(`\.info$`),
-	regexp.MustCompile(`\.name$`),
-	regexp.MustCompile(`\.mobi$`),
-	regexp.MustCompile(`\.so$`),
-	regexp.MustCompile(`\.asia$`),
-	regexp.MustCompile(`\.tel$`),
-	regexp.MustCompile(`\.tv$`),
-	regexp.MustCompile(`\.cc$`),
-	regexp.MustCompile(`\.co$`),
-	regexp.MustCompile(`\.\w{2}$`),
-}
-
 // getPrimaryDomain 用于获取给定主机名的主域名。
+// 主机名可以带有端口，也可以是IPv4或IPv6地址。
+// 主域名基于公共后缀列表计算，即有效顶级域名加一级，
+// 国际化域名会被转换为punycode形式。
+// 对于IP地址，主域名即为其规范形式。
+// 对于localhost等只有一级的主机名，主域名即为其本身，
+// 否则它会因为本身就是公共后缀（默认规则 "*"）而无法被访问。
 func getPrimaryDomain(host string) (string, error) {
 	host = strings.TrimSpace(host)
 	if host == "" {
 		return "", genError("empty host")
 	}
-	if regexpForIP.MatchString(host) {
-		return host, nil
+
+	hostname := splitHostname(host)
+	if hostname == "" {
+		return "", genError(fmt.Sprintf("invalid host %q", host))
 	}
-	var suffixIndex int
-	for _, re := range regexpForDomains {
-		pos := re.FindStringIndex(host)
-		if pos != nil {
-			suffixIndex = pos[0]
-			break
-		}
+
+	if ip := parseIP(hostname); ip != nil {
+		return ip.String(), nil
+	}
+
+	domain, err := normalizeDomain(hostname)
+	if err != nil {
+		return "", genError(fmt.Sprintf("invalid host %q: %s", host, err))
+	}
+	if !strings.Contains(domain, ".") {
+		return domain, nil
+	}
+
+	pd, err := publicsuffix.EffectiveTLDPlusOne(domain)
+	if err != nil {
+		return "", genError(fmt.Sprintf("unrecognized host %q: %s", host, err))
 	}
-	if suffixIndex > 0 {
-		var pdIndex int
-		firstPart := host[:suffixIndex]
-		index := strings.LastIndex(firstPart, ".")
-		if index < 0 {
-			pdIndex = 0
-		} else {
-			pdIndex = index + 1
+	return pd, nil
+}
+
+// splitHostname 用于去掉主机名中的端口以及IPv6地址两侧的方括号。
+func splitHostname(host string) string {
+	if strings.HasPrefix(host, "[") {
+		end := strings.Index(host, "]")
+		if end < 0 {
+			return ""
 		}
-		return host[pdIndex:], nil
+		return host[1:end]
+	}
+
+	// 只有一个冒号时才可能是带有端口的主机名，否则可能是IPv6地址。
+	if strings.Count(host, ":") == 1 {
+		return host[:strings.Index(host, ":")]
+	}
+	return host
+}
+
+// parseIP 用于解析IP地址，IPv6地址可以带有区域标识。
+// 若给定的字符串不是IP地址，则返回nil。
+func parseIP(hostname string) net.IP {
+	if i := strings.Index(hostname, "%"); i > 0 {
+		hostname = hostname[:i]
+	}
+	return net.ParseIP(hostname)
+}
+
+// normalizeDomain 用于把域名转换为规范形式，
+// 即去掉末尾的点，并转换为小写的ASCII形式。
+func normalizeDomain(domain string) (string, error) {
+	domain = strings.TrimSuffix(strings.TrimSpace(domain), ".")
+	if ip := parseIP(domain); ip != nil {
+		return ip.String(), nil
 	}
-	return "", genError("unrecognized host")
+	return publicsuffix.ToASCII(domain)
 }
@@ -3,36 +3,69 @@ package scheduler
 import "testing"
 
 func TestGetPrimaryDomain(t *testing.T) {
-	host := "127.0.0.1"
-	pd, err := getPrimaryDomain(host)
-	if err != nil {
-		t.Fatalf("An error occurs when getting primary domain: %s (host: %s)", err, host)
+	cases := map[string]string{
+		"127.0.0.1":             "127.0.0.1",
+		"127.0.0.1:8080":        "127.0.0.1",
+		"[::1]:8080":            "::1",
+		"[2001:DB8::1]":         "2001:db8::1",
+		"2001:db8::1":           "2001:db8::1",
+		"fe80::1%eth0":          "fe80::1",
+		"cn.bing.com":           "bing.com",
+		"cn.bing.com:443":       "bing.com",
+		"CN.Bing.Com.":          "bing.com",
+		"www.bbc.co.uk":         "bbc.co.uk",
+		"ivanqi.github.io":      "ivanqi.github.io",
+		"pkg.go.dev":            "go.dev",
+		"www.example.com.cn":    "example.com.cn",
+		"www.例子.中国":             "xn--fsqu00a.xn--fiqs8s",
+		"www.xn--fsqu00a.中国:80": "xn--fsqu00a.xn--fiqs8s",
+		"localhost":             "localhost",
+		"LOCALHOST:8080":        "localhost",
+		"intranet.corp":         "intranet.corp",
+		"wiki.intranet.corp":    "intranet.corp",
+		"123.notatld":           "123.notatld",
 	}
 
-	if pd != host {
-		t.Fatalf("Inconsistent primary domain: expected: %s, actual: %s", host, pd)
-	}
+	for host, expectedPD := range cases {
+		pd, err := getPrimaryDomain(host)
+		if err != nil {
+			t.Fatalf("An error occurs when getting primary domain: %s (host: %s)", err, host)
+		}
 
-	host = "cn.bing.com"
-	pd, err = getPrimaryDomain(host)
-	if err != nil {
-		t.Fatalf("An error occurs when getting primary domain: %s (host: %s)", err, host)
+		if pd != expectedPD {
+			t.Fatalf("Inconsistent primary domain for %q: expected: %s, actual: %s", host, expectedPD, pd)
+		}
 	}
 
-	expectedPD := "bing.com"
-	if pd != expectedPD {
-		t.Fatalf("Inconsistent primary domain: expected: %s, actual: %s", expectedPD, pd)
+	invalidHosts := []string{
+		"",
+		"co.uk",
+		"github.io",
+		"[::1",
+		":8080",
 	}
-
-	_, err = getPrimaryDomain("")
-	if err == nil {
-		t.Fatal("It still can get primary domain for a empty host!")
+	for _, host := range invalidHosts {
+		if pd, err := getPrimaryDomain(host); err == nil {
+			t.Fatalf("It still can get primary domain %q for an invalid host %q!", pd, host)
+		}
 	}
+}
 
-	host = "123.abc"
-	_, err = getPrimaryDomain(host)
-	if err == nil {
-		t.Fatalf("It still can get primary domain for a unrecognized host %q!", host)
+func TestNormalizeDomain(t *testing.T) {
+	cases := map[string]string{
+		"bing.com":   "bing.com",
+		" Bing.COM.": "bing.com",
+		"例子.中国":      "xn--fsqu00a.xn--fiqs8s",
+		"::1":        "::1",
 	}
 
+	for domain, expected := range cases {
+		normalized, err := normalizeDomain(domain)
+		if err != nil {
+			t.Fatalf("An error occurs when normalizing domain %q: %s", domain, err)
+		}
+		if normalized != expected {
+			t.Fatalf("Inconsistent normalized domain for %q: expected: %s, actual: %s", domain, expected, normalized)
+		}
+	}
 }
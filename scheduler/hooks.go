@@ -0,0 +1,143 @@
+package scheduler
+
+import (
+	"crawler/module"
+)
+
+// FilterReason 代表请求被过滤的原因。
+type FilterReason string
+
+// 请求被过滤的原因常量。
+const (
+	// FILTER_REASON_STOPPED 代表调度器已停止。
+	FILTER_REASON_STOPPED FilterReason = "stopped"
+	// FILTER_REASON_INVALID 代表请求无效。
+	FILTER_REASON_INVALID FilterReason = "invalid"
+	// FILTER_REASON_SCHEME 代表URL的scheme不受支持。
+	FILTER_REASON_SCHEME FilterReason = "scheme"
+	// FILTER_REASON_REPEATED 代表URL重复。
+	FILTER_REASON_REPEATED FilterReason = "repeated"
+	// FILTER_REASON_DOMAIN 代表主域名不在可接受的列表中。
+	FILTER_REASON_DOMAIN FilterReason = "domain"
+	// FILTER_REASON_SCOPE 代表URL不在范围规则允许的范围内。
+	FILTER_REASON_SCOPE FilterReason = "scope"
+	// FILTER_REASON_DEPTH 代表深度超过了最大深度。
+	FILTER_REASON_DEPTH FilterReason = "depth"
+	// FILTER_REASON_REDIRECTS 代表重定向次数超过了上限。
+	FILTER_REASON_REDIRECTS FilterReason = "redirects"
+	// FILTER_REASON_ROBOTS 代表请求被robots.txt禁止。
+	FILTER_REASON_ROBOTS FilterReason = "robots"
+	// FILTER_REASON_BUDGET 代表请求数量的预算已用尽。
+	FILTER_REASON_BUDGET FilterReason = "budget"
+)
+
+// Hook 代表用于观察爬取流程的钩子的接口类型。
+// 钩子的方法会在调度器的工作协程中被同步调用，
+// 因此该接口的实现类型必须是并发安全的，并且应该尽快返回。
+type Hook interface {
+	// OnRequestScheduled 会在请求被放入请求缓冲池时被调用。
+	OnRequestScheduled(req *module.Request)
+	// OnRequestFiltered 会在请求被过滤掉时被调用。
+	OnRequestFiltered(req *module.Request, reason FilterReason)
+	// OnResponse 会在下载器成功返回响应时被调用。
+	OnResponse(resp *module.Response, mid module.MID)
+	// OnItem 会在条目被条目处理管道处理后被调用。
+	OnItem(item module.Item, mid module.MID)
+	// OnError 会在产生错误时被调用。
+	OnError(err error, mid module.MID)
+	// OnStatusChange 会在调度器的状态变化时被调用。
+	OnStatusChange(oldStatus Status, newStatus Status)
+}
+
+// NopHook 代表什么都不做的钩子。
+// 可以把它嵌入到自定义的钩子中，从而只实现需要的方法。
+type NopHook struct{}
+
+func (NopHook) OnRequestScheduled(req *module.Request) {}
+
+func (NopHook) OnRequestFiltered(req *module.Request, reason FilterReason) {}
+
+func (NopHook) OnResponse(resp *module.Response, mid module.MID) {}
+
+func (NopHook) OnItem(item module.Item, mid module.MID) {}
+
+func (NopHook) OnError(err error, mid module.MID) {}
+
+func (NopHook) OnStatusChange(oldStatus Status, newStatus Status) {}
+
+// hookList 代表已注册的钩子的列表。
+type hookList []Hook
+
+// call 会依次对每个钩子调用给定的函数。
+// 钩子引发的运行时恐慌会被恢复并记录，不会影响调度器。
+func (hooks hookList) call(fn func(hook Hook)) {
+	for _, hook := range hooks {
+		func() {
+			defer func() {
+				if p := recover(); p != nil {
+					logger.Errorf("A hook panicked: %v (hook: %T)", p, hook)
+				}
+			}()
+			fn(hook)
+		}()
+	}
+}
+
+func (hooks hookList) onRequestScheduled(req *module.Request) {
+	hooks.call(func(hook Hook) { hook.OnRequestScheduled(req) })
+}
+
+func (hooks hookList) onRequestFiltered(req *module.Request, reason FilterReason) {
+	hooks.call(func(hook Hook) { hook.OnRequestFiltered(req, reason) })
+}
+
+func (hooks hookList) onResponse(resp *module.Response, mid module.MID) {
+	hooks.call(func(hook Hook) { hook.OnResponse(resp, mid) })
+}
+
+func (hooks hookList) onItem(item module.Item, mid module.MID) {
+	hooks.call(func(hook Hook) { hook.OnItem(item, mid) })
+}
+
+func (hooks hookList) onError(err error, mid module.MID) {
+	hooks.call(func(hook Hook) { hook.OnError(err, mid) })
+}
+
+func (hooks hookList) onStatusChange(oldStatus Status, newStatus Status) {
+	hooks.call(func(hook Hook) { hook.OnStatusChange(oldStatus, newStatus) })
+}
+
+// reportError 会通知钩子并把错误放入错误缓冲池。
+func (sched *myScheduler) reportError(err error, mid module.MID) {
+	if err == nil {
+		return
+	}
+
+	sched.hooks.onError(err, mid)
+	sendError(err, mid, sched.errorBufferPool)
+}
+
+// filterReq 会通知钩子请求已被过滤掉。
+// 结果值总是false，以便在sendReq中直接返回。
+func (sched *myScheduler) filterReq(req *module.Request, reason FilterReason) bool {
+	sched.hooks.onRequestFiltered(req, reason)
+	return false
+}
+
+// finishStatus 用于在状态变化的收尾阶段设置状态并通知钩子。
+// 若参数err不为nil，则恢复为oldStatus，否则设置为finalStatus。
+func (sched *myScheduler) finishStatus(oldStatus Status, finalStatus Status, err error) {
+	sched.statusLock.Lock()
+	currentStatus := sched.status
+	if err != nil {
+		sched.status = oldStatus
+	} else {
+		sched.status = finalStatus
+	}
+	newStatus := sched.status
+	sched.statusLock.Unlock()
+
+	if newStatus != currentStatus {
+		sched.hooks.onStatusChange(currentStatus, newStatus)
+	}
+}
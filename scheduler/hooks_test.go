@@ -0,0 +1,171 @@
+package scheduler
+
+import (
+	"context"
+	"crawler/module"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordHook 代表记录所有回调的钩子。
+type recordHook struct {
+	scheduled []string
+	filtered  map[FilterReason]int
+	responses int
+	items     int
+	errs      int
+	statuses  []Status
+	lock      sync.Mutex
+}
+
+func newRecordHook() *recordHook {
+	return &recordHook{filtered: map[FilterReason]int{}}
+}
+
+func (hook *recordHook) OnRequestScheduled(req *module.Request) {
+	hook.lock.Lock()
+	defer hook.lock.Unlock()
+	hook.scheduled = append(hook.scheduled, req.HTTPReq().URL.Path)
+}
+
+func (hook *recordHook) OnRequestFiltered(req *module.Request, reason FilterReason) {
+	hook.lock.Lock()
+	defer hook.lock.Unlock()
+	hook.filtered[reason]++
+}
+
+func (hook *recordHook) OnResponse(resp *module.Response, mid module.MID) {
+	hook.lock.Lock()
+	defer hook.lock.Unlock()
+	hook.responses++
+}
+
+func (hook *recordHook) OnItem(item module.Item, mid module.MID) {
+	hook.lock.Lock()
+	defer hook.lock.Unlock()
+	hook.items++
+}
+
+func (hook *recordHook) OnError(err error, mid module.MID) {
+	hook.lock.Lock()
+	defer hook.lock.Unlock()
+	hook.errs++
+}
+
+func (hook *recordHook) OnStatusChange(oldStatus Status, newStatus Status) {
+	hook.lock.Lock()
+	defer hook.lock.Unlock()
+	hook.statuses = append(hook.statuses, newStatus)
+}
+
+// panicHook 代表总是引发运行时恐慌的钩子。
+type panicHook struct {
+	NopHook
+}
+
+func (panicHook) OnRequestScheduled(req *module.Request) {
+	panic("boom")
+}
+
+func TestSchedHooks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			fmt.Fprint(w, "User-agent: *\nDisallow: /private\n")
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, `<a href="/a">a</a><a href="/a">a</a><a href="/private">p</a>`)
+			fmt.Fprint(w, `<a href="http://cn.bing.com/">bing</a>`)
+		}
+	}))
+	defer server.Close()
+
+	hook := newRecordHook()
+	requestArgs := genRequestArgs([]string{}, 1)
+	dataArgs := genDataArgs(10, 2, 1)
+	moduleArgs := genSimpleModuleArgs(1, 1, 1, t)
+	sched := NewScheduler()
+	if err := sched.Init(requestArgs, dataArgs, moduleArgs, hook, nil, panicHook{}); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+
+	firstHTTPReq, err := http.NewRequest("GET", server.URL+"/", nil)
+	if err != nil {
+		t.Fatalf("An error occurs when creating a HTTP request: %s (url: %s)", err, server.URL)
+	}
+
+	if err = sched.Start(firstHTTPReq); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	if err = sched.Wait(ctx); err != nil {
+		t.Fatalf("An error occurs when waiting for scheduler: %s", err)
+	}
+	sched.Stop()
+
+	hook.lock.Lock()
+	defer hook.lock.Unlock()
+	if len(hook.scheduled) != 2 {
+		t.Fatalf("Inconsistent scheduled requests: expected: %d, actual: %v", 2, hook.scheduled)
+	}
+
+	expectedFiltered := map[FilterReason]int{
+		FILTER_REASON_REPEATED: 1,
+		FILTER_REASON_ROBOTS:   1,
+		FILTER_REASON_DOMAIN:   1,
+	}
+	for reason, n := range expectedFiltered {
+		if hook.filtered[reason] != n {
+			t.Fatalf("Inconsistent filtered number for %q: expected: %d, actual: %d", reason, n, hook.filtered[reason])
+		}
+	}
+
+	if hook.responses != 2 {
+		t.Fatalf("Inconsistent response number: expected: %d, actual: %d", 2, hook.responses)
+	}
+
+	expectedStatuses := []Status{
+		SCHED_STATUS_INITIALIZED,
+		SCHED_STATUS_STARTING,
+		SCHED_STATUS_STARTED,
+		SCHED_STATUS_STOPPING,
+		SCHED_STATUS_STOPPED,
+	}
+	if len(hook.statuses) != len(expectedStatuses) {
+		t.Fatalf("Inconsistent status changes: expected: %v, actual: %v", expectedStatuses, hook.statuses)
+	}
+	for i, status := range expectedStatuses {
+		if hook.statuses[i] != status {
+			t.Fatalf("Inconsistent status changes: expected: %v, actual: %v", expectedStatuses, hook.statuses)
+		}
+	}
+}
+
+func TestReportError(t *testing.T) {
+	hook := newRecordHook()
+	sched := NewScheduler()
+	if err := sched.Init(genRequestArgs([]string{}, 0), genDataArgs(10, 2, 1),
+		genSimpleModuleArgs(1, 1, 1, t), hook); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+
+	mySched := sched.(*myScheduler)
+	mySched.reportError(nil, "")
+	mySched.reportError(errors.New("something wrong"), "")
+	if hook.errs != 1 {
+		t.Fatalf("Inconsistent error number: expected: %d, actual: %d", 1, hook.errs)
+	}
+
+	datum, err := mySched.errorBufferPool.Get()
+	if err != nil || datum == nil {
+		t.Fatalf("Couldn't get the reported error: %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+package scheduler
+
+import (
+	"context"
+	"crawler/module"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// inFlight 代表进行中的工作的计数器。
+// 每个已放入或正要放入缓冲池的请求、响应和条目都会被计入，
+// 直到相应的工作协程处理完它为止。
+// 计数归零时意味着爬取已完成，此时会关闭完成通知通道。
+type inFlight struct {
+	// count 代表进行中的工作的数量。
+	count int64
+	// doneCh 代表完成通知通道。
+	doneCh chan struct{}
+	// closed 代表完成通知通道是否已关闭。
+	closed bool
+	// finished 代表爬取是否已完成，而不是因调度器停止而结束。
+	finished bool
+	// lock 代表保护完成通知通道的互斥锁。
+	lock sync.Mutex
+}
+
+// newInFlight 用于创建一个进行中的工作的计数器。
+func newInFlight() *inFlight {
+	return &inFlight{doneCh: make(chan struct{})}
+}
+
+// incr 用于增加一个进行中的工作。
+func (f *inFlight) incr() {
+	atomic.AddInt64(&f.count, 1)
+}
+
+// decr 用于减少一个进行中的工作，并在计数归零时尝试发出完成通知。
+func (f *inFlight) decr() {
+	if atomic.AddInt64(&f.count, -1) <= 0 {
+		f.tryFinish()
+	}
+}
+
+// number 用于获取进行中的工作的数量。
+func (f *inFlight) number() int64 {
+	return atomic.LoadInt64(&f.count)
+}
+
+// tryFinish 会在没有进行中的工作时发出完成通知。
+func (f *inFlight) tryFinish() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.closed || atomic.LoadInt64(&f.count) > 0 {
+		return
+	}
+
+	f.finished = true
+	f.closed = true
+	close(f.doneCh)
+}
+
+// stop 会在调度器停止时发出通知。
+func (f *inFlight) stop() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.closed {
+		return
+	}
+
+	f.closed = true
+	close(f.doneCh)
+}
+
+// done 用于获取完成通知通道。
+func (f *inFlight) done() <-chan struct{} {
+	return f.doneCh
+}
+
+// ended 用于判断完成通知通道是否已关闭。
+func (f *inFlight) ended() bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.closed
+}
+
+// isFinished 用于判断爬取是否已完成。
+func (f *inFlight) isFinished() bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.finished
+}
+
+// closedDoneCh 代表已关闭的完成通知通道，在调度器尚未初始化时使用。
+var closedDoneCh = func() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}()
+
+func (sched *myScheduler) Done() <-chan struct{} {
+	if sched.inFlight == nil {
+		return closedDoneCh
+	}
+	return sched.inFlight.done()
+}
+
+func (sched *myScheduler) Wait(ctx context.Context) error {
+	// 计数器会在再次启动时被替换，所以等待和判断都使用同一个计数器。
+	f := sched.inFlight
+	if f == nil {
+		return genError("the scheduler has not been initialized")
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-f.done():
+	}
+
+	if !f.isFinished() {
+		return genError(fmt.Sprintf("the scheduler was stopped before the crawl finished (reason: %s)",
+			sched.StopReason()))
+	}
+	return nil
+}
+
+// putResp 会把响应放入响应缓冲池，并将其计入进行中的工作。
+func (sched *myScheduler) putResp(resp *module.Response) {
+	sched.inFlight.incr()
+	if !sendResp(resp, sched.respBufferPool) {
+		sched.inFlight.decr()
+	}
+}
+
+// putItem 会把条目放入条目缓冲池，并将其计入进行中的工作。
+func (sched *myScheduler) putItem(item *pendingItem) {
+	sched.inFlight.incr()
+	if !sendItem(item, sched.itemBufferPool) {
+		sched.inFlight.decr()
+	}
+}
@@ -0,0 +1,245 @@
+package scheduler
+
+import (
+	"context"
+	"crawler/toolkit/buffer"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestInFlight(t *testing.T) {
+	f := newInFlight()
+	f.incr()
+	f.incr()
+	f.decr()
+	select {
+	case <-f.done():
+		t.Fatal("It was done before all of the work finished!")
+	default:
+	}
+
+	f.decr()
+	select {
+	case <-f.done():
+	default:
+		t.Fatal("It was not done after all of the work finished!")
+	}
+
+	if !f.isFinished() || !f.ended() {
+		t.Fatalf("Inconsistent state: finished: %v, ended: %v", f.isFinished(), f.ended())
+	}
+
+	// 停止时同样会关闭通知通道，但不算完成。
+	f = newInFlight()
+	f.incr()
+	f.stop()
+	f.stop()
+	select {
+	case <-f.done():
+	default:
+		t.Fatal("It was not done after stopping!")
+	}
+
+	if f.isFinished() {
+		t.Fatal("It should not be finished after stopping!")
+	}
+}
+
+func TestSchedWait(t *testing.T) {
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		atomic.AddInt32(&count, 1)
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/" {
+			for i := 0; i < 3; i++ {
+				fmt.Fprintf(w, `<a href="/page%d">page</a>`, i)
+			}
+		}
+	}))
+	defer server.Close()
+
+	requestArgs := genRequestArgs([]string{}, 1)
+	dataArgs := genDataArgs(10, 2, 1)
+	moduleArgs := genSimpleModuleArgs(2, 2, 2, t)
+	sched := NewScheduler()
+	if err := sched.Init(requestArgs, dataArgs, moduleArgs); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+
+	firstHTTPReq, err := http.NewRequest("GET", server.URL+"/", nil)
+	if err != nil {
+		t.Fatalf("An error occurs when creating a HTTP request: %s (url: %s)", err, server.URL)
+	}
+
+	if err = sched.Start(firstHTTPReq); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	defer sched.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	if err = sched.Wait(ctx); err != nil {
+		t.Fatalf("An error occurs when waiting for scheduler: %s", err)
+	}
+
+	if n := atomic.LoadInt32(&count); n != 4 {
+		t.Fatalf("Inconsistent download count: expected: %d, actual: %d", 4, n)
+	}
+
+	if !sched.Idle() {
+		t.Fatal("The scheduler should be idle after the crawl finished!")
+	}
+}
+
+func TestSchedWaitStopped(t *testing.T) {
+	block := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/robots.txt" {
+			<-block
+		}
+	}))
+	defer server.Close()
+	defer close(block)
+
+	requestArgs := genRequestArgs([]string{}, 0)
+	dataArgs := genDataArgs(10, 2, 1)
+	moduleArgs := genSimpleModuleArgs(1, 1, 1, t)
+	sched := NewScheduler()
+	// 测试尚未初始化的情况。
+	select {
+	case <-sched.Done():
+	default:
+		t.Fatal("The done channel of an uninitialized scheduler should be closed!")
+	}
+	if err := sched.Wait(context.Background()); err == nil {
+		t.Fatal("No error when waiting for the uninitialized scheduler!")
+	}
+
+	if err := sched.Init(requestArgs, dataArgs, moduleArgs); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+
+	firstHTTPReq, err := http.NewRequest("GET", server.URL+"/", nil)
+	if err != nil {
+		t.Fatalf("An error occurs when creating a HTTP request: %s (url: %s)", err, server.URL)
+	}
+
+	if err = sched.Start(firstHTTPReq); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+
+	// 测试上下文先被取消的情况。
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	if err = sched.Wait(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Inconsistent error: expected: %v, actual: %v", context.DeadlineExceeded, err)
+	}
+
+	if sched.Idle() {
+		t.Fatal("The scheduler should not be idle while downloading!")
+	}
+
+	// 测试调度器在爬取完成之前被停止的情况。
+	sched.Stop()
+	if err = sched.Wait(context.Background()); err == nil {
+		t.Fatal("No error when waiting for the stopped scheduler!")
+	}
+}
+
+func TestSchedWaitSlowSeed(t *testing.T) {
+	var count int32
+	fastServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer fastServer.Close()
+	// 第二个种子请求的robots.txt响应较慢，放入它时第一个种子请求已处理完毕。
+	slowServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			time.Sleep(time.Millisecond * 500)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		atomic.AddInt32(&count, 1)
+	}))
+	defer slowServer.Close()
+
+	requestArgs := genRequestArgs([]string{}, 0)
+	dataArgs := genDataArgs(10, 2, 1)
+	moduleArgs := genSimpleModuleArgs(2, 2, 2, t)
+	sched := NewScheduler()
+	if err := sched.Init(requestArgs, dataArgs, moduleArgs); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	defer sched.Stop()
+
+	seeds := make([]*http.Request, 0, 2)
+	for _, serverURL := range []string{fastServer.URL, slowServer.URL} {
+		seed, err := http.NewRequest("GET", serverURL+"/", nil)
+		if err != nil {
+			t.Fatalf("An error occurs when creating a HTTP request: %s (url: %s)", err, serverURL)
+		}
+		seeds = append(seeds, seed)
+	}
+	if err := sched.StartWithSeeds(seeds); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	if err := sched.Wait(ctx); err != nil {
+		t.Fatalf("An error occurs when waiting for scheduler: %s", err)
+	}
+	if n := atomic.LoadInt32(&count); n != 1 {
+		t.Fatalf("The slow seed was not crawled before finishing! (count: %d)", n)
+	}
+}
+
+func TestSchedIncorrectData(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Millisecond * 200)
+	}))
+	defer server.Close()
+
+	requestArgs := genRequestArgs([]string{}, 0)
+	requestArgs.IgnoreRobots = true
+	hook := newRecordHook()
+	sched := NewScheduler()
+	if err := sched.Init(requestArgs, genDataArgs(10, 2, 1), genSimpleModuleArgs(1, 1, 1, t), hook); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	defer sched.Stop()
+	mySched := sched.(*myScheduler)
+
+	firstHTTPReq, _ := http.NewRequest("GET", server.URL+"/", nil)
+	if err := sched.Start(firstHTTPReq); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	// 类型不正确的数据会被丢弃，并且不会被一直计入进行中的工作。
+	for _, pool := range []buffer.Pool{mySched.reqBufferPool, mySched.respBufferPool, mySched.itemBufferPool} {
+		mySched.inFlight.incr()
+		if err := pool.Put("incorrect"); err != nil {
+			t.Fatalf("An error occurs when putting incorrect data: %s", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	if err := sched.Wait(ctx); err != nil {
+		t.Fatalf("An error occurs when waiting for scheduler: %s", err)
+	}
+	hook.lock.Lock()
+	defer hook.lock.Unlock()
+	if hook.errs != 3 {
+		t.Fatalf("Inconsistent error number: expected: %d, actual: %d", 3, hook.errs)
+	}
+}
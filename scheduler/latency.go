@@ -0,0 +1,113 @@
+package scheduler
+
+import (
+	"crawler/module"
+	"sync"
+	"time"
+)
+
+// LatencyBuckets 代表延迟直方图的各个桶的上限（包含）。
+// 直方图的桶比上限多一个，最后一个桶没有上限。
+var LatencyBuckets = [...]time.Duration{
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// HistogramSummaryStruct 代表延迟直方图的摘要类型。
+type HistogramSummaryStruct struct {
+	// Count 代表观测值的数量。
+	Count uint64 `json:"count"`
+	// Sum 代表观测值的总和。
+	Sum time.Duration `json:"sum"`
+	// Max 代表最大的观测值。
+	Max time.Duration `json:"max"`
+	// Buckets 代表落入各个桶的观测值的数量，桶的上限见LatencyBuckets。
+	Buckets [len(LatencyBuckets) + 1]uint64 `json:"buckets"`
+}
+
+// observe 用于记录一个观测值。
+func (h *HistogramSummaryStruct) observe(d time.Duration) {
+	h.Count++
+	h.Sum += d
+	if d > h.Max {
+		h.Max = d
+	}
+
+	i := 0
+	for i < len(LatencyBuckets) && d > LatencyBuckets[i] {
+		i++
+	}
+	h.Buckets[i]++
+}
+
+// Mean 用于获取观测值的平均值。
+func (h HistogramSummaryStruct) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// LatencySummaryStruct 代表下载的各阶段延迟的摘要类型。
+// DNS查询、建立连接和TLS握手只在实际发生时才会被记录，复用连接的下载不会计入。
+type LatencySummaryStruct struct {
+	DNS     HistogramSummaryStruct `json:"dns"`
+	Connect HistogramSummaryStruct `json:"connect"`
+	TLS     HistogramSummaryStruct `json:"tls"`
+	TTFB    HistogramSummaryStruct `json:"ttfb"`
+	Total   HistogramSummaryStruct `json:"total"`
+}
+
+// latencyRecorder 代表下载延迟的记录器。
+type latencyRecorder struct {
+	// latency 代表已记录的延迟。
+	latency LatencySummaryStruct
+	// lock 代表互斥锁。
+	lock sync.Mutex
+}
+
+// newLatencyRecorder 用于创建下载延迟的记录器。
+func newLatencyRecorder() *latencyRecorder {
+	return &latencyRecorder{}
+}
+
+// record 用于记录一次下载的各阶段耗时。
+// 总耗时为0的下载（如由下载器的缓存直接提供的响应）没有发出请求，不会被记录。
+func (recorder *latencyRecorder) record(timing module.Timing) {
+	if timing.Total == 0 {
+		return
+	}
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+
+	latency := &recorder.latency
+	if timing.DNS > 0 {
+		latency.DNS.observe(timing.DNS)
+	}
+	if timing.Connect > 0 {
+		latency.Connect.observe(timing.Connect)
+	}
+	if timing.TLS > 0 {
+		latency.TLS.observe(timing.TLS)
+	}
+	latency.TTFB.observe(timing.TTFB)
+	latency.Total.observe(timing.Total)
+}
+
+// summary 用于获取下载延迟的摘要。
+func (recorder *latencyRecorder) summary() LatencySummaryStruct {
+	if recorder == nil {
+		return LatencySummaryStruct{}
+	}
+
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+	return recorder.latency
+}
@@ -0,0 +1,93 @@
+package scheduler
+
+import (
+	"context"
+	"crawler/module"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLatencyRecorder(t *testing.T) {
+	var nilRecorder *latencyRecorder
+	if summary := nilRecorder.summary(); summary != (LatencySummaryStruct{}) {
+		t.Fatalf("Inconsistent latency summary: expected: %#v, actual: %#v", LatencySummaryStruct{}, summary)
+	}
+
+	recorder := newLatencyRecorder()
+	recorder.record(module.Timing{
+		DNS:     5 * time.Millisecond,
+		Connect: 10 * time.Millisecond,
+		TTFB:    300 * time.Millisecond,
+		Total:   time.Minute,
+	})
+	recorder.record(module.Timing{TTFB: 100 * time.Millisecond, Total: 200 * time.Millisecond})
+	// 没有发出请求的下载不会被记录。
+	recorder.record(module.Timing{})
+	summary := recorder.summary()
+
+	if summary.DNS.Count != 1 || summary.Connect.Count != 1 || summary.TLS.Count != 0 {
+		t.Fatalf("The phases which didn't happen should not be recorded: %#v", summary)
+	}
+	// 10ms会落入上限为10ms的桶。
+	if summary.Connect.Buckets[0] != 1 {
+		t.Fatalf("Inconsistent connect buckets: %v", summary.Connect.Buckets)
+	}
+
+	ttfb := summary.TTFB
+	if ttfb.Count != 2 || ttfb.Sum != 400*time.Millisecond || ttfb.Max != 300*time.Millisecond {
+		t.Fatalf("Inconsistent TTFB histogram: %#v", ttfb)
+	}
+	if ttfb.Buckets[2] != 1 || ttfb.Buckets[4] != 1 {
+		t.Fatalf("Inconsistent TTFB buckets: %v", ttfb.Buckets)
+	}
+	if mean := ttfb.Mean(); mean != 200*time.Millisecond {
+		t.Fatalf("Inconsistent mean: expected: %s, actual: %s", 200*time.Millisecond, mean)
+	}
+	if summary.Total.Buckets[len(LatencyBuckets)] != 1 {
+		t.Fatalf("The observation beyond all bounds should be in the last bucket: %v", summary.Total.Buckets)
+	}
+	if mean := (HistogramSummaryStruct{}).Mean(); mean != 0 {
+		t.Fatalf("Inconsistent mean: expected: %s, actual: %s", time.Duration(0), mean)
+	}
+}
+
+func TestSchedLatency(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		for i := 0; i < 3; i++ {
+			fmt.Fprintf(w, `<a href="/page%d">page</a>`, i)
+		}
+	}))
+	defer server.Close()
+
+	requestArgs := genRequestArgs([]string{}, 1)
+	requestArgs.IgnoreRobots = true
+	sched := NewScheduler()
+	if err := sched.Init(requestArgs, genDataArgs(10, 2, 1), genSimpleModuleArgs(1, 1, 1, t)); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	defer sched.Stop()
+
+	firstHTTPReq, _ := http.NewRequest("GET", server.URL+"/", nil)
+	if err := sched.Start(firstHTTPReq); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	sched.Wait(ctx)
+
+	latency := sched.Summary().Struct().Latency
+	if latency.Total.Count != 4 || latency.TTFB.Count != 4 {
+		t.Fatalf("Inconsistent latency count: expected: %d, actual: %d (ttfb: %d)",
+			4, latency.Total.Count, latency.TTFB.Count)
+	}
+	if latency.Connect.Count == 0 || latency.Total.Max <= 0 {
+		t.Fatalf("Inconsistent latency summary: %#v", latency)
+	}
+}
@@ -0,0 +1,157 @@
+package scheduler
+
+import (
+	"crawler/module"
+	"fmt"
+	"sync"
+)
+
+// moduleLeases 代表组件的租用记录。
+// 处理流程在使用组件之前租用它，使用完毕后归还，
+// 以便在移除组件时等待它进行中的工作完成。
+type moduleLeases struct {
+	// counts 代表各组件未归还的租用的数量。
+	counts map[module.MID]int
+	// lock 代表互斥锁。
+	lock sync.Mutex
+	// cond 代表用于等待租用归还的条件变量。
+	cond *sync.Cond
+}
+
+// init 用于初始化租用记录，调用方需持有锁。
+func (leases *moduleLeases) init() {
+	if leases.counts == nil {
+		leases.counts = make(map[module.MID]int)
+		leases.cond = sync.NewCond(&leases.lock)
+	}
+}
+
+// acquireModule 用于从组件注册器中获取并租用一个指定类型的组件。
+// 参数key会被传给组件的负载均衡策略，一般为请求的主机名。
+// 使用完毕后必须调用releaseModule归还。
+func (sched *myScheduler) acquireModule(moduleType module.Type, key string) (module.Module, error) {
+	leases := &sched.leases
+	for {
+		// 负载均衡策略可能会计算每个组件的评分，所以在持有锁之前选择组件，以免各处理流程相互阻塞。
+		m, err := sched.registrar.GetByKey(moduleType, key)
+		if err != nil || m == nil {
+			return m, err
+		}
+
+		// 组件可能在被选择之后被移除，此时需要重新选择。
+		leases.lock.Lock()
+		if modules, _ := sched.registrar.GetAllByType(moduleType); modules[m.ID()] != nil {
+			leases.init()
+			leases.counts[m.ID()]++
+			leases.lock.Unlock()
+			return m, nil
+		}
+		leases.lock.Unlock()
+	}
+}
+
+// releaseModule 用于归还已租用的组件。
+func (sched *myScheduler) releaseModule(m module.Module) {
+	leases := &sched.leases
+	leases.lock.Lock()
+	defer leases.lock.Unlock()
+
+	mid := m.ID()
+	if leases.counts[mid] <= 1 {
+		delete(leases.counts, mid)
+		leases.cond.Broadcast()
+		return
+	}
+	leases.counts[mid]--
+}
+
+// wakeLeaseWaiters 用于唤醒所有等待租用归还的流程，在调度器停止时调用。
+func (sched *myScheduler) wakeLeaseWaiters() {
+	leases := &sched.leases
+	leases.lock.Lock()
+	defer leases.lock.Unlock()
+
+	if leases.cond != nil {
+		leases.cond.Broadcast()
+	}
+}
+
+// checkStatusForModules 用于检查当前状态是否允许在运行时增减组件。
+func (sched *myScheduler) checkStatusForModules() error {
+	switch status := sched.Status(); status {
+	case SCHED_STATUS_INITIALIZED, SCHED_STATUS_STARTED, SCHED_STATUS_PAUSED:
+		return nil
+	default:
+		errMsg := fmt.Sprintf("无法在当前状态下增减组件: %s", GetStatusDescription(status))
+		return genError(errMsg)
+	}
+}
+
+func (sched *myScheduler) AddModule(m module.Module) error {
+	if err := sched.checkStatusForModules(); err != nil {
+		return err
+	}
+
+	if m == nil {
+		return genParameterError("nil module instance")
+	}
+
+	ok, err := sched.registrar.Register(m)
+	if err != nil {
+		return genErrorByError(err)
+	}
+
+	if !ok {
+		errMsg := fmt.Sprintf("Couldn't register module instance with MID %q!", m.ID())
+		return genError(errMsg)
+	}
+
+	logger.Infof("Module %q has been added.", m.ID())
+	return nil
+}
+
+func (sched *myScheduler) RemoveModule(mid module.MID) error {
+	if err := sched.checkStatusForModules(); err != nil {
+		return err
+	}
+
+	ok, moduleType := module.GetType(mid)
+	if !ok {
+		errMsg := fmt.Sprintf("illegal MID: %q", mid)
+		return genParameterError(errMsg)
+	}
+
+	leases := &sched.leases
+	leases.lock.Lock()
+	defer leases.lock.Unlock()
+
+	// 移除同类型的最后一个组件会使相应的处理流程无法继续。
+	modules, _ := sched.registrar.GetAllByType(moduleType)
+	if _, ok := modules[mid]; !ok {
+		errMsg := fmt.Sprintf("Couldn't find module instance with MID %q!", mid)
+		return genError(errMsg)
+	}
+	if len(modules) == 1 {
+		errMsg := fmt.Sprintf("Couldn't remove the last %s instance %q!", moduleType, mid)
+		return genError(errMsg)
+	}
+
+	// 注销后组件不会再被租用，再等待已租用的处理流程归还它。
+	if _, err := sched.registrar.Unregister(mid); err != nil {
+		return genErrorByError(err)
+	}
+
+	leases.init()
+	if leases.counts[mid] > 0 {
+		logger.Infof("Drain module %q... (in flight: %d)", mid, leases.counts[mid])
+	}
+	for leases.counts[mid] > 0 && !sched.canceled() {
+		leases.cond.Wait()
+	}
+
+	if n := leases.counts[mid]; n > 0 {
+		logger.Warnf("The scheduler has been stopped before module %q is drained. (in flight: %d)", mid, n)
+	}
+	logger.Infof("Module %q has been removed.", mid)
+	return nil
+}
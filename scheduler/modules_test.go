@@ -0,0 +1,177 @@
+package scheduler
+
+import (
+	"context"
+	"crawler/module"
+	"crawler/module/local/downloader"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSchedAddAndRemoveModule(t *testing.T) {
+	sched := NewScheduler()
+	extra := genSimpleDownloaders(1, false, module.NewSNGenertor(100, 0), t)[0]
+	if err := sched.AddModule(extra); err == nil {
+		t.Fatal("No error when adding module to an uninitialized scheduler!")
+	}
+
+	moduleArgs := genSimpleModuleArgs(1, 1, 1, t)
+	if err := sched.Init(genRequestArgs([]string{}, 1), genDataArgs(10, 2, 1), moduleArgs); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+
+	if err := sched.AddModule(nil); err == nil {
+		t.Fatal("No error when adding nil module!")
+	}
+	if err := sched.AddModule(moduleArgs.Downloaders[0]); err == nil {
+		t.Fatal("No error when adding a registered module!")
+	}
+	if err := sched.AddModule(extra); err != nil {
+		t.Fatalf("An error occurs when adding module: %s", err)
+	}
+	if n := len(sched.Summary().Struct().Downloaders); n != 2 {
+		t.Fatalf("Inconsistent downloader number: expected: %d, actual: %d", 2, n)
+	}
+
+	invalidMIDs := []module.MID{"", "X1", moduleArgs.Analyzers[0].ID(), "D1000"}
+	for _, mid := range invalidMIDs {
+		if err := sched.RemoveModule(mid); err == nil {
+			t.Fatalf("No error when removing module %q!", mid)
+		}
+	}
+
+	if err := sched.RemoveModule(extra.ID()); err != nil {
+		t.Fatalf("An error occurs when removing module: %s", err)
+	}
+	if n := len(sched.Summary().Struct().Downloaders); n != 1 {
+		t.Fatalf("Inconsistent downloader number: expected: %d, actual: %d", 1, n)
+	}
+}
+
+func TestSchedRemoveModuleDrain(t *testing.T) {
+	started := make(chan struct{}, 1)
+	block := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		started <- struct{}{}
+		<-block
+	}))
+	defer server.Close()
+
+	sched := NewScheduler()
+	moduleArgs := genSimpleModuleArgs(1, 1, 1, t)
+	if err := sched.Init(genRequestArgs([]string{}, 1), genDataArgs(10, 2, 1), moduleArgs); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	defer sched.Stop()
+
+	firstHTTPReq, _ := http.NewRequest("GET", server.URL+"/", nil)
+	if err := sched.Start(firstHTTPReq); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+
+	select {
+	case <-started:
+	case <-time.After(time.Second * 5):
+		t.Fatal("The download should be started!")
+	}
+
+	extra := genSimpleDownloaders(1, false, module.NewSNGenertor(100, 0), t)[0]
+	if err := sched.AddModule(extra); err != nil {
+		t.Fatalf("An error occurs when adding module: %s", err)
+	}
+
+	// 移除正在下载的下载器时，需要等待下载完成。
+	removed := make(chan error, 1)
+	go func() {
+		removed <- sched.RemoveModule(moduleArgs.Downloaders[0].ID())
+	}()
+
+	select {
+	case err := <-removed:
+		t.Fatalf("The module should not be removed before it is drained: %v", err)
+	case <-time.After(time.Millisecond * 100):
+	}
+	if n := len(sched.Summary().Struct().Downloaders); n != 1 {
+		t.Fatalf("Inconsistent downloader number: expected: %d, actual: %d", 1, n)
+	}
+
+	close(block)
+	select {
+	case err := <-removed:
+		if err != nil {
+			t.Fatalf("An error occurs when removing module: %s", err)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("The module should be removed after it is drained!")
+	}
+
+	if err := sched.RemoveModule(extra.ID()); err == nil {
+		t.Fatal("No error when removing the last downloader!")
+	}
+}
+
+// failingTransport 代表总是失败的HTTP传输。
+type failingTransport struct{}
+
+func (failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("broken downloader")
+}
+
+func TestSchedModuleHealth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		for i := 0; i < 5; i++ {
+			fmt.Fprintf(w, `<a href="/page%d">page</a>`, i)
+		}
+	}))
+	defer server.Close()
+
+	moduleArgs := genSimpleModuleArgs(1, 1, 1, t)
+	broken, _ := downloader.New("D100", &http.Client{Transport: failingTransport{}}, nil)
+	moduleArgs.Downloaders = append(moduleArgs.Downloaders, broken)
+	moduleArgs.Health = module.HealthArgs{FailureThreshold: 1, OpenTimeout: time.Hour}
+
+	requestArgs := genRequestArgs([]string{}, 1)
+	requestArgs.IgnoreRobots = true
+	sched := NewScheduler()
+	if err := sched.Init(requestArgs, genDataArgs(10, 2, 1), moduleArgs); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	defer sched.Stop()
+
+	firstHTTPReq, _ := http.NewRequest("GET", server.URL+"/", nil)
+	if err := sched.Start(firstHTTPReq); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	sched.Wait(ctx)
+
+	for _, summary := range sched.Summary().Struct().Downloaders {
+		if summary.Health == nil {
+			t.Fatalf("There should be health in the summary of downloader %q!", summary.ID)
+		}
+		expected := module.BREAKER_STATE_CLOSED
+		if summary.ID == broken.ID() {
+			expected = module.BREAKER_STATE_OPEN
+			if summary.Health.Failures != 1 {
+				t.Fatalf("Inconsistent failures: expected: %d, actual: %d", 1, summary.Health.Failures)
+			}
+		}
+		if summary.Health.State != expected {
+			t.Fatalf("Inconsistent breaker state of downloader %q: expected: %s, actual: %s",
+				summary.ID, expected, summary.Health.State)
+		}
+	}
+}
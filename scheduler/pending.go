@@ -0,0 +1,56 @@
+package scheduler
+
+import (
+	"crawler/module"
+	"sync/atomic"
+)
+
+// itemGroup 代表由同一个响应产生的条目的组。
+// 组中的所有条目都被处理之后，产生它们的请求才不再被视为待处理的请求，
+// 以便在此之前保存的检查点可以重新下载该请求，而不会丢失尚未被处理的条目。
+type itemGroup struct {
+	// req 代表产生这些条目的请求。
+	req *module.Request
+	// remaining 代表尚未被处理的条目的数量。
+	remaining int64
+}
+
+// pendingItem 代表已放入或正要放入条目缓冲池的条目。
+type pendingItem struct {
+	// item 代表条目本身。
+	item module.Item
+	// group 代表条目所属的组，为nil时代表不属于任何组。
+	group *itemGroup
+}
+
+// newItemGroup 用于为分析给定请求的响应所得的数据列表创建条目的组。
+// 若数据列表中没有条目，则请求会立即被视为已处理完毕，此时结果值为nil。
+func (sched *myScheduler) newItemGroup(req *module.Request, dataList []module.Data) *itemGroup {
+	if req == nil {
+		return nil
+	}
+
+	var n int64
+	for _, data := range dataList {
+		if item, ok := data.(module.Item); ok && item != nil {
+			n++
+		}
+	}
+	if n == 0 {
+		sched.donePendingReq(req)
+		return nil
+	}
+	return &itemGroup{req: req, remaining: n}
+}
+
+// doneItem 用于记录给定的条目已被处理。
+// 组中的最后一个条目被处理后，产生它们的请求会被移出待处理请求的字典。
+func (sched *myScheduler) doneItem(item *pendingItem) {
+	group := item.group
+	if group == nil {
+		return
+	}
+	if atomic.AddInt64(&group.remaining, -1) == 0 {
+		sched.donePendingReq(group.req)
+	}
+}
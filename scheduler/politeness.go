@@ -0,0 +1,207 @@
+package scheduler
+
+import (
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HostSummaryStruct 代表单个主机的礼貌性控制状态的摘要类型。
+type HostSummaryStruct struct {
+	Host     string `json:"host"`
+	Delay    string `json:"delay"`
+	InFlight uint32 `json:"in_flight"`
+	Waiting  uint32 `json:"waiting"`
+	Total    uint64 `json:"total"`
+}
+
+// defaultHostIdleExpiry 代表空闲主机的状态被移除之前的保留时长。
+const defaultHostIdleExpiry = 10 * time.Minute
+
+// hostState 代表单个主机的礼貌性控制状态。
+type hostState struct {
+	// delay 代表针对该主机的请求间隔，为0时使用控制器的默认值。
+	delay time.Duration
+	// inFlight 代表正在进行的请求的数量。
+	inFlight uint32
+	// waiting 代表因未获得许可而被推迟的请求的数量。
+	waiting uint32
+	// total 代表已放行的请求的总数。
+	total uint64
+	// nextTime 代表下一个请求最早可以开始的时间。
+	nextTime time.Time
+	// releaseCh 代表请求结束的通知通道，每次有请求结束时都会被关闭并重建。
+	releaseCh chan struct{}
+}
+
+// politeness 代表以主机为单位的礼貌性控制器。
+// 它会限制同一主机的请求间隔和并发请求数。
+// 空闲主机的状态会在一段时间后被移除，单独设置了请求间隔的主机除外。
+type politeness struct {
+	// delay 代表同一主机的两次请求之间的最小间隔。
+	delay time.Duration
+	// jitter 代表在最小间隔之上附加的随机时长的上限。
+	jitter time.Duration
+	// maxInFlight 代表同一主机的最大并发请求数，为0时不限制。
+	maxInFlight uint32
+	// idleExpiry 代表空闲主机的状态被移除之前的保留时长。
+	idleExpiry time.Duration
+	// hosts 代表主机与其状态的映射。
+	hosts map[string]*hostState
+	// lastSweep 代表上一次移除空闲主机的时间。
+	lastSweep time.Time
+	// lock 代表保护主机状态的互斥锁。
+	lock sync.Mutex
+}
+
+// newPoliteness 用于创建一个礼貌性控制器。
+func newPoliteness(delay time.Duration, jitter time.Duration, maxInFlight uint32) *politeness {
+	return &politeness{
+		delay:       delay,
+		jitter:      jitter,
+		maxInFlight: maxInFlight,
+		idleExpiry:  defaultHostIdleExpiry,
+		hosts:       map[string]*hostState{},
+		lastSweep:   time.Now(),
+	}
+}
+
+// hostKey 用于生成主机的键。
+func hostKey(host string) string {
+	return strings.ToLower(strings.TrimSpace(host))
+}
+
+// getState 用于获取给定主机的状态，不存在时会创建。
+// 注意！必须在互斥锁的保护下调用本方法！
+func (p *politeness) getState(host string) *hostState {
+	state, ok := p.hosts[host]
+	if !ok {
+		state = &hostState{releaseCh: make(chan struct{})}
+		p.hosts[host] = state
+	}
+	return state
+}
+
+// interval 用于计算给定主机的下一次请求间隔。
+// 注意！必须在互斥锁的保护下调用本方法！
+func (p *politeness) interval(state *hostState) time.Duration {
+	delay := state.delay
+	if delay == 0 {
+		delay = p.delay
+	}
+	if p.jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(p.jitter)))
+	}
+	return delay
+}
+
+// setHostDelay 用于单独设置某个主机的请求间隔。
+// 若给定的间隔小于默认间隔，则仍使用默认间隔。
+func (p *politeness) setHostDelay(host string, delay time.Duration) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if delay < p.delay {
+		delay = 0
+	}
+	p.getState(hostKey(host)).delay = delay
+}
+
+// tryAcquire 用于尝试在访问给定主机之前获得许可，不会阻塞。
+// 若获得许可，则第一个结果值为true，之后必须调用release方法归还。
+// 否则请求会被记为等待中，调用方应在第二个结果值代表的时长之后，
+// 或第三个结果值代表的通道被关闭之后调用doneWaiting方法并重新尝试。
+func (p *politeness) tryAcquire(host string) (bool, time.Duration, <-chan struct{}) {
+	host = hostKey(host)
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	now := time.Now()
+	p.sweep(now)
+	state := p.getState(host)
+	if p.maxInFlight > 0 && state.inFlight >= p.maxInFlight {
+		state.waiting++
+		return false, 0, state.releaseCh
+	}
+
+	if now.Before(state.nextTime) {
+		state.waiting++
+		return false, state.nextTime.Sub(now), nil
+	}
+
+	state.inFlight++
+	state.total++
+	state.nextTime = now.Add(p.interval(state))
+	return true, 0, nil
+}
+
+// doneWaiting 用于结束给定主机的一个请求的等待。
+func (p *politeness) doneWaiting(host string) {
+	host = hostKey(host)
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if state, ok := p.hosts[host]; ok && state.waiting > 0 {
+		state.waiting--
+	}
+}
+
+// sweep 用于移除空闲时间超过保留时长的主机的状态，每个保留时长内最多执行一次。
+// 注意！必须在互斥锁的保护下调用本方法！
+func (p *politeness) sweep(now time.Time) {
+	if now.Sub(p.lastSweep) < p.idleExpiry {
+		return
+	}
+
+	p.lastSweep = now
+	for host, state := range p.hosts {
+		if state.inFlight == 0 && state.waiting == 0 && state.delay == 0 &&
+			now.Sub(state.nextTime) >= p.idleExpiry {
+			delete(p.hosts, host)
+		}
+	}
+}
+
+// release 用于归还访问给定主机的许可。
+func (p *politeness) release(host string) {
+	host = hostKey(host)
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	state, ok := p.hosts[host]
+	if !ok || state.inFlight == 0 {
+		return
+	}
+
+	state.inFlight--
+	close(state.releaseCh)
+	state.releaseCh = make(chan struct{})
+}
+
+// summary 用于获取所有主机的状态摘要，并按主机名排序。
+func (p *politeness) summary() []HostSummaryStruct {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	summaries := make([]HostSummaryStruct, 0, len(p.hosts))
+	for host, state := range p.hosts {
+		delay := state.delay
+		if delay == 0 {
+			delay = p.delay
+		}
+		summaries = append(summaries, HostSummaryStruct{
+			Host:     host,
+			Delay:    delay.String(),
+			InFlight: state.inFlight,
+			Waiting:  state.waiting,
+			Total:    state.total,
+		})
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Host < summaries[j].Host
+	})
+	return summaries
+}
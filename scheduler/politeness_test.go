@@ -0,0 +1,217 @@
+package scheduler
+
+import (
+	"crawler/module"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPolitenessDelay(t *testing.T) {
+	delay := time.Millisecond * 50
+	p := newPoliteness(delay, 0, 0)
+	host := "cn.bing.com"
+	if ok, _, _ := p.tryAcquire(host); !ok {
+		t.Fatalf("Couldn't acquire an idle host %q!", host)
+	}
+	p.release(host)
+
+	ok, wait, releaseCh := p.tryAcquire(host)
+	if ok {
+		t.Fatalf("It still can acquire host %q before the delay!", host)
+	}
+	if wait <= 0 || wait > delay || releaseCh != nil {
+		t.Fatalf("Inconsistent wait: expected: (0, %s], actual: %s", delay, wait)
+	}
+	if hs := p.summary()[0]; hs.Waiting != 1 {
+		t.Fatalf("Inconsistent waiting number: expected: %d, actual: %d", 1, hs.Waiting)
+	}
+
+	// 不同的主机之间互不影响。
+	for _, host := range []string{"a.bing.com", "b.bing.com", "c.bing.com"} {
+		if ok, _, _ := p.tryAcquire(host); !ok {
+			t.Fatalf("The delay of different hosts should be independent! (host: %s)", host)
+		}
+		p.release(host)
+	}
+
+	time.Sleep(wait)
+	p.doneWaiting(host)
+	if ok, _, _ := p.tryAcquire(host); !ok {
+		t.Fatalf("Couldn't acquire host %q after the delay!", host)
+	}
+	p.release(host)
+
+	hs := p.summary()[3]
+	if hs.Host != host || hs.Total != 2 || hs.InFlight != 0 || hs.Waiting != 0 {
+		t.Fatalf("Inconsistent host summary: %#v", hs)
+	}
+}
+
+func TestPolitenessMaxInFlight(t *testing.T) {
+	max := uint32(2)
+	p := newPoliteness(0, 0, max)
+	host := "cn.bing.com"
+	for i := uint32(0); i < max; i++ {
+		if ok, _, _ := p.tryAcquire(host); !ok {
+			t.Fatalf("Couldn't acquire host %q! (in flight: %d)", host, i)
+		}
+	}
+
+	ok, wait, releaseCh := p.tryAcquire(host)
+	if ok {
+		t.Fatalf("The max in-flight number was not respected: expected: <=%d, actual: %d", max, max+1)
+	}
+	if wait != 0 || releaseCh == nil {
+		t.Fatalf("Inconsistent wait for a busy host: %s", wait)
+	}
+
+	p.release(host)
+	select {
+	case <-releaseCh:
+	default:
+		t.Fatalf("The release channel was not closed after releasing host %q!", host)
+	}
+	p.doneWaiting(host)
+	if ok, _, _ := p.tryAcquire(host); !ok {
+		t.Fatalf("Couldn't acquire host %q after releasing!", host)
+	}
+
+	hs := p.summary()[0]
+	if hs.Host != host || hs.Total != 3 || hs.InFlight != 2 || hs.Waiting != 0 {
+		t.Fatalf("Inconsistent host summary: %#v", hs)
+	}
+}
+
+func TestPolitenessSweep(t *testing.T) {
+	p := newPoliteness(0, 0, 1)
+	p.idleExpiry = time.Millisecond * 20
+	p.setHostDelay("a.bing.com", time.Minute)
+	for _, host := range []string{"b.bing.com", "c.bing.com", "d.bing.com"} {
+		if ok, _, _ := p.tryAcquire(host); !ok {
+			t.Fatalf("Couldn't acquire host %q!", host)
+		}
+	}
+	p.release("b.bing.com")
+	p.release("c.bing.com")
+	// 等待中的请求所在的主机不会被移除。
+	if ok, _, _ := p.tryAcquire("d.bing.com"); ok {
+		t.Fatalf("It still can acquire a busy host!")
+	}
+	p.release("d.bing.com")
+
+	time.Sleep(p.idleExpiry)
+	if ok, _, _ := p.tryAcquire("c.bing.com"); !ok {
+		t.Fatalf("Couldn't acquire an idle host!")
+	}
+
+	summaries := p.summary()
+	hosts := make([]string, 0, len(summaries))
+	for _, hs := range summaries {
+		hosts = append(hosts, hs.Host)
+	}
+	// 单独设置了请求间隔的主机不会被移除，被移除的主机再次被访问时会重新记录。
+	expected := []string{"a.bing.com", "c.bing.com", "d.bing.com"}
+	if len(hosts) != len(expected) {
+		t.Fatalf("Inconsistent hosts after sweeping: expected: %v, actual: %v", expected, hosts)
+	}
+	for i, host := range expected {
+		if hosts[i] != host {
+			t.Fatalf("Inconsistent hosts after sweeping: expected: %v, actual: %v", expected, hosts)
+		}
+	}
+	if summaries[1].Total != 1 {
+		t.Fatalf("Inconsistent host summary: %#v", summaries[1])
+	}
+}
+
+func TestPolitenessSetHostDelay(t *testing.T) {
+	p := newPoliteness(time.Second, 0, 0)
+	p.setHostDelay("A.bing.com", time.Minute)
+	p.setHostDelay("b.bing.com", time.Millisecond)
+
+	summaries := p.summary()
+	if len(summaries) != 2 {
+		t.Fatalf("Inconsistent host summary number: expected: %d, actual: %d", 2, len(summaries))
+	}
+
+	if summaries[0].Host != "a.bing.com" || summaries[0].Delay != time.Minute.String() {
+		t.Fatalf("Inconsistent host summary: %#v", summaries[0])
+	}
+
+	if summaries[1].Delay != time.Second.String() {
+		t.Fatalf("The host delay should not be less than the default delay: %#v", summaries[1])
+	}
+}
+
+func TestSchedPolitenessDeferred(t *testing.T) {
+	var slowCount, fastCount int32
+	slowServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&slowCount, 1)
+	}))
+	defer slowServer.Close()
+	fastServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fastCount, 1)
+	}))
+	defer fastServer.Close()
+
+	requestArgs := genRequestArgs([]string{}, 0)
+	requestArgs.IgnoreRobots = true
+	dataArgs := genDataArgs(10, 2, 1)
+	moduleArgs := genSimpleModuleArgs(1, 1, 1, t)
+	sched := NewScheduler()
+	if err := sched.Init(requestArgs, dataArgs, moduleArgs); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	defer sched.Stop()
+	mySched := sched.(*myScheduler)
+	slowURL, _ := url.Parse(slowServer.URL)
+	mySched.politeness.setHostDelay(slowURL.Host, time.Hour)
+
+	newReq := func(serverURL string, path string) *http.Request {
+		httpReq, err := http.NewRequest("GET", serverURL+path, nil)
+		if err != nil {
+			t.Fatalf("An error occurs when creating a HTTP request: %s (URL: %s)", err, serverURL+path)
+		}
+		return httpReq
+	}
+	seeds := []*http.Request{newReq(slowServer.URL, "/a"), newReq(slowServer.URL, "/b")}
+	if err := sched.StartWithSeeds(seeds); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+
+	// 唯一的下载工作协程不会因为等待请求间隔而阻塞其他主机的下载。
+	waiting := func() bool {
+		for _, hs := range mySched.politeness.summary() {
+			if hs.Host == hostKey(slowURL.Host) {
+				return hs.Waiting == 1
+			}
+		}
+		return false
+	}
+	for deadline := time.Now().Add(time.Second * 2); !waiting(); time.Sleep(time.Millisecond * 10) {
+		if time.Now().After(deadline) {
+			t.Fatalf("The second request of the slow host was not deferred!")
+		}
+	}
+	mySched.sendReq(module.NewRequest(newReq(fastServer.URL, "/a"), 0))
+	mySched.sendReq(module.NewRequest(newReq(fastServer.URL, "/b"), 0))
+	for deadline := time.Now().Add(time.Second * 2); atomic.LoadInt32(&fastCount) != 2; time.Sleep(time.Millisecond * 10) {
+		if time.Now().After(deadline) {
+			t.Fatalf("The requests of another host were blocked by the slow host! (count: %d)", atomic.LoadInt32(&fastCount))
+		}
+	}
+
+	if n := atomic.LoadInt32(&slowCount); n != 1 {
+		t.Fatalf("The host delay was not respected: expected: %d, actual: %d", 1, n)
+	}
+	// 被推迟的请求仍属于待处理的请求，其他请求在响应被分析之后才处理完毕。
+	for deadline := time.Now().Add(time.Second * 2); mySched.pendingReqMap.Len() != 1; time.Sleep(time.Millisecond * 10) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Inconsistent pending request number: expected: %d, actual: %d", 1, mySched.pendingReqMap.Len())
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package scheduler
+
+import (
+	"crawler/module"
+	"regexp"
+)
+
+// Prioritizer 代表用于计算请求优先级的函数的类型。
+// 参数parent代表请求所属的响应，首次请求的parent为nil。
+// 结果值越大，请求越先被下载。
+type Prioritizer func(req *module.Request, parent *module.Response) int
+
+// ShallowFirst 会让深度较小的请求先被下载。
+func ShallowFirst(req *module.Request, parent *module.Response) int {
+	return -int(req.Depth())
+}
+
+// BoostURL 用于生成一个为URL匹配给定模式的请求增加优先级的函数。
+// 参数next代表用于计算基础优先级的函数，可以为nil。
+func BoostURL(pattern *regexp.Regexp, boost int, next Prioritizer) Prioritizer {
+	return func(req *module.Request, parent *module.Response) int {
+		priority := 0
+		if next != nil {
+			priority = next(req, parent)
+		}
+
+		if pattern != nil && req.HTTPReq() != nil && req.HTTPReq().URL != nil &&
+			pattern.MatchString(req.HTTPReq().URL.String()) {
+			priority += boost
+		}
+		return priority
+	}
+}
+
+// prioritize 会使用优先级计算函数设置请求的优先级。
+// 若未设置优先级计算函数，则保留请求原有的优先级。
+func (sched *myScheduler) prioritize(req *module.Request, parent *module.Response) {
+	if sched.prioritizer == nil || req == nil {
+		return
+	}
+
+	req.SetPriority(sched.prioritizer(req, parent))
+}
@@ -0,0 +1,73 @@
+package scheduler
+
+import (
+	"crawler/module"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// genTestRequest 用于生成测试用的请求。
+func genTestRequest(url string, depth uint32, t *testing.T) *module.Request {
+	httpReq, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		t.Fatalf("An error occurs when creating a HTTP request: %s (url: %s)", err, url)
+	}
+	return module.NewRequest(httpReq, depth)
+}
+
+func TestPrioritizer(t *testing.T) {
+	shallow := genTestRequest("http://cn.bing.com/a", 0, t)
+	deep := genTestRequest("http://cn.bing.com/b", 3, t)
+	if ShallowFirst(shallow, nil) <= ShallowFirst(deep, nil) {
+		t.Fatal("The shallow request should have a higher priority!")
+	}
+
+	boost := BoostURL(regexp.MustCompile(`/b$`), 10, ShallowFirst)
+	if p := boost(deep, nil); p != 7 {
+		t.Fatalf("Inconsistent priority: expected: %d, actual: %d", 7, p)
+	}
+
+	if p := boost(shallow, nil); p != 0 {
+		t.Fatalf("Inconsistent priority: expected: %d, actual: %d", 0, p)
+	}
+
+	if p := BoostURL(regexp.MustCompile(`/a$`), 5, nil)(shallow, nil); p != 5 {
+		t.Fatalf("Inconsistent priority: expected: %d, actual: %d", 5, p)
+	}
+}
+
+func TestSchedPriority(t *testing.T) {
+	requestArgs := genRequestArgs([]string{}, 0)
+	requestArgs.Prioritizer = ShallowFirst
+	dataArgs := genDataArgs(10, 2, 1)
+	moduleArgs := genSimpleModuleArgs(1, 1, 1, t)
+	sched := NewScheduler()
+	if err := sched.Init(requestArgs, dataArgs, moduleArgs); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	mySched := sched.(*myScheduler)
+
+	depths := []uint32{2, 0, 3, 1}
+	for i, depth := range depths {
+		req := genTestRequest("http://cn.bing.com/"+string(rune('a'+i)), depth, t)
+		mySched.prioritize(req, nil)
+		mySched.enqueueReq(req)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for mySched.reqBufferPool.Total() < uint64(len(depths)) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	for expected := uint32(0); expected < uint32(len(depths)); expected++ {
+		datum, err := mySched.reqBufferPool.Get()
+		if err != nil {
+			t.Fatalf("Couldn't get request: %s", err)
+		}
+		if depth := datum.(*module.Request).Depth(); depth != expected {
+			t.Fatalf("Inconsistent request depth: expected: %d, actual: %d", expected, depth)
+		}
+	}
+}
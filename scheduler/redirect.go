@@ -0,0 +1,64 @@
+package scheduler
+
+import (
+	"crawler/module"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// defaultMaxRedirects 代表默认的重定向请求最多可以经过的重定向次数。
+const defaultMaxRedirects = 10
+
+// maxRedirectBodyDrain 代表关闭重定向响应之前最多读出的响应体的字节数，以便复用连接。
+const maxRedirectBodyDrain = 4 << 10
+
+// handOffRedirect 会把下载器未跟随的重定向请求交给调度器。
+// 重定向请求会和其他请求一样经过过滤和去重，重定向响应本身不会被解析。
+func (sched *myScheduler) handOffRedirect(resp *module.Response, redirectReq *module.Request) {
+	if httpResp := resp.HTTPResp(); httpResp != nil && httpResp.Body != nil {
+		io.CopyN(ioutil.Discard, httpResp.Body, maxRedirectBodyDrain)
+		httpResp.Body.Close()
+	}
+
+	if redirectReq.Redirects() > sched.maxRedirects {
+		logger.Warnf("Ignore the redirect! It has been redirected %d times, more than %d. (URL: %s)\n",
+			redirectReq.Redirects(), sched.maxRedirects, redirectReq.HTTPReq().URL)
+		sched.filterReq(redirectReq, FILTER_REASON_REDIRECTS)
+		return
+	}
+
+	logger.Infof("Hand off the redirect: %s -> %s\n",
+		resp.FinalURL(), redirectReq.HTTPReq().URL)
+	sched.prioritize(redirectReq, resp)
+	sched.sendReq(redirectReq)
+}
+
+// markRedirected 会把已被下载器跟随的重定向的最终URL记为已处理，以免它被重复下载。
+func (sched *myScheduler) markRedirected(req *module.Request, resp *module.Response) {
+	finalURL := resp.FinalURL()
+	if len(resp.FetchInfo().Redirects) == 0 || finalURL == nil {
+		return
+	}
+
+	method := http.MethodGet
+	if finalHTTPReq := resp.HTTPResp().Request; finalHTTPReq != nil && finalHTTPReq.Method != "" {
+		method = finalHTTPReq.Method
+	}
+	httpReq, err := http.NewRequest(method, finalURL.String(), nil)
+	if err != nil {
+		return
+	}
+	httpReq.Header = req.HTTPReq().Header
+	if _, err := sched.deduper.Add(sched.fingerprint(module.NewRequest(httpReq, req.Depth()))); err != nil {
+		logger.Errorf("An error occurs when recording the redirected request: %s (URL: %s)\n", err, finalURL)
+	}
+}
+
+// maxRedirectsOrDefault 用于获取实际使用的重定向请求最多可以经过的重定向次数。
+func maxRedirectsOrDefault(maxRedirects uint32) uint32 {
+	if maxRedirects == 0 {
+		return defaultMaxRedirects
+	}
+	return maxRedirects
+}
@@ -0,0 +1,214 @@
+package scheduler
+
+import (
+	"context"
+	"crawler/module"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// redirectHook 代表记录重定向请求的钩子。
+type redirectHook struct {
+	NopHook
+	reqs []*module.Request
+	lock sync.Mutex
+}
+
+func (hook *redirectHook) OnRequestScheduled(req *module.Request) {
+	if req.Source() != module.SOURCE_REDIRECT {
+		return
+	}
+	hook.lock.Lock()
+	defer hook.lock.Unlock()
+	hook.reqs = append(hook.reqs, req)
+}
+
+func TestSchedRedirect(t *testing.T) {
+	var lock sync.Mutex
+	hits := map[string]int{}
+	hit := func(r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		hits[r.Host+r.URL.Path]++
+	}
+
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit(r)
+	}))
+	defer other.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit(r)
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<a href="/moved">moved</a><a href="/away">away</a>`)
+		case "/moved":
+			http.Redirect(w, r, "/dest", http.StatusFound)
+		case "/away":
+			http.Redirect(w, r, other.URL+"/page", http.StatusFound)
+		case "/dest":
+			// 重定向的目标URL同样会被去重，不会被再次下载。
+			fmt.Fprint(w, `<a href="/dest">dest</a>`)
+		}
+	}))
+	defer server.Close()
+
+	requestArgs := genRequestArgs([]string{}, 3)
+	requestArgs.IgnoreRobots = true
+	hook := &redirectHook{}
+	sched := NewScheduler()
+	if err := sched.Init(requestArgs, genDataArgs(10, 2, 1), genSimpleModuleArgs(1, 1, 1, t), hook); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	defer sched.Stop()
+
+	firstHTTPReq, _ := http.NewRequest("GET", server.URL+"/", nil)
+	if err := sched.Start(firstHTTPReq); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	sched.Wait(ctx)
+
+	lock.Lock()
+	defer lock.Unlock()
+	serverHost := server.Listener.Addr().String()
+	otherHost := other.Listener.Addr().String()
+	expected := map[string]int{
+		serverHost + "/":      1,
+		serverHost + "/moved": 1,
+		serverHost + "/dest":  1,
+		serverHost + "/away":  1,
+		otherHost + "/page":   1,
+	}
+	for path, n := range expected {
+		if hits[path] != n {
+			t.Fatalf("Inconsistent hits of %s: expected: %d, actual: %d (hits: %v)", path, n, hits[path], hits)
+		}
+	}
+
+	hook.lock.Lock()
+	defer hook.lock.Unlock()
+	// 同一主机内的重定向也会被交给调度器。
+	referrers := map[string]string{}
+	for _, req := range hook.reqs {
+		referrers[req.Referrer()] = req.HTTPReq().URL.String()
+	}
+	if len(hook.reqs) != 2 || referrers[server.URL+"/moved"] != server.URL+"/dest" ||
+		referrers[server.URL+"/away"] != other.URL+"/page" {
+		t.Fatalf("Inconsistent redirect requests: %v", referrers)
+	}
+}
+
+func TestSchedRedirectFiltered(t *testing.T) {
+	var lock sync.Mutex
+	hits := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		hits[r.URL.Path]++
+		lock.Unlock()
+		switch r.URL.Path {
+		case "/robots.txt":
+			fmt.Fprint(w, "User-agent: *\nDisallow: /private\n")
+		case "/to-login":
+			http.Redirect(w, r, "/login", http.StatusFound)
+		case "/to-private":
+			http.Redirect(w, r, "/private", http.StatusFound)
+		case "/to-seen":
+			http.Redirect(w, r, "/to-login", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	requestArgs := genRequestArgs([]string{}, 0)
+	requestArgs.ScopeRules = []ScopeRule{{Action: SCOPE_ACTION_DENY, Path: "/login*"}}
+	hook := newRecordHook()
+	sched := NewScheduler()
+	if err := sched.Init(requestArgs, genDataArgs(10, 2, 1), genSimpleModuleArgs(1, 1, 1, t), hook); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	defer sched.Stop()
+
+	seeds := make([]*http.Request, 0, 3)
+	for _, path := range []string{"/to-login", "/to-private", "/to-seen"} {
+		seed, _ := http.NewRequest("GET", server.URL+path, nil)
+		seeds = append(seeds, seed)
+	}
+	if err := sched.StartWithSeeds(seeds); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	if err := sched.Wait(ctx); err != nil {
+		t.Fatalf("An error occurs when waiting for scheduler: %s", err)
+	}
+
+	// 同一主机内的重定向同样会经过范围规则、robots.txt和去重的检查。
+	lock.Lock()
+	defer lock.Unlock()
+	for path, n := range map[string]int{"/to-login": 1, "/to-private": 1, "/to-seen": 1, "/login": 0, "/private": 0} {
+		if hits[path] != n {
+			t.Fatalf("Inconsistent hits of %s: expected: %d, actual: %d (hits: %v)", path, n, hits[path], hits)
+		}
+	}
+	hook.lock.Lock()
+	defer hook.lock.Unlock()
+	for reason, n := range map[FilterReason]int{FILTER_REASON_SCOPE: 1, FILTER_REASON_ROBOTS: 1, FILTER_REASON_REPEATED: 1} {
+		if hook.filtered[reason] != n {
+			t.Fatalf("Inconsistent filtered count of %s: expected: %d, actual: %d", reason, n, hook.filtered[reason])
+		}
+	}
+}
+
+func TestSchedRedirectLimit(t *testing.T) {
+	var count int32
+	var servers [2]*httptest.Server
+	// 两个服务器相互重定向，每次都重定向到另一个主机上的新URL。
+	for i := range servers {
+		i := i
+		servers[i] = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&count, 1)
+			n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+			http.Redirect(w, r, servers[1-i].URL+"/"+strconv.Itoa(n+1), http.StatusFound)
+		}))
+		defer servers[i].Close()
+	}
+
+	requestArgs := genRequestArgs([]string{}, 0)
+	requestArgs.IgnoreRobots = true
+	requestArgs.MaxRedirects = 3
+	hook := newRecordHook()
+	sched := NewScheduler()
+	if err := sched.Init(requestArgs, genDataArgs(10, 2, 1), genSimpleModuleArgs(1, 1, 1, t), hook); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	defer sched.Stop()
+
+	firstHTTPReq, _ := http.NewRequest("GET", servers[0].URL+"/0", nil)
+	if err := sched.Start(firstHTTPReq); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	if err := sched.Wait(ctx); err != nil {
+		t.Fatalf("An error occurs when waiting for scheduler: %s", err)
+	}
+
+	// 首次请求和3个重定向请求会被下载，第4个重定向请求会被过滤掉。
+	if n := atomic.LoadInt32(&count); n != 4 {
+		t.Fatalf("Inconsistent download count: expected: %d, actual: %d", 4, n)
+	}
+	hook.lock.Lock()
+	defer hook.lock.Unlock()
+	if n := hook.filtered[FILTER_REASON_REDIRECTS]; n != 1 {
+		t.Fatalf("Inconsistent filtered redirect count: expected: %d, actual: %d", 1, n)
+	}
+}
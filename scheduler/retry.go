@@ -0,0 +1,275 @@
+package scheduler
+
+import (
+	"context"
+	"crawler/errors"
+	"crawler/module"
+	goerrors "errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrorClass 代表下载错误的分类。
+type ErrorClass string
+
+// 下载错误分类常量。
+const (
+	// ERROR_CLASS_TIMEOUT 代表超时错误。
+	ERROR_CLASS_TIMEOUT ErrorClass = "timeout"
+	// ERROR_CLASS_CONNECTION 代表连接被拒绝、被重置等连接错误。
+	ERROR_CLASS_CONNECTION ErrorClass = "connection"
+	// ERROR_CLASS_EOF 代表连接被意外关闭的错误。
+	ERROR_CLASS_EOF ErrorClass = "eof"
+	// ERROR_CLASS_DNS 代表域名解析错误。
+	ERROR_CLASS_DNS ErrorClass = "dns"
+	// ERROR_CLASS_OTHER 代表其他错误。
+	ERROR_CLASS_OTHER ErrorClass = "other"
+)
+
+// 重试策略的默认值。
+const (
+	// defaultRetryBackoffBase 代表默认的退避基础时长。
+	defaultRetryBackoffBase = time.Second
+	// defaultRetryBackoffCap 代表默认的退避时长上限。
+	defaultRetryBackoffCap = time.Minute
+)
+
+// defaultRetryableStatusCodes 代表默认的可重试的HTTP状态码。
+var defaultRetryableStatusCodes = []int{429, 500, 502, 503, 504}
+
+// defaultRetryableErrors 代表默认的可重试的错误分类。
+var defaultRetryableErrors = []ErrorClass{
+	ERROR_CLASS_TIMEOUT,
+	ERROR_CLASS_CONNECTION,
+	ERROR_CLASS_EOF,
+}
+
+// RetryPolicy 代表下载失败时的重试策略。
+type RetryPolicy struct {
+	// MaxAttempts 代表最多重试的次数，为0时不重试。
+	MaxAttempts uint32 `json:"max_attempts"`
+	// BackoffBase 代表首次重试前的等待时长，之后每次翻倍，为0时使用1秒。
+	BackoffBase time.Duration `json:"backoff_base"`
+	// BackoffCap 代表重试前的等待时长的上限，为0时使用1分钟。
+	BackoffCap time.Duration `json:"backoff_cap"`
+	// RetryableStatusCodes 代表可重试的HTTP状态码，为空时使用429和5xx中的常见状态码。
+	RetryableStatusCodes []int `json:"retryable_status_codes"`
+	// RetryableErrors 代表可重试的错误分类，为空时使用超时、连接和EOF错误。
+	RetryableErrors []ErrorClass `json:"retryable_errors"`
+}
+
+// Check 用于检查重试策略的有效性。
+func (policy *RetryPolicy) Check() error {
+	if policy.BackoffBase < 0 {
+		return genError(fmt.Sprintf("负的重试退避时长: %s", policy.BackoffBase))
+	}
+
+	if policy.BackoffCap < 0 {
+		return genError(fmt.Sprintf("负的重试退避时长上限: %s", policy.BackoffCap))
+	}
+
+	for _, code := range policy.RetryableStatusCodes {
+		if code < 100 || code > 599 {
+			return genError(fmt.Sprintf("无效的可重试状态码: %d", code))
+		}
+	}
+
+	for _, class := range policy.RetryableErrors {
+		switch class {
+		case ERROR_CLASS_TIMEOUT, ERROR_CLASS_CONNECTION, ERROR_CLASS_EOF, ERROR_CLASS_DNS, ERROR_CLASS_OTHER:
+		default:
+			return genError(fmt.Sprintf("未知的错误分类: %q", class))
+		}
+	}
+
+	return nil
+}
+
+// Same 用于判断两个重试策略是否相同。
+func (policy *RetryPolicy) Same(another *RetryPolicy) bool {
+	if another == nil {
+		return false
+	}
+
+	if another.MaxAttempts != policy.MaxAttempts ||
+		another.BackoffBase != policy.BackoffBase ||
+		another.BackoffCap != policy.BackoffCap {
+		return false
+	}
+
+	if len(another.RetryableStatusCodes) != len(policy.RetryableStatusCodes) {
+		return false
+	}
+
+	for i, code := range another.RetryableStatusCodes {
+		if code != policy.RetryableStatusCodes[i] {
+			return false
+		}
+	}
+
+	if len(another.RetryableErrors) != len(policy.RetryableErrors) {
+		return false
+	}
+
+	for i, class := range another.RetryableErrors {
+		if class != policy.RetryableErrors[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// retryer 代表依据重试策略做出判断的类型。
+type retryer struct {
+	// maxAttempts 代表最多重试的次数。
+	maxAttempts uint32
+	// backoffBase 代表退避基础时长。
+	backoffBase time.Duration
+	// backoffCap 代表退避时长上限。
+	backoffCap time.Duration
+	// statusCodes 代表可重试的HTTP状态码的集合。
+	statusCodes map[int]struct{}
+	// errorClasses 代表可重试的错误分类的集合。
+	errorClasses map[ErrorClass]struct{}
+}
+
+// newRetryer 用于根据重试策略创建一个重试判断器。
+func newRetryer(policy RetryPolicy) *retryer {
+	r := &retryer{
+		maxAttempts:  policy.MaxAttempts,
+		backoffBase:  policy.BackoffBase,
+		backoffCap:   policy.BackoffCap,
+		statusCodes:  map[int]struct{}{},
+		errorClasses: map[ErrorClass]struct{}{},
+	}
+
+	if r.backoffBase == 0 {
+		r.backoffBase = defaultRetryBackoffBase
+	}
+
+	if r.backoffCap == 0 {
+		r.backoffCap = defaultRetryBackoffCap
+	}
+
+	if r.backoffCap < r.backoffBase {
+		r.backoffCap = r.backoffBase
+	}
+
+	statusCodes := policy.RetryableStatusCodes
+	if len(statusCodes) == 0 {
+		statusCodes = defaultRetryableStatusCodes
+	}
+
+	for _, code := range statusCodes {
+		r.statusCodes[code] = struct{}{}
+	}
+
+	errorClasses := policy.RetryableErrors
+	if len(errorClasses) == 0 {
+		errorClasses = defaultRetryableErrors
+	}
+
+	for _, class := range errorClasses {
+		r.errorClasses[class] = struct{}{}
+	}
+
+	return r
+}
+
+// enabled 用于判断是否启用了重试。
+func (r *retryer) enabled() bool {
+	return r != nil && r.maxAttempts > 0
+}
+
+// exhausted 用于判断给定请求的重试次数是否已耗尽。
+func (r *retryer) exhausted(req *module.Request) bool {
+	return req.Attempt() >= r.maxAttempts
+}
+
+// retryableStatus 用于判断给定的HTTP状态码是否可重试。
+func (r *retryer) retryableStatus(code int) bool {
+	_, ok := r.statusCodes[code]
+	return ok
+}
+
+// retryableError 用于判断给定的下载错误是否可重试。
+func (r *retryer) retryableError(err error) bool {
+	_, ok := r.errorClasses[classifyError(err)]
+	return ok
+}
+
+// backoff 用于计算第attempt次重试之前的等待时长。
+// 等待时长以指数增长并附加随机抖动，且不会超过上限。
+func (r *retryer) backoff(attempt uint32) time.Duration {
+	d := r.backoffBase
+	for i := uint32(1); i < attempt && d < r.backoffCap; i++ {
+		d *= 2
+	}
+
+	if d > r.backoffCap {
+		d = r.backoffCap
+	}
+
+	// 在[d/2, d]的范围内随机取值，以免大量请求同时重试。
+	half := d / 2
+	if half > 0 {
+		d = half + time.Duration(rand.Int63n(int64(half)+1))
+	}
+
+	return d
+}
+
+// classifyError 用于对下载错误进行分类。
+func classifyError(err error) ErrorClass {
+	if err == nil {
+		return ERROR_CLASS_OTHER
+	}
+
+	if goerrors.Is(err, context.DeadlineExceeded) {
+		return ERROR_CLASS_TIMEOUT
+	}
+
+	var dnsErr *net.DNSError
+	if goerrors.As(err, &dnsErr) {
+		if dnsErr.IsTimeout {
+			return ERROR_CLASS_TIMEOUT
+		}
+		return ERROR_CLASS_DNS
+	}
+
+	var netErr net.Error
+	if goerrors.As(err, &netErr) && netErr.Timeout() {
+		return ERROR_CLASS_TIMEOUT
+	}
+
+	if goerrors.Is(err, syscall.ECONNREFUSED) ||
+		goerrors.Is(err, syscall.ECONNRESET) ||
+		goerrors.Is(err, syscall.ECONNABORTED) ||
+		goerrors.Is(err, syscall.EPIPE) {
+		return ERROR_CLASS_CONNECTION
+	}
+
+	if goerrors.Is(err, io.EOF) || goerrors.Is(err, io.ErrUnexpectedEOF) {
+		return ERROR_CLASS_EOF
+	}
+
+	var opErr *net.OpError
+	if goerrors.As(err, &opErr) {
+		return ERROR_CLASS_CONNECTION
+	}
+
+	return ERROR_CLASS_OTHER
+}
+
+// genRetryExhaustedError 用于生成重试次数耗尽的爬虫错误。
+func genRetryExhaustedError(req *module.Request, reason string) error {
+	errMsg := fmt.Sprintf("gave up after %d retries: %s (URL: %s)",
+		req.Attempt(), strings.TrimSpace(reason), req.HTTPReq().URL)
+	return errors.NewCrawlerError(errors.ERROR_TYPE_RETRY_EXHAUSTED, errMsg)
+}
@@ -0,0 +1,199 @@
+package scheduler
+
+import (
+	"context"
+	"crawler/errors"
+	"crawler/module"
+	goerrors "errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyCheck(t *testing.T) {
+	policy := RetryPolicy{}
+	if err := policy.Check(); err != nil {
+		t.Fatalf("An error occurs when checking the zero retry policy: %s", err)
+	}
+
+	invalidPolicies := []RetryPolicy{
+		{BackoffBase: -time.Second},
+		{BackoffCap: -time.Second},
+		{RetryableStatusCodes: []int{99}},
+		{RetryableErrors: []ErrorClass{"unknown"}},
+	}
+	for _, policy := range invalidPolicies {
+		if err := policy.Check(); err == nil {
+			t.Fatalf("No error when checking the invalid retry policy %+v!", policy)
+		}
+	}
+
+	one := RetryPolicy{MaxAttempts: 3, RetryableStatusCodes: []int{503}}
+	another := RetryPolicy{MaxAttempts: 3, RetryableStatusCodes: []int{503}}
+	if !one.Same(&another) {
+		t.Fatalf("Inconsistent retry policy sameness: expected: %v, actual: %v", true, false)
+	}
+
+	another.RetryableStatusCodes = []int{502}
+	if one.Same(&another) {
+		t.Fatalf("Inconsistent retry policy sameness: expected: %v, actual: %v", false, true)
+	}
+
+	another = one
+	another.RetryableErrors = []ErrorClass{ERROR_CLASS_DNS}
+	if one.Same(&another) {
+		t.Fatalf("Inconsistent retry policy sameness: expected: %v, actual: %v", false, true)
+	}
+}
+
+func TestRetryerBackoff(t *testing.T) {
+	r := newRetryer(RetryPolicy{
+		MaxAttempts: 10,
+		BackoffBase: time.Millisecond * 100,
+		BackoffCap:  time.Millisecond * 500,
+	})
+
+	expectedList := []time.Duration{
+		time.Millisecond * 100,
+		time.Millisecond * 200,
+		time.Millisecond * 400,
+		time.Millisecond * 500,
+		time.Millisecond * 500,
+	}
+	for i, expected := range expectedList {
+		attempt := uint32(i + 1)
+		backoff := r.backoff(attempt)
+		if backoff < expected/2 || backoff > expected {
+			t.Fatalf("Inconsistent backoff for attempt %d: expected: %s~%s, actual: %s",
+				attempt, expected/2, expected, backoff)
+		}
+	}
+
+	r = newRetryer(RetryPolicy{})
+	if r.enabled() {
+		t.Fatal("The retry should be disabled when the max attempts is 0!")
+	}
+
+	if r.backoffBase != defaultRetryBackoffBase || r.backoffCap != defaultRetryBackoffCap {
+		t.Fatalf("Inconsistent default backoff: expected: %s/%s, actual: %s/%s",
+			defaultRetryBackoffBase, defaultRetryBackoffCap, r.backoffBase, r.backoffCap)
+	}
+
+	if !r.retryableStatus(503) || r.retryableStatus(404) {
+		t.Fatal("Inconsistent default retryable status codes!")
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	cases := []struct {
+		err   error
+		class ErrorClass
+	}{
+		{context.DeadlineExceeded, ERROR_CLASS_TIMEOUT},
+		{&net.DNSError{Err: "no such host", Name: "cn.bing.com"}, ERROR_CLASS_DNS},
+		{&net.DNSError{Err: "timeout", Name: "cn.bing.com", IsTimeout: true}, ERROR_CLASS_TIMEOUT},
+		{&net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, ERROR_CLASS_CONNECTION},
+		{io.ErrUnexpectedEOF, ERROR_CLASS_EOF},
+		{goerrors.New("something wrong"), ERROR_CLASS_OTHER},
+	}
+
+	for _, c := range cases {
+		if class := classifyError(c.err); class != c.class {
+			t.Fatalf("Inconsistent error class for %q: expected: %s, actual: %s", c.err, c.class, class)
+		}
+	}
+}
+
+func TestSchedRetry(t *testing.T) {
+	var flakyCount, deadCount int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/flaky":
+			if atomic.AddInt32(&flakyCount, 1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		default:
+			atomic.AddInt32(&deadCount, 1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	requestArgs := genRequestArgs([]string{}, 0)
+	requestArgs.IgnoreRobots = true
+	requestArgs.Retry = RetryPolicy{
+		MaxAttempts: 2,
+		BackoffBase: time.Millisecond * 10,
+		BackoffCap:  time.Millisecond * 20,
+	}
+	dataArgs := genDataArgs(10, 2, 1)
+	moduleArgs := genSimpleModuleArgs(1, 1, 1, t)
+	sched := NewScheduler()
+	if err := sched.Init(requestArgs, dataArgs, moduleArgs); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	mySched := sched.(*myScheduler)
+
+	newReq := func(path string) *module.Request {
+		httpReq, err := http.NewRequest("GET", server.URL+path, nil)
+		if err != nil {
+			t.Fatalf("An error occurs when creating a HTTP request: %s (path: %s)", err, path)
+		}
+		return module.NewRequest(httpReq, 0)
+	}
+
+	nextReq := func(attempt uint32) *module.Request {
+		datum, err := mySched.reqBufferPool.Get()
+		if err != nil {
+			t.Fatalf("Couldn't get the retried request: %s", err)
+		}
+		req := datum.(*module.Request)
+		if req.Attempt() != attempt {
+			t.Fatalf("Inconsistent attempt: expected: %d, actual: %d", attempt, req.Attempt())
+		}
+		return req
+	}
+
+	// 暂时失败的请求会在重试后成功。
+	mySched.downloadOne(newReq("/flaky"))
+	mySched.downloadOne(nextReq(1))
+	datum, err := mySched.respBufferPool.Get()
+	if err != nil {
+		t.Fatalf("Couldn't get the response: %s", err)
+	}
+	if code := datum.(*module.Response).HTTPResp().StatusCode; code != http.StatusOK {
+		t.Fatalf("Inconsistent status code: expected: %d, actual: %d", http.StatusOK, code)
+	}
+	// 响应被分析之前，请求仍属于待处理的请求。
+	if mySched.pendingReqMap.Len() != 1 {
+		t.Fatalf("Inconsistent pending request number: expected: %d, actual: %d", 1, mySched.pendingReqMap.Len())
+	}
+	mySched.newItemGroup(datum.(*module.Response).Request(), nil)
+
+	// 始终失败的请求会在重试次数耗尽后产生专门的错误。
+	req := newReq("/dead")
+	mySched.downloadOne(req)
+	mySched.downloadOne(nextReq(1))
+	mySched.downloadOne(nextReq(2))
+	datum, err = mySched.errorBufferPool.Get()
+	if err != nil {
+		t.Fatalf("Couldn't get the error: %s", err)
+	}
+	crawlerError, ok := datum.(errors.CrawlerError)
+	if !ok || crawlerError.Type() != errors.ERROR_TYPE_RETRY_EXHAUSTED {
+		t.Fatalf("Inconsistent error: expected type: %s, actual: %v", errors.ERROR_TYPE_RETRY_EXHAUSTED, datum)
+	}
+
+	if n := atomic.LoadInt32(&deadCount); n != 3 {
+		t.Fatalf("Inconsistent download count: expected: %d, actual: %d", 3, n)
+	}
+
+	if mySched.pendingReqMap.Len() != 0 {
+		t.Fatalf("Inconsistent pending request number: expected: %d, actual: %d", 0, mySched.pendingReqMap.Len())
+	}
+}
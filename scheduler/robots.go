@@ -0,0 +1,325 @@
+package scheduler
+
+import (
+	"crawler/module"
+	"crawler/toolkit/robots"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultRobotsExpiry 代表robots.txt规则的默认缓存时长。
+const defaultRobotsExpiry = 24 * time.Hour
+
+// robotsErrorExpiry 代表robots.txt下载失败时的缓存时长。
+const robotsErrorExpiry = 10 * time.Minute
+
+// robotsSweepInterval 代表移除已过期的缓存条目的最小间隔。
+const robotsSweepInterval = 10 * time.Minute
+
+// maxRobotsSize 代表robots.txt内容的最大读取长度。
+const maxRobotsSize = 512 * 1024
+
+// maxRobotsRedirects 代表下载robots.txt时最多跟随的重定向次数，见RFC 9309第2.3.1.2节。
+const maxRobotsRedirects = 5
+
+// robotsEntry 代表某个站点的robots.txt规则的缓存条目。
+type robotsEntry struct {
+	// rules 代表已解析的规则，为nil时代表规则尚未就绪。
+	rules *robots.Rules
+	// expireAt 代表缓存的过期时间。
+	expireAt time.Time
+	// fetching 代表是否正在下载robots.txt。
+	fetching bool
+	// waiters 代表等待规则就绪的回调函数的列表。
+	waiters []func(rules *robots.Rules)
+}
+
+// robotsFetcher 代表用于下载和解析robots.txt的函数的类型。
+// 第二个结果值代表规则的缓存时长。
+type robotsFetcher func(robotsURL *url.URL) (*robots.Rules, time.Duration)
+
+// robotsCache 代表robots.txt规则的缓存。
+// robots.txt总是在后台下载，同一站点的并发查询只会触发一次下载。
+// 已过期且未被再次查询的缓存条目会被定期移除，以免缓存随着访问过的站点无限增长。
+type robotsCache struct {
+	// fetch 代表下载和解析robots.txt的函数。
+	fetch robotsFetcher
+	// entries 代表站点与缓存条目的映射。
+	entries map[string]*robotsEntry
+	// sweepInterval 代表移除已过期的缓存条目的最小间隔。
+	sweepInterval time.Duration
+	// lastSweep 代表上一次移除已过期的缓存条目的时间。
+	lastSweep time.Time
+	// lock 代表保护缓存条目的互斥锁。
+	lock sync.Mutex
+}
+
+// newRobotsCache 用于创建一个robots.txt规则的缓存。
+func newRobotsCache(fetch robotsFetcher) *robotsCache {
+	return &robotsCache{
+		fetch:         fetch,
+		entries:       map[string]*robotsEntry{},
+		sweepInterval: robotsSweepInterval,
+		lastSweep:     time.Now(),
+	}
+}
+
+// get 用于获取给定URL所在站点的规则，不会等待robots.txt的下载。
+// 若规则已就绪，则第二个结果值为true。已过期的规则仍会被返回，同时会在后台重新下载robots.txt。
+// 否则第二个结果值为false，此时会在后台下载robots.txt，并在规则就绪后以规则为参数调用then。
+func (cache *robotsCache) get(reqURL *url.URL, then func(rules *robots.Rules)) (*robots.Rules, bool) {
+	site := strings.ToLower(reqURL.Scheme + "://" + reqURL.Host)
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	cache.sweep(time.Now())
+	entry, ok := cache.entries[site]
+	if !ok {
+		entry = &robotsEntry{}
+		cache.entries[site] = entry
+	}
+	if entry.rules != nil {
+		if !entry.fetching && !time.Now().Before(entry.expireAt) {
+			cache.startFetch(reqURL, entry)
+		}
+		return entry.rules, true
+	}
+
+	if then != nil {
+		entry.waiters = append(entry.waiters, then)
+	}
+	if !entry.fetching {
+		cache.startFetch(reqURL, entry)
+	}
+	return nil, false
+}
+
+// sweep 用于移除已过期且不在下载中的缓存条目，每个间隔内最多执行一次。
+// 调用方必须持有缓存的锁。
+func (cache *robotsCache) sweep(now time.Time) {
+	if now.Sub(cache.lastSweep) < cache.sweepInterval {
+		return
+	}
+
+	cache.lastSweep = now
+	for site, entry := range cache.entries {
+		if entry.rules != nil && !entry.fetching && !now.Before(entry.expireAt) {
+			delete(cache.entries, site)
+		}
+	}
+}
+
+// startFetch 会在后台下载给定URL所在站点的robots.txt，并在完成后通知等待规则的调用方。
+// 调用方必须持有缓存的锁。
+func (cache *robotsCache) startFetch(reqURL *url.URL, entry *robotsEntry) {
+	entry.fetching = true
+	robotsURL := &url.URL{Scheme: reqURL.Scheme, Host: reqURL.Host, Path: "/robots.txt"}
+	go func() {
+		rules, expiry := cache.fetch(robotsURL)
+		if rules == nil {
+			rules = robots.AllowAll()
+		}
+
+		cache.lock.Lock()
+		entry.rules = rules
+		entry.expireAt = time.Now().Add(expiry)
+		entry.fetching = false
+		waiters := entry.waiters
+		entry.waiters = nil
+		cache.lock.Unlock()
+
+		for _, then := range waiters {
+			then(rules)
+		}
+	}()
+}
+
+// parkedRequests 代表正在等待robots.txt规则的请求的集合。
+// 这些请求尚未被记为已处理，保存检查点时需要单独记录。
+type parkedRequests struct {
+	// reqs 代表请求的集合。
+	reqs map[*module.Request]struct{}
+	// lock 代表互斥锁。
+	lock sync.Mutex
+}
+
+// newParkedRequests 用于创建一个等待robots.txt规则的请求的集合。
+func newParkedRequests() *parkedRequests {
+	return &parkedRequests{reqs: map[*module.Request]struct{}{}}
+}
+
+// add 用于添加请求。
+func (parked *parkedRequests) add(req *module.Request) {
+	parked.lock.Lock()
+	defer parked.lock.Unlock()
+	parked.reqs[req] = struct{}{}
+}
+
+// remove 用于移除请求。
+func (parked *parkedRequests) remove(req *module.Request) {
+	parked.lock.Lock()
+	defer parked.lock.Unlock()
+	delete(parked.reqs, req)
+}
+
+// list 用于获取所有请求。
+func (parked *parkedRequests) list() []*module.Request {
+	parked.lock.Lock()
+	defer parked.lock.Unlock()
+	reqs := make([]*module.Request, 0, len(parked.reqs))
+	for req := range parked.reqs {
+		reqs = append(reqs, req)
+	}
+	return reqs
+}
+
+// fetchRobots 会通过已注册的下载器下载并解析robots.txt。
+// 下载失败或服务端出错时允许访问所有路径，并在较短的时间后重试。
+func (sched *myScheduler) fetchRobots(robotsURL *url.URL) (*robots.Rules, time.Duration) {
+	errorExpiry := robotsErrorExpiry
+	if sched.robotsExpiry < errorExpiry {
+		errorExpiry = sched.robotsExpiry
+	}
+
+	m, err := sched.acquireModule(module.TYPE_DOWNLOADER, robotsURL.Host)
+	if err != nil || m == nil {
+		logger.Warnf("Couldn't get a downloader for robots.txt: %s (URL: %s)", err, robotsURL)
+		return robots.AllowAll(), errorExpiry
+	}
+	defer sched.releaseModule(m)
+
+	downloader, ok := m.(module.Downloader)
+	if !ok {
+		logger.Warnf("Incorrect downloader type: %T (MID: %s)", m, m.ID())
+		return robots.AllowAll(), errorExpiry
+	}
+
+	httpReq, err := http.NewRequest(http.MethodGet, robotsURL.String(), nil)
+	if err != nil {
+		logger.Warnf("Couldn't create request for robots.txt: %s (URL: %s)", err, robotsURL)
+		return robots.AllowAll(), errorExpiry
+	}
+	if sched.robotsUserAgent != "*" {
+		httpReq.Header.Set("User-Agent", sched.robotsUserAgent)
+	}
+
+	logger.Infof("Fetch robots.txt... (URL: %s)", robotsURL)
+	req := module.NewRequest(httpReq, 0)
+	var httpResp *http.Response
+	for {
+		resp, err := downloader.Download(req)
+		if err != nil || resp == nil || resp.HTTPResp() == nil {
+			logger.Warnf("Couldn't fetch robots.txt: %v (URL: %s)", err, robotsURL)
+			return robots.AllowAll(), errorExpiry
+		}
+		httpResp = resp.HTTPResp()
+		// 下载器未跟随的重定向（如跳转到带www的主机）同样需要跟随，否则会误以为没有robots.txt。
+		req = resp.RedirectRequest()
+		if req == nil {
+			break
+		}
+		if httpResp.Body != nil {
+			httpResp.Body.Close()
+		}
+		if req.Redirects() > maxRobotsRedirects {
+			logger.Warnf("Too many redirects for robots.txt (URL: %s)", robotsURL)
+			return robots.AllowAll(), errorExpiry
+		}
+	}
+	if httpResp.Body != nil {
+		defer httpResp.Body.Close()
+	}
+
+	switch {
+	case httpResp.StatusCode >= 200 && httpResp.StatusCode < 300:
+	case httpResp.StatusCode >= 400 && httpResp.StatusCode < 500:
+		// robots.txt不存在或不可访问时允许访问所有路径。
+		return robots.AllowAll(), sched.robotsExpiry
+	default:
+		logger.Warnf("Unexpected status code %d for robots.txt (URL: %s)", httpResp.StatusCode, robotsURL)
+		return robots.AllowAll(), errorExpiry
+	}
+
+	var body io.Reader = http.NoBody
+	if httpResp.Body != nil {
+		body = io.LimitReader(httpResp.Body, maxRobotsSize)
+	}
+
+	rules, err := robots.Parse(body, sched.robotsUserAgent)
+	if err != nil {
+		logger.Warnf("Couldn't parse robots.txt: %s (URL: %s)", err, robotsURL)
+		return robots.AllowAll(), errorExpiry
+	}
+
+	if delay := rules.CrawlDelay(); delay > 0 {
+		sched.politeness.setHostDelay(robotsURL.Host, delay)
+	}
+
+	return rules, sched.robotsExpiry
+}
+
+// checkRobots 用于在robots.txt规则就绪后检查并发送请求。
+// 请求在检查期间会被记为等待中的请求和进行中的工作，
+// 规则尚未就绪时会在后台下载robots.txt，以免阻塞调用方所在的工作协程。
+// 若请求已被发送或正在等待规则，则结果值为true。
+func (sched *myScheduler) checkRobots(req *module.Request, fp string, pd string) bool {
+	sched.parkedReqs.add(req)
+	sched.inFlight.incr()
+	rules, ok := sched.robotsCache.get(req.HTTPReq().URL, func(rules *robots.Rules) {
+		sched.admitParkedReq(req, fp, pd, rules)
+	})
+	if !ok {
+		logger.Infof("Park the request until robots.txt is fetched. (URL: %s)\n", req.HTTPReq().URL)
+		return true
+	}
+	return sched.admitParkedReq(req, fp, pd, rules)
+}
+
+// admitParkedReq 会根据robots.txt规则过滤等待中的请求，并发送通过检查的请求。
+func (sched *myScheduler) admitParkedReq(req *module.Request, fp string, pd string, rules *robots.Rules) bool {
+	// 先发送请求再将其移出等待中的请求，以免保存检查点时遗漏它。
+	defer sched.inFlight.decr()
+	defer sched.parkedReqs.remove(req)
+
+	if sched.canceled() {
+		return sched.filterReq(req, FILTER_REASON_STOPPED)
+	}
+
+	reqURL := req.HTTPReq().URL
+	if !rules.Allowed(reqURL) {
+		logger.Warnf("Ignore the request! It is disallowed by robots.txt. (URL: %s)\n", reqURL)
+		return sched.filterReq(req, FILTER_REASON_ROBOTS)
+	}
+	return sched.admitReq(req, fp, pd)
+}
+
+// robotsUserAgentOrDefault 用于获取实际使用的robots.txt user-agent。
+func robotsUserAgentOrDefault(userAgent string) string {
+	userAgent = strings.TrimSpace(userAgent)
+	if userAgent == "" {
+		return "*"
+	}
+	return userAgent
+}
+
+// robotsExpiryOrDefault 用于获取实际使用的robots.txt规则缓存时长。
+func robotsExpiryOrDefault(expiry time.Duration) time.Duration {
+	if expiry <= 0 {
+		return defaultRobotsExpiry
+	}
+	return expiry
+}
+
+// robotsDesc 用于生成robots.txt检查设置的描述。
+func robotsDesc(requestArgs RequestArgs) string {
+	if requestArgs.IgnoreRobots {
+		return "ignored"
+	}
+	return fmt.Sprintf("user-agent: %s, expiry: %s",
+		robotsUserAgentOrDefault(requestArgs.RobotsUserAgent), robotsExpiryOrDefault(requestArgs.RobotsExpiry))
+}
@@ -0,0 +1,317 @@
+package scheduler
+
+import (
+	"context"
+	"crawler/module"
+	"crawler/toolkit/robots"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// getRules 用于从缓存中获取规则，规则尚未就绪时会等待下载完成。
+func getRules(cache *robotsCache, reqURL *url.URL) *robots.Rules {
+	ch := make(chan *robots.Rules, 1)
+	if rules, ok := cache.get(reqURL, func(rules *robots.Rules) { ch <- rules }); ok {
+		return rules
+	}
+	return <-ch
+}
+
+// waitForParkedReqs 用于等待所有正在等待robots.txt规则的请求被处理，最多等待1秒。
+func waitForParkedReqs(sched *myScheduler) {
+	for i := 0; i < 100 && len(sched.parkedReqs.list()) > 0; i++ {
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+func TestRobotsCache(t *testing.T) {
+	var count int32
+	cache := newRobotsCache(func(robotsURL *url.URL) (*robots.Rules, time.Duration) {
+		atomic.AddInt32(&count, 1)
+		if robotsURL.Path != "/robots.txt" {
+			t.Errorf("Inconsistent robots.txt path: expected: %s, actual: %s", "/robots.txt", robotsURL.Path)
+		}
+		time.Sleep(time.Millisecond * 50)
+		rules, _ := robots.Parse(strings.NewReader("User-agent: *\nDisallow: /private"), "*")
+		return rules, time.Hour
+	})
+
+	reqURL, _ := url.Parse("http://cn.bing.com/search?q=golang")
+	// 规则尚未就绪时不会阻塞。
+	if rules, ok := cache.get(reqURL, nil); ok || rules != nil {
+		t.Fatalf("The rules should not be ready before fetching! (URL: %s)", reqURL)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if !getRules(cache, reqURL).Allowed(reqURL) {
+				t.Errorf("The URL should be allowed! (URL: %s)", reqURL)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&count); n != 1 {
+		t.Fatalf("Inconsistent fetch count: expected: %d, actual: %d", 1, n)
+	}
+
+	privateURL, _ := url.Parse("http://cn.bing.com/private/a")
+	rules, ok := cache.get(privateURL, nil)
+	if !ok || rules.Allowed(privateURL) {
+		t.Fatalf("The URL should be disallowed! (URL: %s)", privateURL)
+	}
+
+	// 不同的站点会分别下载robots.txt。
+	otherURL, _ := url.Parse("https://cn.bing.com/private/a")
+	getRules(cache, otherURL)
+	if n := atomic.LoadInt32(&count); n != 2 {
+		t.Fatalf("Inconsistent fetch count: expected: %d, actual: %d", 2, n)
+	}
+}
+
+func TestRobotsCacheExpiry(t *testing.T) {
+	var count int32
+	cache := newRobotsCache(func(robotsURL *url.URL) (*robots.Rules, time.Duration) {
+		atomic.AddInt32(&count, 1)
+		return nil, time.Millisecond * 50
+	})
+
+	reqURL, _ := url.Parse("http://cn.bing.com/")
+	if !getRules(cache, reqURL).Allowed(reqURL) {
+		t.Fatalf("The URL should be allowed when the rules are nil! (URL: %s)", reqURL)
+	}
+	getRules(cache, reqURL)
+	if n := atomic.LoadInt32(&count); n != 1 {
+		t.Fatalf("Inconsistent fetch count: expected: %d, actual: %d", 1, n)
+	}
+
+	// 过期的规则仍会被返回，同时会在后台重新下载。
+	time.Sleep(time.Millisecond * 100)
+	if _, ok := cache.get(reqURL, nil); !ok {
+		t.Fatalf("The expired rules should still be returned! (URL: %s)", reqURL)
+	}
+	for i := 0; i < 100 && atomic.LoadInt32(&count) < 2; i++ {
+		time.Sleep(time.Millisecond * 10)
+	}
+	if n := atomic.LoadInt32(&count); n != 2 {
+		t.Fatalf("Inconsistent fetch count after expiry: expected: %d, actual: %d", 2, n)
+	}
+}
+
+func TestRobotsCacheSweep(t *testing.T) {
+	cache := newRobotsCache(func(robotsURL *url.URL) (*robots.Rules, time.Duration) {
+		if robotsURL.Host == "expired.bing.com" {
+			return nil, time.Millisecond * 10
+		}
+		return nil, time.Hour
+	})
+	cache.sweepInterval = time.Millisecond * 20
+
+	expiredURL, _ := url.Parse("http://expired.bing.com/")
+	freshURL, _ := url.Parse("http://fresh.bing.com/")
+	getRules(cache, expiredURL)
+	getRules(cache, freshURL)
+
+	// 已过期的缓存条目会在查询其他站点时被移除。
+	time.Sleep(cache.sweepInterval)
+	getRules(cache, freshURL)
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	if _, ok := cache.entries["http://expired.bing.com"]; ok || len(cache.entries) != 1 {
+		t.Fatalf("The expired entry was not removed! (entries: %d)", len(cache.entries))
+	}
+}
+
+func TestSchedRobots(t *testing.T) {
+	var robotsCount int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			atomic.AddInt32(&robotsCount, 1)
+			fmt.Fprint(w, "User-agent: *\nDisallow: /private\nCrawl-delay: 2\n")
+		default:
+			w.Header().Set("Content-Type", "text/html")
+		}
+	}))
+	defer server.Close()
+
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("An error occurs when parsing the server URL: %s (URL: %s)", err, server.URL)
+	}
+	host := serverURL.Host
+	pd, _ := getPrimaryDomain(host)
+	requestArgs := genRequestArgs([]string{pd}, 0)
+	dataArgs := genDataArgs(10, 2, 1)
+	moduleArgs := genSimpleModuleArgs(3, 2, 1, t)
+	sched := NewScheduler()
+	if err := sched.Init(requestArgs, dataArgs, moduleArgs); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	mySched := sched.(*myScheduler)
+
+	sendReq := func(path string) bool {
+		httpReq, err := http.NewRequest("GET", server.URL+path, nil)
+		if err != nil {
+			t.Fatalf("An error occurs when creating a HTTP request: %s (path: %s)", err, path)
+		}
+		return mySched.sendReq(module.NewRequest(httpReq, 0))
+	}
+
+	if !sendReq("/public") {
+		t.Fatalf("Couldn't send the request allowed by robots.txt!")
+	}
+	// 第一个请求会等待robots.txt在后台下载完成。
+	waitForParkedReqs(mySched)
+	if n := mySched.pendingReqMap.Len(); n != 1 {
+		t.Fatalf("Inconsistent pending request number: expected: %d, actual: %d", 1, n)
+	}
+
+	if sendReq("/private/a") {
+		t.Fatalf("It still can send the request disallowed by robots.txt!")
+	}
+
+	if n := atomic.LoadInt32(&robotsCount); n != 1 {
+		t.Fatalf("Inconsistent robots.txt fetch count: expected: %d, actual: %d", 1, n)
+	}
+
+	if state, ok := mySched.politeness.hosts[hostKey(host)]; !ok || state.delay != time.Second*2 {
+		t.Fatalf("The crawl delay in robots.txt was not applied! (host: %s)", host)
+	}
+
+	// 测试忽略robots.txt的情况。
+	requestArgs.IgnoreRobots = true
+	if err := sched.Init(requestArgs, dataArgs, moduleArgs); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	if !sendReq("/private/a") {
+		t.Fatalf("Couldn't send the request when robots.txt is ignored!")
+	}
+}
+
+func TestSchedRobotsRedirect(t *testing.T) {
+	// 带www的主机上的robots.txt。
+	www := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "User-agent: *\nDisallow: /private\n")
+	}))
+	defer www.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, www.URL+r.URL.Path, http.StatusMovedPermanently)
+	}))
+	defer server.Close()
+
+	requestArgs := genRequestArgs([]string{}, 0)
+	sched := NewScheduler()
+	if err := sched.Init(requestArgs, genDataArgs(10, 2, 1), genSimpleModuleArgs(1, 1, 1, t)); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	mySched := sched.(*myScheduler)
+
+	privateURL, _ := url.Parse(server.URL + "/private/a")
+	rules := getRules(mySched.robotsCache, privateURL)
+	if rules.Allowed(privateURL) {
+		t.Fatalf("The redirected robots.txt was not applied! (URL: %s)", privateURL)
+	}
+}
+
+func TestSchedRobotsParked(t *testing.T) {
+	var count int32
+	block := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			<-block
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		atomic.AddInt32(&count, 1)
+	}))
+	defer server.Close()
+
+	requestArgs := genRequestArgs([]string{}, 0)
+	dataArgs := genDataArgs(10, 2, 1)
+	moduleArgs := genSimpleModuleArgs(1, 1, 1, t)
+	sched := NewScheduler()
+	if err := sched.Init(requestArgs, dataArgs, moduleArgs); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+
+	firstHTTPReq, err := http.NewRequest("GET", server.URL+"/", nil)
+	if err != nil {
+		t.Fatalf("An error occurs when creating a HTTP request: %s (url: %s)", err, server.URL)
+	}
+	// 下载robots.txt时不会阻塞种子请求的发送。
+	begin := time.Now()
+	if err = sched.Start(firstHTTPReq); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	if elapsed := time.Since(begin); elapsed > time.Second {
+		t.Fatalf("Starting the scheduler was blocked by robots.txt! (elapsed: %s)", elapsed)
+	}
+	if sched.Idle() {
+		t.Fatal("The scheduler should not be idle while the seed is parked!")
+	}
+
+	// 正在等待robots.txt规则的请求会被保存到检查点中。
+	if err = sched.Pause(); err != nil {
+		t.Fatalf("An error occurs when pausing scheduler: %s", err)
+	}
+	filePath := filepath.Join(t.TempDir(), "crawler.checkpoint")
+	if err = sched.Checkpoint(filePath); err != nil {
+		t.Fatalf("An error occurs when saving checkpoint: %s", err)
+	}
+	sched.Stop()
+	close(block)
+
+	cp, err := LoadCheckpoint(filePath)
+	if err != nil {
+		t.Fatalf("An error occurs when loading checkpoint: %s", err)
+	}
+	if n := countFingerprints(t, cp); len(cp.Requests) != 1 || n != 0 {
+		t.Fatalf("Inconsistent checkpoint: requests: %d, fingerprints: %d", len(cp.Requests), n)
+	}
+
+	sched = NewScheduler()
+	if err = sched.Init(requestArgs, dataArgs, moduleArgs); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	if err = sched.StartFrom(filePath); err != nil {
+		t.Fatalf("An error occurs when starting scheduler from checkpoint: %s", err)
+	}
+	defer sched.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	if err = sched.Wait(ctx); err != nil {
+		t.Fatalf("An error occurs when waiting for scheduler: %s", err)
+	}
+	if n := atomic.LoadInt32(&count); n != 1 {
+		t.Fatalf("Inconsistent download count: expected: %d, actual: %d", 1, n)
+	}
+}
+
+func TestRobotsArgsDefault(t *testing.T) {
+	if ua := robotsUserAgentOrDefault(" "); ua != "*" {
+		t.Fatalf("Inconsistent default user-agent: expected: %q, actual: %q", "*", ua)
+	}
+
+	if expiry := robotsExpiryOrDefault(0); expiry != defaultRobotsExpiry {
+		t.Fatalf("Inconsistent default expiry: expected: %s, actual: %s", defaultRobotsExpiry, expiry)
+	}
+
+	requestArgs := genRequestArgs([]string{}, 0)
+	requestArgs.RobotsExpiry = -time.Second
+	if err := requestArgs.Check(); err == nil {
+		t.Fatal("No error when checking request arguments with negative robots expiry!")
+	}
+}
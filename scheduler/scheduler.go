@@ -6,11 +6,13 @@ import (
 	log "crawler/logger"
 	"crawler/module"
 	"crawler/toolkit/buffer"
+	"crawler/toolkit/dedupe"
 	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 	"sync"
+	"time"
 )
 
 // logger 代表日志记录器
@@ -19,15 +21,35 @@ var logger = log.DLogger()
 // Scheduler 代表调度器的接口类型
 type Scheduler interface {
 	// Init 用于初始化调度器, 参数requestArgs代表请求相关的参数, 参数dataArgs代表数据相关的参数, 参数moduleArgs代表组件相关的参数
-	Init(requestArgs RequestArgs, dataArgs DataArgs, moduleArgs ModuleArgs) (err error)
+	// 参数hooks代表用于观察爬取流程的钩子，再次初始化时会替换之前注册的钩子
+	Init(requestArgs RequestArgs, dataArgs DataArgs, moduleArgs ModuleArgs, hooks ...Hook) (err error)
 
 	// Start 用于启动调度器并执行爬取流程
 	// 参数firstHTTPReq即代表首次请求。调度器会以此为起始点开始执行爬取流程
 	Start(firstHTTPReq *http.Request) (err error)
+	// StartWithSeeds 用于以多个种子请求启动调度器
+	// 每个种子请求的深度都为0
+	// 若未设置忽略种子域名，则每个种子请求的主域名都会被添加到可接受的主域名的字典
+	StartWithSeeds(seeds []*http.Request) (err error)
 
 	// Stop 用于停止调度器的运行
 	// 所有处理模块执行的流程都会被中止
 	Stop() (err error)
+	// Close 用于释放调度器占用的资源，如磁盘去重器的工作目录
+	// 只有在调度器未启动或已停止时才能调用，之后不能再保存检查点，除非重新初始化
+	Close() (err error)
+	// StopReason 用于获取调度器停止的原因
+	// 爬取预算用尽时调度器会自动停止，此时的原因即为用尽的预算
+	// 若结果值为空字符串，则说明调度器未被停止
+	StopReason() StopReason
+
+	// Pause 用于暂停调度器的运行
+	// 暂停后各个处理模块不再从缓冲池中取出数据，但缓冲池中已有的请求、响应和条目都会被保留
+	Pause() (err error)
+
+	// Resume 用于恢复已暂停的调度器
+	// 恢复后各个处理模块会继续处理缓冲池中的数据
+	Resume() (err error)
 
 	// Status 用于获取调度器的状态
 	Status() Status
@@ -39,19 +61,49 @@ type Scheduler interface {
 
 	// Idle 用于判断所有处理模块是否都处于空闲状态
 	Idle() bool
+	// Done 用于获得完成通知通道
+	// 当所有缓冲池都已为空且没有任何组件或待放入的数据处于活动状态时，或者调度器被停止时，该通道会被关闭
+	// 调度器在完成或停止后再次启动时会使用新的通道，之前获得的通道不会反映新一次爬取的进度，需要重新获取
+	// 调度器尚未初始化时返回已关闭的通道
+	Done() <-chan struct{}
+	// Wait 用于等待爬取完成
+	// 若调度器尚未初始化，或在爬取完成之前被停止，则返回非nil的错误值
+	// 若参数ctx先被取消，则返回ctx的错误值
+	Wait(ctx context.Context) error
 
 	// Summary 用于获取摘要实例
 	Summary() SchedSummary
+
+	// Checkpoint 用于把当前的爬取进度保存到给定的文件中
+	// 进度包括待处理的请求、已处理的URL以及摘要信息
+	// 为了保证进度的一致性，只有在调度器已暂停或已停止时才能保存
+	Checkpoint(filePath string) (err error)
+
+	// StartFrom 用于从给定的检查点文件恢复爬取进度并启动调度器
+	// 检查点中的待处理请求会被重新放入请求缓冲池
+	StartFrom(filePath string) (err error)
+
+	// AddModule 用于在调度器运行时添加组件
+	// 只有在调度器已初始化、已启动或已暂停时才能添加
+	AddModule(m module.Module) (err error)
+	// RemoveModule 用于在调度器运行时移除组件
+	// 被移除的组件不会再被分配新的工作，本方法会等待它进行中的工作完成后才返回
+	// 同类型的最后一个组件不能被移除
+	RemoveModule(mid module.MID) (err error)
 }
 
 // myScheduler 代表调度器的实现类型。
 type myScheduler struct {
 	// maxDepth 代表爬取的最大深度。首次请求的深度为0。
 	maxDepth uint32
+	// maxRedirects 代表重定向请求最多可以经过的重定向次数。
+	maxRedirects uint32
 	// acceptedDomainMap 代表可以接受的URL的主域名的字典。
 	acceptedDomainMap cmap.ConcurrentMap
 	// registrar 代表组件注册器。
 	registrar module.Registrar
+	// leases 代表组件的租用记录。
+	leases moduleLeases
 	// reqBufferPool 代表请求的缓冲池。
 	reqBufferPool buffer.Pool
 	// respBufferPool 代表响应的缓冲池。
@@ -60,8 +112,45 @@ type myScheduler struct {
 	itemBufferPool buffer.Pool
 	// errorBufferPool 代表错误的缓冲池。
 	errorBufferPool buffer.Pool
-	// urlMap 代表已处理的URL的字典。
-	urlMap cmap.ConcurrentMap
+	// deduper 代表已处理的请求的去重器，键为请求的指纹。
+	deduper dedupe.Deduper
+	// pendingReqMap 代表尚未处理完毕的请求的字典。
+	// 请求从放入请求缓冲池开始，直到其响应被分析且由此产生的条目都被处理为止，都会被视为待处理的请求。
+	pendingReqMap cmap.ConcurrentMap
+	// inFlight 代表进行中的工作的计数器。
+	inFlight *inFlight
+	// hooks 代表已注册的钩子的列表。
+	hooks hookList
+	// canonicalizer 代表用于判断请求是否重复的URL规范化器。
+	canonicalizer *canonicalizer
+	// fingerprintHeaders 代表需要计入请求指纹的请求头的名称。
+	fingerprintHeaders []string
+	// scope 代表URL范围规则引擎。
+	scope *scope
+	// politeness 代表以主机为单位的礼貌性控制器。
+	politeness *politeness
+	// robotsCache 代表robots.txt规则的缓存。为nil时不检查robots.txt。
+	robotsCache *robotsCache
+	// parkedReqs 代表正在等待robots.txt规则的请求的集合。
+	parkedReqs *parkedRequests
+	// robotsUserAgent 代表匹配robots.txt规则时使用的user-agent。
+	robotsUserAgent string
+	// robotsExpiry 代表robots.txt规则的缓存时长。
+	robotsExpiry time.Duration
+	// retryer 代表下载失败时的重试判断器。
+	retryer *retryer
+	// prioritizer 代表用于计算请求优先级的函数。
+	prioritizer Prioritizer
+	// acceptSeedDomains 代表是否把种子请求的主域名添加到可接受的主域名的字典。
+	acceptSeedDomains bool
+	// budget 代表爬取预算的计量器。
+	budget *budget
+	// latency 代表下载延迟的记录器。
+	latency *latencyRecorder
+	// stopReason 代表调度器停止的原因。
+	stopReason StopReason
+	// stopReasonLock 代表专用于停止原因的读写锁。
+	stopReasonLock sync.RWMutex
 	// ctx 代表上下文，用于感知调度器的停止。
 	ctx context.Context
 	// cancelFunc 代表取消函数，用于停止调度器。
@@ -72,6 +161,16 @@ type myScheduler struct {
 	statusLock sync.RWMutex
 	// summary 代表摘要信息。
 	summary SchedSummary
+	// downloadWorkerNumber 代表下载工作协程的数量。
+	downloadWorkerNumber int
+	// analyzeWorkerNumber 代表分析工作协程的数量。
+	analyzeWorkerNumber int
+	// pickWorkerNumber 代表条目处理工作协程的数量。
+	pickWorkerNumber int
+	// resumeCh 代表恢复通知通道。仅在暂停期间不为nil，恢复时会被关闭。
+	resumeCh chan struct{}
+	// pauseLock 代表专用于暂停与恢复的读写锁。
+	pauseLock sync.RWMutex
 }
 
 // NewScheduler 会创建一个调度器实例。
@@ -79,7 +178,7 @@ func NewScheduler() Scheduler {
 	return &myScheduler{}
 }
 
-func (sched *myScheduler) Init(requestArgs RequestArgs, dataArgs DataArgs, moduleArgs ModuleArgs) (err error) {
+func (sched *myScheduler) Init(requestArgs RequestArgs, dataArgs DataArgs, moduleArgs ModuleArgs, hooks ...Hook) (err error) {
 	// 检查状态。
 	logger.Info("检查初始化状态...")
 
@@ -90,15 +189,18 @@ func (sched *myScheduler) Init(requestArgs RequestArgs, dataArgs DataArgs, modul
 	}
 
 	defer func() {
-		sched.statusLock.Lock()
-		if err != nil {
-			sched.status = oldStatus
-		} else {
-			sched.status = SCHED_STATUS_INITIALIZED
-		}
-		sched.statusLock.Unlock()
+		sched.finishStatus(oldStatus, SCHED_STATUS_INITIALIZED, err)
 	}()
 
+	// 注册钩子。
+	sched.hooks = nil
+	for _, hook := range hooks {
+		if hook != nil {
+			sched.hooks = append(sched.hooks, hook)
+		}
+	}
+	logger.Infof("-- Hooks: %d", len(sched.hooks))
+
 	// 检查参数。
 	logger.Info("检查请求参数...")
 	if err = requestArgs.Check(); err != nil {
@@ -120,25 +222,84 @@ func (sched *myScheduler) Init(requestArgs RequestArgs, dataArgs DataArgs, modul
 	logger.Info("模块参数是有效的.")
 	// 初始化内部字段。
 	logger.Info("初始化调度程序的字段...")
-	if sched.registrar == nil {
-		sched.registrar = module.NewRegistrar()
-	} else {
-		sched.registrar.Clear()
+	sched.registrar, err = module.NewRegistrarWithArgs(module.RegistrarArgs{
+		Health:    moduleArgs.Health,
+		Selectors: moduleArgs.Selectors,
+	})
+	if err != nil {
+		return genErrorByError(err)
 	}
 
 	sched.maxDepth = requestArgs.MaxDepth
 	logger.Infof("-- Max depth: %d", sched.maxDepth)
+	sched.maxRedirects = maxRedirectsOrDefault(requestArgs.MaxRedirects)
+	logger.Infof("-- Max redirects: %d", sched.maxRedirects)
 
 	sched.acceptedDomainMap, _ = cmap.NewConcurrentMap(1, nil)
 	for _, domain := range requestArgs.AcceptedDomains {
-		sched.acceptedDomainMap.Put(domain, struct{}{})
+		// 主域名以规范形式存储，以便与getPrimaryDomain的结果比较。
+		normalized, err := normalizeDomain(domain)
+		if err != nil {
+			logger.Warnf("Couldn't normalize accepted domain %q: %s", domain, err)
+			normalized = domain
+		}
+		sched.acceptedDomainMap.Put(normalized, struct{}{})
 	}
 
 	logger.Infof("--接受的 domains: %v", requestArgs.AcceptedDomains)
 
-	sched.urlMap, _ = cmap.NewConcurrentMap(16, nil)
-	logger.Infof("-- URL map: length: %d, concurrency: %d", sched.urlMap.Len(), sched.urlMap.Concurrency())
+	sched.canonicalizer, err = newCanonicalizer(requestArgs.Canonical)
+	if err != nil {
+		return
+	}
+
+	sched.fingerprintHeaders = requestArgs.FingerprintHeaders
+	sched.budget = newBudget(requestArgs.Budget, sched.onBudgetExhausted)
+	sched.latency = newLatencyRecorder()
+	logger.Infof("-- Budget: max pages: %d, max bytes: %d, max duration: %s, max pages per domain: %d",
+		requestArgs.Budget.MaxPages, requestArgs.Budget.MaxBytes,
+		requestArgs.Budget.MaxDuration, requestArgs.Budget.MaxPagesPerDomain)
+
+	sched.scope, err = newScope(requestArgs.ScopeRules)
+	if err != nil {
+		return
+	}
+	logger.Infof("-- Scope rules: %d", len(requestArgs.ScopeRules))
+
+	if sched.deduper != nil && sched.deduper != requestArgs.Dedupe.Deduper {
+		if err := sched.deduper.Close(); err != nil {
+			logger.Warnf("An error occurs when closing the previous deduper: %s", err)
+		}
+	}
+	sched.deduper, err = newDeduper(requestArgs.Dedupe)
+	if err != nil {
+		return
+	}
+	logger.Infof("-- Deduper: %s", sched.deduper.Summary().Kind)
+	sched.pendingReqMap, _ = cmap.NewConcurrentMap(16, nil)
+	sched.inFlight = newInFlight()
+	sched.politeness = newPoliteness(requestArgs.HostDelay, requestArgs.HostDelayJitter, requestArgs.HostMaxInFlight)
+	logger.Infof("-- Politeness: host delay: %s, jitter: %s, max in flight: %d",
+		requestArgs.HostDelay, requestArgs.HostDelayJitter, requestArgs.HostMaxInFlight)
+	sched.robotsUserAgent = robotsUserAgentOrDefault(requestArgs.RobotsUserAgent)
+	sched.robotsExpiry = robotsExpiryOrDefault(requestArgs.RobotsExpiry)
+	sched.robotsCache = nil
+	sched.parkedReqs = newParkedRequests()
+	if !requestArgs.IgnoreRobots {
+		sched.robotsCache = newRobotsCache(sched.fetchRobots)
+	}
+	logger.Infof("-- Robots: %s", robotsDesc(requestArgs))
+	sched.retryer = newRetryer(requestArgs.Retry)
+	sched.prioritizer = requestArgs.Prioritizer
+	sched.acceptSeedDomains = !requestArgs.IgnoreSeedDomains
+	logger.Infof("-- Retry: max attempts: %d, backoff: %s~%s",
+		sched.retryer.maxAttempts, sched.retryer.backoffBase, sched.retryer.backoffCap)
 	sched.initBufferPool(dataArgs)
+	sched.downloadWorkerNumber = workerNumber(dataArgs.DownloadWorkerNumber)
+	sched.analyzeWorkerNumber = workerNumber(dataArgs.AnalyzeWorkerNumber)
+	sched.pickWorkerNumber = workerNumber(dataArgs.PickWorkerNumber)
+	logger.Infof("-- Worker number: download: %d, analyze: %d, pick: %d",
+		sched.downloadWorkerNumber, sched.analyzeWorkerNumber, sched.pickWorkerNumber)
 	sched.resetContext()
 
 	sched.summary = newSchedSummary(requestArgs, dataArgs, moduleArgs, sched)
@@ -154,6 +315,10 @@ func (sched *myScheduler) Init(requestArgs RequestArgs, dataArgs DataArgs, modul
 }
 
 func (sched *myScheduler) Start(firstHTTPReq *http.Request) (err error) {
+	return sched.StartWithSeeds([]*http.Request{firstHTTPReq})
+}
+
+func (sched *myScheduler) StartWithSeeds(seeds []*http.Request) (err error) {
 	defer func() {
 		if p := recover(); p != nil {
 			errMsg := fmt.Sprintf("Fatal scheduler error: %s", p)
@@ -170,13 +335,7 @@ func (sched *myScheduler) Start(firstHTTPReq *http.Request) (err error) {
 	oldStatus, err = sched.checkAndSetStatus(SCHED_STATUS_STARTING)
 
 	defer func() {
-		sched.statusLock.Lock()
-		if err != nil {
-			sched.status = oldStatus
-		} else {
-			sched.status = SCHED_STATUS_STARTED
-		}
-		sched.statusLock.Unlock()
+		sched.finishStatus(oldStatus, SCHED_STATUS_STARTED, err)
 	}()
 
 	if err != nil {
@@ -184,63 +343,158 @@ func (sched *myScheduler) Start(firstHTTPReq *http.Request) (err error) {
 	}
 
 	// 检查参数。
-	logger.Info("Check first HTTP request...")
-	if firstHTTPReq == nil {
-		err = genParameterError("nil first HTTP request")
+	logger.Info("Check seed HTTP requests...")
+	if len(seeds) == 0 {
+		err = genParameterError("empty seed HTTP request list")
+		return
+	}
+
+	primaryDomains := make([]string, 0, len(seeds))
+	for i, seed := range seeds {
+		if seed == nil {
+			err = genParameterError(fmt.Sprintf("nil seed HTTP request (index: %d)", i))
+			return
+		}
+
+		// 获得种子请求的主域名。
+		var primaryDomain string
+		primaryDomain, err = getPrimaryDomain(seed.Host)
+		if err != nil {
+			return
+		}
+		primaryDomains = append(primaryDomains, primaryDomain)
+	}
+
+	logger.Infof("The %d seed HTTP request(s) are valid.", len(seeds))
+	// 将种子请求的主域名添加到可接受的主域名的字典。
+	if sched.acceptSeedDomains {
+		for i, primaryDomain := range primaryDomains {
+			logger.Infof("-- Host: %s, primary domain: %s", seeds[i].Host, primaryDomain)
+			sched.acceptedDomainMap.Put(primaryDomain, struct{}{})
+		}
+	}
+
+	// 开始调度数据和组件。
+	if err = sched.startLoops(); err != nil {
 		return
 	}
 
-	logger.Info("The first HTTP request is valid.")
-	// 获得首次请求的主域名，并将其添加到可接受的主域名的字典。
-	logger.Info("Get the primary domain...")
-	logger.Infof("-- Host: %s", firstHTTPReq.Host)
+	logger.Info("Scheduler has been started.")
+	// 放入种子请求。
+	// 放入期间视作一项进行中的工作，以免在放入后续种子请求之前，
+	// 先放入的种子请求均已处理完毕而过早地发出完成通知。
+	sched.inFlight.incr()
+	for _, seed := range seeds {
+		seedReq := module.NewRequest(seed, 0)
+		seedReq.SetSource(module.SOURCE_SEED)
+		sched.prioritize(seedReq, nil)
+		sched.sendReq(seedReq)
+	}
+	// 所有种子请求都被过滤掉时，爬取即已完成。
+	sched.inFlight.decr()
+	return nil
+}
+
+func (sched *myScheduler) StartFrom(filePath string) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			errMsg := fmt.Sprintf("Fatal scheduler error: %s", p)
+			logger.Fatal(errMsg)
+			err = genError(errMsg)
+		}
+	}()
+
+	logger.Infof("Start scheduler from checkpoint %s...", filePath)
+	// 检查状态。
+	logger.Info("Check status for start...")
+
+	var oldStatus Status
+	oldStatus, err = sched.checkAndSetStatus(SCHED_STATUS_STARTING)
+
+	defer func() {
+		sched.finishStatus(oldStatus, SCHED_STATUS_STARTED, err)
+	}()
 
-	var primaryDomain string
-	primaryDomain, err = getPrimaryDomain(firstHTTPReq.Host)
 	if err != nil {
 		return
 	}
 
-	logger.Infof("-- Primary domain: %s", primaryDomain)
-	sched.acceptedDomainMap.Put(primaryDomain, struct{}{})
-	// 开始调度数据和组件。
-	if err = sched.checkBufferPoolForStart(); err != nil {
+	// 加载检查点。
+	logger.Info("Load checkpoint...")
+	var cp *Checkpoint
+	cp, err = LoadCheckpoint(filePath)
+	if err != nil {
 		return
 	}
 
-	sched.download()
-	sched.analyze()
-	sched.pick()
+	reqs := make([]*module.Request, 0, len(cp.Requests))
+	for _, cpReq := range cp.Requests {
+		var req *module.Request
+		req, err = cpReq.toRequest()
+		if err != nil {
+			return
+		}
+		reqs = append(reqs, req)
+	}
+
+	// 恢复可接受的主域名和已处理的URL。
+	for _, domain := range cp.AcceptedDomains {
+		sched.acceptedDomainMap.Put(domain, struct{}{})
+	}
+
+	if err = restoreDedupeState(sched.deduper, cp); err != nil {
+		return
+	}
+
+	logger.Infof("-- Accepted domains: %d, requests: %d, pending requests: %d",
+		len(cp.AcceptedDomains), sched.deduper.Len(), len(reqs))
+	// 开始调度数据和组件。
+	if err = sched.startLoops(); err != nil {
+		return
+	}
 
 	logger.Info("Scheduler has been started.")
-	// 放入第一个请求。
-	firstReq := module.NewRequest(firstHTTPReq, 0)
-	sched.sendReq(firstReq)
+	// 重新放入待处理的请求。
+	// 尚未被记为已处理的请求是保存检查点时正在等待robots.txt规则的请求，需要重新检查。
+	sched.inFlight.incr()
+	for _, req := range reqs {
+		if exists, err := sched.deduper.Contains(sched.fingerprint(req)); err == nil && !exists {
+			sched.sendReq(req)
+			continue
+		}
+		sched.enqueueReq(req)
+	}
+	// 检查点中没有待处理的请求时，爬取即已完成。
+	sched.inFlight.decr()
+
 	return nil
 }
 
 func (sched *myScheduler) Stop() (err error) {
-	logger.Info("Stop scheduler...")
+	return sched.stop(STOP_REASON_MANUAL)
+}
+
+// stop 用于以给定的原因停止调度器。
+func (sched *myScheduler) stop(reason StopReason) (err error) {
+	logger.Infof("Stop scheduler (reason: %s)...", reason)
 	// 检查状态。
 	logger.Info("Check status for stop...")
 	var oldStatus Status
 	oldStatus, err = sched.checkAndSetStatus(SCHED_STATUS_STOPPING)
 
 	defer func() {
-		sched.statusLock.Lock()
-		if err != nil {
-			sched.status = oldStatus
-		} else {
-			sched.status = SCHED_STATUS_STOPPED
-		}
-		sched.statusLock.Unlock()
+		sched.finishStatus(oldStatus, SCHED_STATUS_STOPPED, err)
 	}()
 
 	if err != nil {
 		return
 	}
 
+	sched.setStopReason(reason)
 	sched.cancelFunc()
+	sched.releasePause()
+	sched.wakeLeaseWaiters()
+	sched.inFlight.stop()
 	sched.reqBufferPool.Close()
 	sched.respBufferPool.Close()
 	sched.itemBufferPool.Close()
@@ -250,6 +504,72 @@ func (sched *myScheduler) Stop() (err error) {
 	return nil
 }
 
+func (sched *myScheduler) Close() (err error) {
+	logger.Info("Close scheduler...")
+	sched.statusLock.Lock()
+	defer sched.statusLock.Unlock()
+	switch sched.status {
+	case SCHED_STATUS_UNINITIALIZED, SCHED_STATUS_INITIALIZED, SCHED_STATUS_STOPPED:
+	default:
+		errMsg := fmt.Sprintf("couldn't close scheduler in status %q", GetStatusDescription(sched.status))
+		return genError(errMsg)
+	}
+
+	if sched.deduper != nil {
+		if err = sched.deduper.Close(); err != nil {
+			return genErrorByError(err)
+		}
+	}
+
+	logger.Info("Scheduler has been closed.")
+	return nil
+}
+
+func (sched *myScheduler) Pause() (err error) {
+	logger.Info("Pause scheduler...")
+	// 检查状态。
+	logger.Info("Check status for pause...")
+	var oldStatus Status
+	oldStatus, err = sched.checkAndSetStatus(SCHED_STATUS_PAUSING)
+
+	defer func() {
+		sched.finishStatus(oldStatus, SCHED_STATUS_PAUSED, err)
+	}()
+
+	if err != nil {
+		return
+	}
+
+	sched.pauseLock.Lock()
+	if sched.resumeCh == nil {
+		sched.resumeCh = make(chan struct{})
+	}
+	sched.pauseLock.Unlock()
+
+	logger.Info("Scheduler has been paused.")
+	return nil
+}
+
+func (sched *myScheduler) Resume() (err error) {
+	logger.Info("Resume scheduler...")
+	// 检查状态。
+	logger.Info("Check status for resume...")
+	var oldStatus Status
+	oldStatus, err = sched.checkAndSetStatus(SCHED_STATUS_RESUMING)
+
+	defer func() {
+		sched.finishStatus(oldStatus, SCHED_STATUS_STARTED, err)
+	}()
+
+	if err != nil {
+		return
+	}
+
+	sched.releasePause()
+	logger.Info("Scheduler has been resumed.")
+	return nil
+}
+
 func (sched *myScheduler) Status() Status {
 	var status Status
 	sched.statusLock.RLock()
@@ -279,7 +599,7 @@ func (sched *myScheduler) ErrorChan() <-chan error {
 			err, ok := datum.(error)
 			if !ok {
 				errMsg := fmt.Sprintf("incorrect error type: %T", datum)
-				sendError(errors.New(errMsg), "", sched.errorBufferPool)
+				sched.reportError(errors.New(errMsg), "")
 				continue
 			}
 
@@ -308,6 +628,11 @@ func (sched *myScheduler) Idle() bool {
 		return false
 	}
 
+	// 正要放入缓冲池或正在被处理的数据也意味着调度器并不空闲。
+	if sched.inFlight != nil && sched.inFlight.number() > 0 {
+		return false
+	}
+
 	return true
 }
 
@@ -318,14 +643,17 @@ func (sched *myScheduler) Summary() SchedSummary {
 // checkAndSetStatus 用于状态的检查，并在条件满足时设置状态。
 func (sched *myScheduler) checkAndSetStatus(wantedStatus Status) (oldStatus Status, err error) {
 	sched.statusLock.Lock()
-	defer sched.statusLock.Unlock()
 
 	oldStatus = sched.status
 	err = checkStatus(oldStatus, wantedStatus, nil)
 	if err == nil {
 		sched.status = wantedStatus
 	}
+	sched.statusLock.Unlock()
 
+	if err == nil {
+		sched.hooks.onStatusChange(oldStatus, wantedStatus)
+	}
 	return
 }
 
@@ -385,30 +713,41 @@ func (sched *myScheduler) registerModules(moduleArgs ModuleArgs) error {
 	return nil
 }
 
-// download 会从请求缓冲池取出请求并下载，
+// download 会启动若干工作协程，从请求缓冲池取出请求并下载，
 // 然后把得到的响应放入响应缓冲池。
 func (sched *myScheduler) download() {
-	go func() {
-		for {
-			if sched.canceled() {
-				break
-			}
+	for i := 0; i < sched.downloadWorkerNumber; i++ {
+		go sched.downloadLoop()
+	}
+}
 
-			datum, err := sched.reqBufferPool.Get()
-			if err != nil {
-				logger.Warnln("The request buffer pool was closed. Break request reception.")
-				break
-			}
+// downloadLoop 代表单个下载工作协程的处理流程。
+func (sched *myScheduler) downloadLoop() {
+	for {
+		sched.waitForResume()
+		if sched.canceled() {
+			break
+		}
 
-			req, ok := datum.(*module.Request)
-			if !ok {
-				errMsg := fmt.Sprintf("incorrect request type: %T", datum)
-				sendError(errors.New(errMsg), "", sched.errorBufferPool)
-			}
+		datum, err := sched.reqBufferPool.Get()
+		if err != nil {
+			logger.Warnln("The request buffer pool was closed. Break request reception.")
+			break
+		}
 
-			sched.downloadOne(req)
+		req, ok := datum.(*module.Request)
+		if !ok {
+			errMsg := fmt.Sprintf("incorrect request type: %T", datum)
+			sched.reportError(errors.New(errMsg), "")
+			sched.inFlight.decr()
+			continue
 		}
-	}()
+
+		// 若取出请求时调度器已被暂停，则持有该请求直至恢复。
+		sched.waitForResume()
+		sched.downloadOne(req)
+		sched.inFlight.decr()
+	}
 }
 
 // downloadOne 会根据给定的请求执行下载并把响应放入响应缓冲池。
@@ -421,58 +760,142 @@ func (sched *myScheduler) downloadOne(req *module.Request) {
 		return
 	}
 
-	m, err := sched.registrar.Get(module.TYPE_DOWNLOADER)
+	// 在下载之前遵守针对目标主机的礼貌性限制，尚不能访问的请求会被推迟，以免阻塞其他主机的下载。
+	host := req.HTTPReq().Host
+	if host == "" {
+		host = req.HTTPReq().URL.Host
+	}
+	ok, wait, releaseCh := sched.politeness.tryAcquire(host)
+	if !ok {
+		sched.deferReq(req, host, wait, releaseCh)
+		return
+	}
+	defer sched.politeness.release(host)
+
+	m, err := sched.acquireModule(module.TYPE_DOWNLOADER, host)
 	if err != nil || m == nil {
 		errMsg := fmt.Sprintf("couldn't get a downloader: %s", err)
-		sendError(errors.New(errMsg), "", sched.errorBufferPool)
-		sched.sendReq(req)
+		sched.reportError(errors.New(errMsg), "")
+		sched.enqueueReq(req)
 		return
 	}
 
 	downloader, ok := m.(module.Downloader)
 	if !ok {
+		sched.releaseModule(m)
 		errMsg := fmt.Sprintf("incorrect downloader type: %T (MID: %s)",
 			m, m.ID())
-		sendError(errors.New(errMsg), m.ID(), sched.errorBufferPool)
-		sched.sendReq(req)
+		sched.reportError(errors.New(errMsg), m.ID())
+		sched.enqueueReq(req)
 		return
 	}
 
 	resp, err := downloader.Download(req)
+	sched.releaseModule(m)
+	sched.registrar.Report(m.ID(), err == nil)
+	if err == nil && resp != nil {
+		sched.latency.record(resp.FetchInfo().Timing)
+	}
+	if sched.retry(req, resp, err, m.ID()) {
+		return
+	}
+	// 响应会被放入响应缓冲池时，请求在其响应被分析之前仍属于待处理的请求。
+	if resp == nil || resp.RedirectRequest() != nil || resp.Request() == nil {
+		sched.donePendingReq(req)
+	}
+
 	if resp != nil {
-		sendResp(resp, sched.respBufferPool)
+		// 由缓存提供的响应体并未被下载，不计入预算。
+		if httpResp := resp.HTTPResp(); httpResp != nil && !resp.Cached() {
+			httpResp.Body = sched.budget.countBody(httpResp.Body)
+		}
+		sched.hooks.onResponse(resp, m.ID())
+		if redirectReq := resp.RedirectRequest(); redirectReq != nil {
+			sched.handOffRedirect(resp, redirectReq)
+		} else {
+			sched.markRedirected(req, resp)
+			sched.putResp(resp)
+		}
+	}
+
+	if err != nil {
+		sched.reportError(err, m.ID())
+	}
+}
+
+// retry 会在下载失败且可以重试时把请求重新放入请求缓冲池。
+// 重试次数耗尽时会发送专门的错误。
+// 若结果值为true，则说明下载的结果已被处理，无需再发送响应或错误。
+func (sched *myScheduler) retry(req *module.Request, resp *module.Response, err error, mid module.MID) bool {
+	if !sched.retryer.enabled() {
+		return false
 	}
 
+	var reason string
 	if err != nil {
-		sendError(err, m.ID(), sched.errorBufferPool)
+		if !sched.retryer.retryableError(err) {
+			return false
+		}
+		reason = err.Error()
+	} else {
+		if resp == nil || resp.HTTPResp() == nil ||
+			!sched.retryer.retryableStatus(resp.HTTPResp().StatusCode) {
+			return false
+		}
+		reason = fmt.Sprintf("unexpected status code %d", resp.HTTPResp().StatusCode)
+	}
+
+	if resp != nil && resp.HTTPResp() != nil && resp.HTTPResp().Body != nil {
+		resp.HTTPResp().Body.Close()
 	}
 
+	if sched.retryer.exhausted(req) {
+		sched.donePendingReq(req)
+		sched.reportError(genRetryExhaustedError(req, reason), mid)
+		return true
+	}
+
+	backoff := sched.retryer.backoff(req.Attempt() + 1)
+	logger.Warnf("Retry the request after %s: %s (attempt: %d, URL: %s)\n",
+		backoff, reason, req.Attempt()+1, req.HTTPReq().URL)
+	sched.enqueueReq(module.NewRetryRequest(req, time.Now().Add(backoff)))
+	return true
 }
 
-// analyze 会从响应缓冲池取出响应并解析，
+// analyze 会启动若干工作协程，从响应缓冲池取出响应并解析，
 // 然后把得到的条目或请求放入相应的缓冲池。
 func (sched *myScheduler) analyze() {
-	go func() {
-		for {
-			if sched.canceled() {
-				break
-			}
+	for i := 0; i < sched.analyzeWorkerNumber; i++ {
+		go sched.analyzeLoop()
+	}
+}
 
-			datum, err := sched.respBufferPool.Get()
-			if err != nil {
-				logger.Warnln("The response buffer pool was closed. Break response reception.")
-				break
-			}
+// analyzeLoop 代表单个分析工作协程的处理流程。
+func (sched *myScheduler) analyzeLoop() {
+	for {
+		sched.waitForResume()
+		if sched.canceled() {
+			break
+		}
 
-			resp, ok := datum.(*module.Response)
-			if !ok {
-				errMsg := fmt.Sprintf("incorrect response type: %T", datum)
-				sendError(errors.New(errMsg), "", sched.errorBufferPool)
-			}
+		datum, err := sched.respBufferPool.Get()
+		if err != nil {
+			logger.Warnln("The response buffer pool was closed. Break response reception.")
+			break
+		}
 
-			sched.analyzeOne(resp)
+		resp, ok := datum.(*module.Response)
+		if !ok {
+			errMsg := fmt.Sprintf("incorrect response type: %T", datum)
+			sched.reportError(errors.New(errMsg), "")
+			sched.inFlight.decr()
+			continue
 		}
-	}()
+
+		sched.waitForResume()
+		sched.analyzeOne(resp)
+		sched.inFlight.decr()
+	}
 }
 
 // analyzeOne 会根据给定的响应执行解析并把结果放入相应的缓冲池。
@@ -485,24 +908,33 @@ func (sched *myScheduler) analyzeOne(resp *module.Response) {
 		return
 	}
 
-	m, err := sched.registrar.Get(module.TYPE_ANALYZER)
+	var host string
+	if httpReq := resp.HTTPResp().Request; httpReq != nil {
+		host = httpReq.URL.Host
+	}
+	m, err := sched.acquireModule(module.TYPE_ANALYZER, host)
 	if err != nil || m == nil {
 		errMsg := fmt.Sprintf("couldn't get an analyzer: %s", err)
-		sendError(errors.New(errMsg), "", sched.errorBufferPool)
-		sendResp(resp, sched.respBufferPool)
+		sched.reportError(errors.New(errMsg), "")
+		sched.putResp(resp)
 		return
 	}
 
 	analyzer, ok := m.(module.Analyzer)
 	if !ok {
+		sched.releaseModule(m)
 		errMsg := fmt.Sprintf("incorrect analyzer type: %T (MID: %s)",
 			m, m.ID())
-		sendError(errors.New(errMsg), m.ID(), sched.errorBufferPool)
-		sendResp(resp, sched.respBufferPool)
+		sched.reportError(errors.New(errMsg), m.ID())
+		sched.putResp(resp)
 		return
 	}
 
 	dataList, errs := analyzer.Analyze(resp)
+	sched.releaseModule(m)
+	// 只有错误而没有任何结果时才视为分析器的调用失败。
+	sched.registrar.Report(m.ID(), len(errs) == 0 || len(dataList) > 0)
+	group := sched.newItemGroup(resp.Request(), dataList)
 	if dataList != nil {
 		for _, data := range dataList {
 			if data == nil {
@@ -510,79 +942,98 @@ func (sched *myScheduler) analyzeOne(resp *module.Response) {
 			}
 			switch d := data.(type) {
 			case *module.Request:
+				sched.prioritize(d, resp)
 				sched.sendReq(d)
 			case module.Item:
-				sendItem(d, sched.itemBufferPool)
+				sched.putItem(&pendingItem{item: d, group: group})
 			default:
 				errMsg := fmt.Sprintf("Unsupported data type %T! (data: %#v)", d, d)
-				sendError(errors.New(errMsg), m.ID(), sched.errorBufferPool)
+				sched.reportError(errors.New(errMsg), m.ID())
 			}
 		}
 	}
 
 	if errs != nil {
 		for _, err := range errs {
-			sendError(err, m.ID(), sched.errorBufferPool)
+			sched.reportError(err, m.ID())
 		}
 	}
 
 }
 
-// pick 会从条目缓冲池取出条目并处理。
+// pick 会启动若干工作协程，从条目缓冲池取出条目并处理。
 func (sched *myScheduler) pick() {
-	go func() {
-		for {
-			if sched.canceled() {
-				break
-			}
+	for i := 0; i < sched.pickWorkerNumber; i++ {
+		go sched.pickLoop()
+	}
+}
 
-			datum, err := sched.itemBufferPool.Get()
-			if err != nil {
-				logger.Warnln("The item buffer pool was closed. Break item reception.")
-				break
-			}
+// pickLoop 代表单个条目处理工作协程的处理流程。
+func (sched *myScheduler) pickLoop() {
+	for {
+		sched.waitForResume()
+		if sched.canceled() {
+			break
+		}
 
-			item, ok := datum.(module.Item)
-			if !ok {
-				errMsg := fmt.Sprintf("incorrect item type: %T", datum)
-				sendError(errors.New(errMsg), "", sched.errorBufferPool)
-			}
+		datum, err := sched.itemBufferPool.Get()
+		if err != nil {
+			logger.Warnln("The item buffer pool was closed. Break item reception.")
+			break
+		}
 
-			sched.pickOne(item)
+		item, ok := datum.(*pendingItem)
+		if !ok {
+			errMsg := fmt.Sprintf("incorrect item type: %T", datum)
+			sched.reportError(errors.New(errMsg), "")
+			sched.inFlight.decr()
+			continue
 		}
-	}()
+
+		sched.waitForResume()
+		sched.pickOne(item)
+		sched.inFlight.decr()
+	}
 }
 
 // pickOne 会处理给定的条目。
-func (sched *myScheduler) pickOne(item module.Item) {
+func (sched *myScheduler) pickOne(item *pendingItem) {
+	if item == nil {
+		return
+	}
+
 	if sched.canceled() {
 		return
 	}
 
-	m, err := sched.registrar.Get(module.TYPE_PIPELINE)
+	m, err := sched.acquireModule(module.TYPE_PIPELINE, "")
 	if err != nil || m == nil {
 		errMsg := fmt.Sprintf("couldn't get a pipeline: %s", err)
-		sendError(errors.New(errMsg), "", sched.errorBufferPool)
-		sendItem(item, sched.itemBufferPool)
+		sched.reportError(errors.New(errMsg), "")
+		sched.putItem(item)
 		return
 	}
 
 	pipeline, ok := m.(module.Pipeline)
 	if !ok {
+		sched.releaseModule(m)
 		errMsg := fmt.Sprintf("incorrect pipeline type: %T (MID: %s)",
 			m, m.ID())
-		sendError(errors.New(errMsg), m.ID(), sched.errorBufferPool)
-		sendItem(item, sched.itemBufferPool)
+		sched.reportError(errors.New(errMsg), m.ID())
+		sched.putItem(item)
 		return
 	}
 
-	errs := pipeline.Send(item)
+	errs := pipeline.Send(item.item)
+	sched.releaseModule(m)
+	sched.registrar.Report(m.ID(), len(errs) == 0)
+	sched.doneItem(item)
+	sched.hooks.onItem(item.item, m.ID())
 	if errs != nil {
 		for _, err := range errs {
-			sendError(err, m.ID(), sched.errorBufferPool)
+			sched.reportError(err, m.ID())
 		}
 	}
-
 }
 
 // sendReq 会向请求缓冲池发送请求。
@@ -593,31 +1044,34 @@ func (sched *myScheduler) sendReq(req *module.Request) bool {
 	}
 
 	if sched.canceled() {
-		return false
+		return sched.filterReq(req, FILTER_REASON_STOPPED)
 	}
 
 	httpReq := req.HTTPReq()
 	if httpReq == nil {
 		logger.Warnln("Ignore the request! Its HTTP request is invalid!")
-		return false
+		return sched.filterReq(req, FILTER_REASON_INVALID)
 	}
 
 	reqURL := httpReq.URL
 	if reqURL == nil {
 		logger.Warnln("Ignore the request! Its URL is invalid!")
-		return false
+		return sched.filterReq(req, FILTER_REASON_INVALID)
 	}
 
 	scheme := strings.ToLower(reqURL.Scheme)
 	if scheme != "http" && scheme != "https" {
 		logger.Warnf("Ignore the request! Its URL scheme is %q, but should be %q or %q. (URL: %s)\n",
 			scheme, "http", "https", reqURL)
-		return false
+		return sched.filterReq(req, FILTER_REASON_SCHEME)
 	}
 
-	if v := sched.urlMap.Get(reqURL.String()); v != nil {
-		logger.Warnf("Ignore the request! Its URL is repeated. (URL: %s)\n", reqURL)
-		return false
+	fp := sched.fingerprint(req)
+	if exists, err := sched.deduper.Contains(fp); err != nil {
+		logger.Errorf("An error occurs when checking the request: %s (URL: %s)\n", err, reqURL)
+	} else if exists {
+		logger.Warnf("Ignore the request! It is repeated. (method: %s, URL: %s)\n", httpReq.Method, reqURL)
+		return sched.filterReq(req, FILTER_REASON_REPEATED)
 	}
 
 	pd, _ := getPrimaryDomain(httpReq.Host)
@@ -627,23 +1081,118 @@ func (sched *myScheduler) sendReq(req *module.Request) bool {
 		}
 		logger.Warnf("Ignore the request! Its host %q is not in accepted primary domain map. (URL: %s)\n",
 			httpReq.Host, reqURL)
-		return false
+		return sched.filterReq(req, FILTER_REASON_DOMAIN)
+	}
+
+	if !sched.scope.allowed(reqURL) {
+		logger.Warnf("Ignore the request! It is out of the scope. (URL: %s)\n", reqURL)
+		return sched.filterReq(req, FILTER_REASON_SCOPE)
 	}
 
 	if req.Depth() > sched.maxDepth {
 		logger.Warnf("Ignore the request! Its depth %d is greater than %d. (URL: %s)\n",
 			req.Depth(), sched.maxDepth, reqURL)
-		return false
+		return sched.filterReq(req, FILTER_REASON_DEPTH)
 	}
 
+	if sched.robotsCache != nil {
+		return sched.checkRobots(req, fp, pd)
+	}
+	return sched.admitReq(req, fp, pd)
+}
+
+// admitReq 会把已通过前述检查的请求记为已处理并放入请求缓冲池。
+// 若请求重复或爬取预算已耗尽，则会被过滤掉。
+func (sched *myScheduler) admitReq(req *module.Request, fp string, pd string) bool {
+	httpReq := req.HTTPReq()
+	reqURL := httpReq.URL
+	// 先申请预算再记为已处理，以免因预算用尽而被忽略的请求在提高预算后也无法再被发送。
+	if !sched.budget.takePage(pd) {
+		logger.Warnf("Ignore the request! The crawl budget is exhausted. (URL: %s)\n", reqURL)
+		return sched.filterReq(req, FILTER_REASON_BUDGET)
+	}
+
+	// 同样的请求可能在检查之后被并发地发送，所以需要根据添加的结果再次判断。
+	if added, err := sched.deduper.Add(fp); err != nil {
+		logger.Errorf("An error occurs when recording the request: %s (URL: %s)\n", err, reqURL)
+	} else if !added {
+		sched.budget.refundPage(pd)
+		logger.Warnf("Ignore the request! It is repeated. (method: %s, URL: %s)\n", httpReq.Method, reqURL)
+		return sched.filterReq(req, FILTER_REASON_REPEATED)
+	}
+
+	sched.enqueueReq(req)
+	sched.hooks.onRequestScheduled(req)
+	return true
+}
+
+// enqueueReq 会把请求放入请求缓冲池并记为待处理，不做任何过滤。
+// 若请求设置了可被下载的最早时间，则会等到该时间之后再放入。
+func (sched *myScheduler) enqueueReq(req *module.Request) {
+	// 下载器直接跟随的重定向会绕过过滤和去重，所以每一次重定向都要交给调度器。
+	req.SetHandOffRedirects(true)
+	sched.pendingReqMap.Put(sched.fingerprint(req), req)
+	sched.inFlight.incr()
+	ctx := sched.ctx
 	go func(req *module.Request) {
+		if wait := time.Until(req.NotBefore()); wait > 0 {
+			timer := time.NewTimer(wait)
+			defer timer.Stop()
+			select {
+			case <-ctx.Done():
+				sched.inFlight.decr()
+				return
+			case <-timer.C:
+			}
+		}
+
 		if err := sched.reqBufferPool.Put(req); err != nil {
 			logger.Warnln("The request buffer pool was closed. Ignore request sending.")
+			sched.inFlight.decr()
 		}
 	}(req)
+}
 
-	sched.urlMap.Put(reqURL.String(), struct{}{})
-	return true
+// deferReq 会在给定的时长之后或给定的通道被关闭之后把请求重新放入请求缓冲池。
+// 请求在此期间仍属于待处理的请求和进行中的工作。
+func (sched *myScheduler) deferReq(req *module.Request, host string, wait time.Duration, releaseCh <-chan struct{}) {
+	sched.inFlight.incr()
+	ctx := sched.ctx
+	go func(req *module.Request) {
+		var timeout <-chan time.Time
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+			defer timer.Stop()
+			timeout = timer.C
+		}
+		select {
+		case <-ctx.Done():
+			sched.politeness.doneWaiting(host)
+			sched.inFlight.decr()
+			return
+		case <-timeout:
+		case <-releaseCh:
+		}
+		sched.politeness.doneWaiting(host)
+
+		if err := sched.reqBufferPool.Put(req); err != nil {
+			logger.Warnln("The request buffer pool was closed. Ignore request sending.")
+			sched.inFlight.decr()
+		}
+	}(req)
+}
+
+// donePendingReq 会把请求从待处理请求的字典中移除。
+func (sched *myScheduler) donePendingReq(req *module.Request) {
+	if httpReq := req.HTTPReq(); httpReq != nil && httpReq.URL != nil {
+		sched.pendingReqMap.Delete(sched.fingerprint(req))
+	}
+}
+
+// fingerprint 用于计算请求的指纹，请求的URL必须有效。
+func (sched *myScheduler) fingerprint(req *module.Request) string {
+	canonicalURL := sched.canonicalizer.canonicalize(req.HTTPReq().URL)
+	return module.Fingerprint(req, canonicalURL, sched.fingerprintHeaders...)
 }
 
 // sendResp 会向响应缓冲池发送响应。
@@ -662,12 +1211,12 @@ func sendResp(resp *module.Response, respBufferPool buffer.Pool) bool {
 }
 
 // sendItem 会向条目缓冲池发送条目。
-func sendItem(item module.Item, itemBufferPool buffer.Pool) bool {
-	if item == nil || itemBufferPool == nil || itemBufferPool.Closed() {
+func sendItem(item *pendingItem, itemBufferPool buffer.Pool) bool {
+	if item == nil || item.item == nil || itemBufferPool == nil || itemBufferPool.Closed() {
 		return false
 	}
 
-	go func(item module.Item) {
+	go func(item *pendingItem) {
 		if err := itemBufferPool.Put(item); err != nil {
 			logger.Warnln("The item buffer pool was closed. Ignore item sending.")
 		}
@@ -676,6 +1225,29 @@ func sendItem(item module.Item, itemBufferPool buffer.Pool) bool {
 	return true
 }
 
+// startLoops 会检查缓冲池并开始调度数据和组件。
+func (sched *myScheduler) startLoops() error {
+	if err := sched.checkBufferPoolForStart(); err != nil {
+		return err
+	}
+
+	// 调度器被停止后再次启动时需要重置上下文和待处理请求。
+	if sched.canceled() {
+		sched.resetContext()
+	}
+	sched.pendingReqMap, _ = cmap.NewConcurrentMap(16, nil)
+	if sched.inFlight == nil || sched.inFlight.ended() {
+		sched.inFlight = newInFlight()
+	}
+	sched.setStopReason("")
+	sched.budget.start(sched.ctx)
+
+	sched.download()
+	sched.analyze()
+	sched.pick()
+	return nil
+}
+
 // initBufferPool 用于按照给定的参数初始化缓冲池。
 // 如果某个缓冲池可用且未关闭，就先关闭该缓冲池。
 func (sched *myScheduler) initBufferPool(dataArgs DataArgs) {
@@ -684,7 +1256,7 @@ func (sched *myScheduler) initBufferPool(dataArgs DataArgs) {
 		sched.reqBufferPool.Close()
 	}
 
-	sched.reqBufferPool, _ = buffer.NewPool(dataArgs.ReqBufferCap, dataArgs.ReqMaxBufferNumber)
+	sched.reqBufferPool, _ = buffer.NewPriorityPool(dataArgs.ReqBufferCap, dataArgs.ReqMaxBufferNumber)
 
 	logger.Infof("-- Request buffer pool: bufferCap: %d, maxBufferNumber: %d", sched.reqBufferPool.BufferCap(), sched.reqBufferPool.MaxBufferNumber())
 
@@ -723,7 +1295,7 @@ func (sched *myScheduler) checkBufferPoolForStart() error {
 	}
 
 	if sched.reqBufferPool != nil && sched.reqBufferPool.Closed() {
-		sched.reqBufferPool, _ = buffer.NewPool(sched.reqBufferPool.BufferCap(), sched.reqBufferPool.MaxBufferNumber())
+		sched.reqBufferPool, _ = buffer.NewPriorityPool(sched.reqBufferPool.BufferCap(), sched.reqBufferPool.MaxBufferNumber())
 	}
 
 	// 检查响应缓冲池。
@@ -761,6 +1333,32 @@ func (sched *myScheduler) resetContext() {
 	sched.ctx, sched.cancelFunc = context.WithCancel(context.Background())
 }
 
+// waitForResume 用于在调度器已暂停时阻塞，直至调度器被恢复或停止。
+func (sched *myScheduler) waitForResume() {
+	sched.pauseLock.RLock()
+	resumeCh := sched.resumeCh
+	sched.pauseLock.RUnlock()
+	if resumeCh == nil {
+		return
+	}
+
+	select {
+	case <-resumeCh:
+	case <-sched.ctx.Done():
+	}
+}
+
+// releasePause 用于解除暂停，唤醒所有等待恢复的处理流程。
+func (sched *myScheduler) releasePause() {
+	sched.pauseLock.Lock()
+	defer sched.pauseLock.Unlock()
+
+	if sched.resumeCh != nil {
+		close(sched.resumeCh)
+		sched.resumeCh = nil
+	}
+}
+
 // canceled 用于判断调度器的上下文是否已被取消。
 func (sched *myScheduler) canceled() bool {
 	select {
@@ -1,14 +1,17 @@
 package scheduler
 
 import (
+	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"runtime"
+	"sync/atomic"
 	"testing"
 	"time"
 
-	"crawler/cmap"
 	"crawler/module"
 	"crawler/toolkit/buffer"
+	"crawler/toolkit/dedupe"
 )
 
 // snGen 代表序列号生成器。
@@ -79,7 +82,7 @@ func TestSchedInit(t *testing.T) {
 		dataArgs := genDataArgs(10, 2, 1)
 		err = sched.Init(requestArgs, dataArgs, invalidModuleArgs)
 		if err == nil {
-			t.Fatalf("No error when initialize scheduler with illegal module arguments %s!", invalidModuleArgs)
+			t.Fatalf("No error when initialize scheduler with illegal module arguments %#v!", invalidModuleArgs)
 		}
 	}
 
@@ -135,6 +138,75 @@ func TestSchedStart(t *testing.T) {
 	sched.Stop()
 }
 
+func TestSchedStartWithSeeds(t *testing.T) {
+	urls := []string{
+		"http://cn.bing.com/search?q=golang",
+		"http://www.sogou.com/web?query=golang",
+	}
+	seeds := []*http.Request{}
+	for _, url := range urls {
+		seed, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			t.Fatalf("An error occurs when creating a HTTP request: %s (url: %s)", err, url)
+		}
+		seeds = append(seeds, seed)
+	}
+
+	requestArgs := genRequestArgs([]string{}, 0)
+	requestArgs.IgnoreRobots = true
+	dataArgs := genDataArgs(10, 2, 1)
+	moduleArgs := genSimpleModuleArgs(3, 2, 1, t)
+	sched := NewScheduler()
+	if err := sched.Init(requestArgs, dataArgs, moduleArgs); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+
+	// 测试种子请求异常时的情况。
+	if err := sched.StartWithSeeds(nil); err == nil {
+		t.Fatal("No error when start scheduler with empty seed list!")
+	}
+
+	if err := sched.StartWithSeeds([]*http.Request{seeds[0], nil}); err == nil {
+		t.Fatal("No error when start scheduler with nil seed!")
+	}
+
+	if err := sched.StartWithSeeds(seeds); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+
+	mySched := sched.(*myScheduler)
+	if n := mySched.deduper.Len(); n != uint64(len(seeds)) {
+		t.Fatalf("Inconsistent deduper length: expected: %d, actual: %d", len(seeds), n)
+	}
+
+	for _, domain := range []string{"bing.com", "sogou.com"} {
+		if mySched.acceptedDomainMap.Get(domain) == nil {
+			t.Fatalf("The primary domain %q of seed was not accepted!", domain)
+		}
+	}
+	sched.Stop()
+
+	// 测试忽略种子域名的情况。
+	requestArgs.IgnoreSeedDomains = true
+	requestArgs.AcceptedDomains = []string{"bing.com"}
+	if err := sched.Init(requestArgs, dataArgs, moduleArgs); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+
+	if err := sched.StartWithSeeds(seeds); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	defer sched.Stop()
+
+	if mySched.acceptedDomainMap.Get("sogou.com") != nil {
+		t.Fatal("The primary domain of seed should not be accepted!")
+	}
+
+	if n := mySched.deduper.Len(); n != 1 {
+		t.Fatalf("Inconsistent deduper length: expected: %d, actual: %d", 1, n)
+	}
+}
+
 func TestSchedStop(t *testing.T) {
 	sched := NewScheduler()
 	requestArgs := genRequestArgs([]string{}, 0)
@@ -232,6 +304,160 @@ func TestSchedStatus(t *testing.T) {
 
 }
 
+func TestSchedPause(t *testing.T) {
+	requestArgs := genRequestArgs([]string{"bing.com"}, 0)
+	dataArgs := genDataArgs(10, 2, 1)
+	moduleArgs := genSimpleModuleArgs(3, 2, 1, t)
+	sched := NewScheduler()
+
+	// 测试未启动状态下的暂停与恢复。
+	if err := sched.Pause(); err == nil {
+		t.Fatal("No error when pause scheduler before initialize!")
+	}
+
+	if err := sched.Init(requestArgs, dataArgs, moduleArgs); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+
+	if err := sched.Pause(); err == nil {
+		t.Fatal("No error when pause scheduler after initialize!")
+	}
+
+	if err := sched.Resume(); err == nil {
+		t.Fatal("No error when resume scheduler after initialize!")
+	}
+
+	url := "http://cn.bing.com/search?q=golang"
+	firstHTTPReq, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		t.Fatalf("An error occurs when creating a HTTP request: %s (url: %s)", err, url)
+	}
+
+	if err = sched.Start(firstHTTPReq); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	waitForParkedReqs(sched.(*myScheduler))
+
+	// 测试已启动状态下的暂停。
+	if err = sched.Pause(); err != nil {
+		t.Fatalf("An error occurs when pausing scheduler: %s", err)
+	}
+
+	if status := sched.Status(); status != SCHED_STATUS_PAUSED {
+		t.Fatalf("Inconsistent status: expected: %q, actual: %q",
+			GetStatusDescription(SCHED_STATUS_PAUSED), GetStatusDescription(status))
+	}
+
+	// 测试暂停期间缓冲池中的请求被保留。
+	mySched := sched.(*myScheduler)
+	url = "http://cn.bing.com/images/search?q=golang"
+	httpReq, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		t.Fatalf("An error occurs when creating a HTTP request: %s (url: %s)", err, url)
+	}
+
+	if !mySched.sendReq(module.NewRequest(httpReq, 0)) {
+		t.Fatalf("Couldn't send request in paused scheduler!")
+	}
+
+	time.Sleep(time.Millisecond * 100)
+	if total := mySched.reqBufferPool.Total(); total == 0 {
+		t.Fatalf("The request buffer pool has been drained in paused scheduler!")
+	}
+
+	// 测试重复暂停和已暂停状态下的启动。
+	if err = sched.Pause(); err == nil {
+		t.Fatal("No error when repeatedly pause scheduler!")
+	}
+
+	if err = sched.Start(firstHTTPReq); err == nil {
+		t.Fatal("No error when start scheduler after pause!")
+	}
+
+	// 测试已暂停状态下的恢复。
+	if err = sched.Resume(); err != nil {
+		t.Fatalf("An error occurs when resuming scheduler: %s", err)
+	}
+
+	if status := sched.Status(); status != SCHED_STATUS_STARTED {
+		t.Fatalf("Inconsistent status: expected: %q, actual: %q",
+			GetStatusDescription(SCHED_STATUS_STARTED), GetStatusDescription(status))
+	}
+
+	if err = sched.Resume(); err == nil {
+		t.Fatal("No error when repeatedly resume scheduler!")
+	}
+
+	// 测试已暂停状态下的停止。
+	if err = sched.Pause(); err != nil {
+		t.Fatalf("An error occurs when pausing scheduler: %s", err)
+	}
+
+	if err = sched.Stop(); err != nil {
+		t.Fatalf("An error occurs when stopping paused scheduler: %s", err)
+	}
+}
+
+func TestSchedWorkers(t *testing.T) {
+	// 准备一个响应缓慢的HTTP服务，并记录最大的并发请求数。
+	var current, maxConcurrent int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&current, 1)
+		defer atomic.AddInt32(&current, -1)
+		for {
+			max := atomic.LoadInt32(&maxConcurrent)
+			if n <= max || atomic.CompareAndSwapInt32(&maxConcurrent, max, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond * 200)
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/" {
+			for i := 0; i < 8; i++ {
+				fmt.Fprintf(w, `<a href="/page%d">page</a>`, i)
+			}
+		}
+	}))
+	defer server.Close()
+
+	requestArgs := genRequestArgs([]string{}, 1)
+	dataArgs := genDataArgs(10, 2, 1)
+	dataArgs.DownloadWorkerNumber = 4
+	dataArgs.AnalyzeWorkerNumber = 2
+	dataArgs.PickWorkerNumber = 2
+	moduleArgs := genSimpleModuleArgs(4, 2, 2, t)
+	sched := NewScheduler()
+	if err := sched.Init(requestArgs, dataArgs, moduleArgs); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+
+	mySched := sched.(*myScheduler)
+	if mySched.downloadWorkerNumber != 4 || mySched.analyzeWorkerNumber != 2 || mySched.pickWorkerNumber != 2 {
+		t.Fatalf("Inconsistent worker number: expected: %d/%d/%d, actual: %d/%d/%d", 4, 2, 2,
+			mySched.downloadWorkerNumber, mySched.analyzeWorkerNumber, mySched.pickWorkerNumber)
+	}
+
+	firstHTTPReq, err := http.NewRequest("GET", server.URL+"/", nil)
+	if err != nil {
+		t.Fatalf("An error occurs when creating a HTTP request: %s (url: %s)", err, server.URL)
+	}
+
+	if err = sched.Start(firstHTTPReq); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	defer sched.Stop()
+
+	deadline := time.Now().Add(time.Second * 5)
+	for time.Now().Before(deadline) && mySched.deduper.Len() < 9 {
+		time.Sleep(time.Millisecond * 50)
+	}
+	time.Sleep(time.Millisecond * 500)
+
+	if max := atomic.LoadInt32(&maxConcurrent); max < 2 {
+		t.Fatalf("The requests were not downloaded concurrently! (max concurrent: %d)", max)
+	}
+}
+
 func TestSchedSimple(t *testing.T) {
 	requestArgs := genRequestArgs([]string{}, 0)
 	dataArgs := genDataArgs(10, 2, 1)
@@ -334,9 +560,10 @@ func TestSchedSendReq(t *testing.T) {
 	}
 
 	mySched := sched.(*myScheduler)
-	urlMapLen := mySched.urlMap.Len()
-	if urlMapLen != 1 {
-		t.Fatalf("Inconsistent URL map length: expected: %d, actual: %d", 1, urlMapLen)
+	waitForParkedReqs(mySched)
+	dedupeLen := mySched.deduper.Len()
+	if dedupeLen != 1 {
+		t.Fatalf("Inconsistent deduper length: expected: %d, actual: %d", 1, dedupeLen)
 	}
 
 	// 测试参数无效的情况。
@@ -360,7 +587,7 @@ func TestSchedSendReq(t *testing.T) {
 		t.Fatalf("It still can send repeated request!")
 	}
 
-	mySched.urlMap, _ = cmap.NewConcurrentMap(16, nil)
+	mySched.deduper = dedupe.NewMapDeduper()
 	// 测试scheme不匹配的情况。
 	httpReq.URL.Scheme = "tcp"
 	if mySched.sendReq(req) {
@@ -413,7 +640,7 @@ func TestSendItem(t *testing.T) {
 	// 测试响应无效的情况。
 	item := module.Item(map[string]interface{}{})
 	buffer.Close()
-	done := sendItem(item, buffer)
+	done := sendItem(&pendingItem{item: item}, buffer)
 	runtime.Gosched()
 	if done {
 		t.Fatalf("It still can send item with closed buffer!")
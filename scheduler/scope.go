@@ -0,0 +1,259 @@
+package scheduler
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// ScopeAction 代表范围规则的动作。
+type ScopeAction string
+
+// 范围规则的动作常量。
+const (
+	// SCOPE_ACTION_ALLOW 代表允许匹配的URL。
+	SCOPE_ACTION_ALLOW ScopeAction = "allow"
+	// SCOPE_ACTION_DENY 代表拒绝匹配的URL。
+	SCOPE_ACTION_DENY ScopeAction = "deny"
+)
+
+// ScopeSyntax 代表范围规则中的模式的语法。
+type ScopeSyntax string
+
+// 范围规则的模式语法常量。
+const (
+	// SCOPE_SYNTAX_GLOB 代表通配符语法，"*"匹配任意个字符，"?"匹配单个字符。
+	SCOPE_SYNTAX_GLOB ScopeSyntax = "glob"
+	// SCOPE_SYNTAX_REGEX 代表正则表达式语法，匹配时不会自动添加首尾锚点。
+	SCOPE_SYNTAX_REGEX ScopeSyntax = "regex"
+)
+
+// ScopeRule 代表URL范围规则。
+// 规则中非空的模式必须全部匹配，规则才算匹配。所有模式都为空的规则匹配任何URL。
+type ScopeRule struct {
+	// Action 代表规则匹配时的动作。
+	Action ScopeAction `json:"action"`
+	// Syntax 代表模式的语法，为空时使用通配符语法。
+	Syntax ScopeSyntax `json:"syntax,omitempty"`
+	// Host 代表主机名的模式，主机名不含端口且为小写。
+	Host string `json:"host,omitempty"`
+	// Path 代表路径的模式。
+	Path string `json:"path,omitempty"`
+	// Query 代表查询字符串的模式，不含"?"。
+	Query string `json:"query,omitempty"`
+}
+
+// String 用于获取范围规则的字符串形式。
+func (rule ScopeRule) String() string {
+	parts := []string{string(rule.Action)}
+	if rule.Syntax == SCOPE_SYNTAX_REGEX {
+		parts = append(parts, "regex")
+	}
+	if rule.Host != "" {
+		parts = append(parts, "host="+rule.Host)
+	}
+	if rule.Path != "" {
+		parts = append(parts, "path="+rule.Path)
+	}
+	if rule.Query != "" {
+		parts = append(parts, "query="+rule.Query)
+	}
+	return strings.Join(parts, " ")
+}
+
+// compile 用于编译范围规则中的模式。
+func (rule ScopeRule) compile() (*scopeMatcher, error) {
+	if rule.Action != SCOPE_ACTION_ALLOW && rule.Action != SCOPE_ACTION_DENY {
+		return nil, genParameterError(fmt.Sprintf("unknown scope action %q (rule: %s)", rule.Action, rule))
+	}
+
+	var err error
+	matcher := &scopeMatcher{}
+	patterns := []struct {
+		pattern string
+		re      **regexp.Regexp
+	}{
+		{rule.Host, &matcher.host},
+		{rule.Path, &matcher.path},
+		{rule.Query, &matcher.query},
+	}
+	for _, p := range patterns {
+		if p.pattern == "" {
+			continue
+		}
+
+		switch rule.Syntax {
+		case "", SCOPE_SYNTAX_GLOB:
+			*p.re, err = regexp.Compile(globToRegexp(p.pattern))
+		case SCOPE_SYNTAX_REGEX:
+			*p.re, err = regexp.Compile(p.pattern)
+		default:
+			return nil, genParameterError(fmt.Sprintf("unknown scope syntax %q (rule: %s)", rule.Syntax, rule))
+		}
+
+		if err != nil {
+			return nil, genParameterError(fmt.Sprintf("invalid scope pattern %q: %s (rule: %s)", p.pattern, err, rule))
+		}
+	}
+	return matcher, nil
+}
+
+// globToRegexp 用于把通配符模式转换为完整匹配的正则表达式。
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// scopeMatcher 代表已编译的范围规则的模式。
+type scopeMatcher struct {
+	host  *regexp.Regexp
+	path  *regexp.Regexp
+	query *regexp.Regexp
+}
+
+// match 用于判断给定的URL是否匹配。
+func (matcher *scopeMatcher) match(host string, u *url.URL) bool {
+	if matcher.host != nil && !matcher.host.MatchString(host) {
+		return false
+	}
+
+	if matcher.path != nil {
+		path := u.EscapedPath()
+		if path == "" {
+			path = "/"
+		}
+		if !matcher.path.MatchString(path) {
+			return false
+		}
+	}
+
+	if matcher.query != nil && !matcher.query.MatchString(u.RawQuery) {
+		return false
+	}
+	return true
+}
+
+// ScopeRuleSummaryStruct 代表范围规则的摘要类型。
+type ScopeRuleSummaryStruct struct {
+	Rule string `json:"rule"`
+	Hits uint64 `json:"hits"`
+}
+
+// ScopeSummaryStruct 代表范围规则引擎的摘要类型。
+type ScopeSummaryStruct struct {
+	AllowRules    int                      `json:"allow_rules"`
+	DenyRules     int                      `json:"deny_rules"`
+	DefaultDenied uint64                   `json:"default_denied"`
+	Rules         []ScopeRuleSummaryStruct `json:"rules"`
+}
+
+// Same 用于判断两份范围规则引擎的摘要是否相同。
+func (one *ScopeSummaryStruct) Same(another ScopeSummaryStruct) bool {
+	if another.AllowRules != one.AllowRules ||
+		another.DenyRules != one.DenyRules ||
+		another.DefaultDenied != one.DefaultDenied {
+		return false
+	}
+
+	if len(another.Rules) != len(one.Rules) {
+		return false
+	}
+
+	for i, rs := range another.Rules {
+		if rs != one.Rules[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// scope 代表URL范围规则引擎。
+// 规则按顺序求值，第一条匹配的规则决定URL是否在范围内。
+// 没有规则匹配时，若存在允许规则则拒绝，否则允许。
+type scope struct {
+	// rules 代表范围规则的列表。
+	rules []ScopeRule
+	// matchers 代表与规则一一对应的已编译的模式。
+	matchers []*scopeMatcher
+	// hits 代表与规则一一对应的匹配次数。
+	hits []uint64
+	// allowRules 代表允许规则的数量。
+	allowRules int
+	// defaultDenied 代表因没有规则匹配而被拒绝的次数。
+	defaultDenied uint64
+}
+
+// newScope 用于根据给定的规则创建范围规则引擎。
+func newScope(rules []ScopeRule) (*scope, error) {
+	s := &scope{
+		rules:    rules,
+		matchers: make([]*scopeMatcher, 0, len(rules)),
+		hits:     make([]uint64, len(rules)),
+	}
+
+	for _, rule := range rules {
+		matcher, err := rule.compile()
+		if err != nil {
+			return nil, err
+		}
+		s.matchers = append(s.matchers, matcher)
+		if rule.Action == SCOPE_ACTION_ALLOW {
+			s.allowRules++
+		}
+	}
+	return s, nil
+}
+
+// allowed 用于判断给定的URL是否在范围内。
+func (s *scope) allowed(u *url.URL) bool {
+	if s == nil || len(s.rules) == 0 {
+		return true
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for i, matcher := range s.matchers {
+		if matcher.match(host, u) {
+			atomic.AddUint64(&s.hits[i], 1)
+			return s.rules[i].Action == SCOPE_ACTION_ALLOW
+		}
+	}
+
+	if s.allowRules > 0 {
+		atomic.AddUint64(&s.defaultDenied, 1)
+		return false
+	}
+	return true
+}
+
+// summary 用于获取范围规则引擎的摘要。
+func (s *scope) summary() ScopeSummaryStruct {
+	summary := ScopeSummaryStruct{Rules: []ScopeRuleSummaryStruct{}}
+	if s == nil {
+		return summary
+	}
+
+	summary.AllowRules = s.allowRules
+	summary.DenyRules = len(s.rules) - s.allowRules
+	summary.DefaultDenied = atomic.LoadUint64(&s.defaultDenied)
+	for i, rule := range s.rules {
+		summary.Rules = append(summary.Rules, ScopeRuleSummaryStruct{
+			Rule: rule.String(),
+			Hits: atomic.LoadUint64(&s.hits[i]),
+		})
+	}
+	return summary
+}
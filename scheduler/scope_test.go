@@ -0,0 +1,136 @@
+package scheduler
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"crawler/module"
+)
+
+func TestGlobToRegexp(t *testing.T) {
+	cases := []struct {
+		glob    string
+		s       string
+		matched bool
+	}{
+		{"*.example.com", "docs.example.com", true},
+		{"*.example.com", "example.com", false},
+		{"/api/*", "/api/v1/users", true},
+		{"/api/*", "/apis", false},
+		{"/a?c", "/abc", true},
+		{"/a.c", "/abc", false},
+		{"*sessionid=*", "a=1&sessionid=2", true},
+	}
+
+	for _, c := range cases {
+		matcher, err := ScopeRule{Action: SCOPE_ACTION_ALLOW, Path: c.glob}.compile()
+		if err != nil {
+			t.Fatalf("An error occurs when compiling glob %q: %s", c.glob, err)
+		}
+		if matched := matcher.path.MatchString(c.s); matched != c.matched {
+			t.Fatalf("Inconsistent match result for %q and %q: expected: %v, actual: %v",
+				c.glob, c.s, c.matched, matched)
+		}
+	}
+}
+
+func TestScopeRuleCheck(t *testing.T) {
+	invalidRules := []ScopeRule{
+		{Action: "skip", Path: "/a"},
+		{Action: SCOPE_ACTION_DENY, Syntax: "xpath", Path: "/a"},
+		{Action: SCOPE_ACTION_DENY, Syntax: SCOPE_SYNTAX_REGEX, Path: "(/a"},
+	}
+
+	for _, rule := range invalidRules {
+		requestArgs := genRequestArgs([]string{}, 0)
+		requestArgs.ScopeRules = []ScopeRule{rule}
+		if err := requestArgs.Check(); err == nil {
+			t.Fatalf("No error when checking invalid scope rule %s!", rule)
+		}
+	}
+
+	one := genRequestArgs([]string{}, 0)
+	one.ScopeRules = []ScopeRule{{Action: SCOPE_ACTION_DENY, Path: "/login*"}}
+	another := genRequestArgs([]string{}, 0)
+	another.ScopeRules = []ScopeRule{{Action: SCOPE_ACTION_DENY, Path: "/logout*"}}
+	if one.Same(&another) {
+		t.Fatalf("Inconsistent request args sameness: expected: %v, actual: %v", false, true)
+	}
+}
+
+func TestScope(t *testing.T) {
+	s, err := newScope([]ScopeRule{
+		{Action: SCOPE_ACTION_DENY, Path: "/login*"},
+		{Action: SCOPE_ACTION_DENY, Syntax: SCOPE_SYNTAX_REGEX, Query: `(^|&)sessionid=`},
+		{Action: SCOPE_ACTION_ALLOW, Host: "docs.example.com", Path: "/api/*"},
+	})
+	if err != nil {
+		t.Fatalf("An error occurs when creating scope: %s", err)
+	}
+
+	cases := map[string]bool{
+		"http://docs.example.com/api/v1":                true,
+		"http://DOCS.example.com:8080/api/v1?page=2":    true,
+		"http://docs.example.com/api/v1?sessionid=abc":  false,
+		"http://docs.example.com/login":                 false,
+		"http://docs.example.com/guide":                 false,
+		"http://www.example.com/api/v1":                 false,
+		"http://docs.example.com/api/x?a=1&sessionid=2": false,
+	}
+	for rawURL, expected := range cases {
+		u, _ := url.Parse(rawURL)
+		if allowed := s.allowed(u); allowed != expected {
+			t.Fatalf("Inconsistent scope result for %s: expected: %v, actual: %v", rawURL, expected, allowed)
+		}
+	}
+
+	summary := s.summary()
+	expectedSummary := ScopeSummaryStruct{
+		AllowRules:    1,
+		DenyRules:     2,
+		DefaultDenied: 2,
+		Rules: []ScopeRuleSummaryStruct{
+			{Rule: "deny path=/login*", Hits: 1},
+			{Rule: "deny regex query=(^|&)sessionid=", Hits: 2},
+			{Rule: "allow host=docs.example.com path=/api/*", Hits: 2},
+		},
+	}
+	if !summary.Same(expectedSummary) {
+		t.Fatalf("Inconsistent scope summary: expected: %#v, actual: %#v", expectedSummary, summary)
+	}
+
+	// 只有拒绝规则时，没有规则匹配的URL都会被接受。
+	s, _ = newScope([]ScopeRule{{Action: SCOPE_ACTION_DENY, Host: "*.bing.com"}})
+	u, _ := url.Parse("http://www.sogou.com/")
+	if !s.allowed(u) {
+		t.Fatalf("The URL should be allowed! (URL: %s)", u)
+	}
+}
+
+func TestSchedScope(t *testing.T) {
+	requestArgs := genRequestArgs([]string{"example.com"}, 0)
+	requestArgs.IgnoreRobots = true
+	requestArgs.ScopeRules = []ScopeRule{
+		{Action: SCOPE_ACTION_ALLOW, Host: "docs.example.com"},
+	}
+	sched := NewScheduler()
+	if err := sched.Init(requestArgs, genDataArgs(10, 2, 1), genSimpleModuleArgs(1, 1, 1, t)); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+
+	mySched := sched.(*myScheduler)
+	for rawURL, expected := range map[string]bool{
+		"http://docs.example.com/a": true,
+		"http://www.example.com/a":  false,
+	} {
+		httpReq, _ := http.NewRequest("GET", rawURL, nil)
+		if sent := mySched.sendReq(module.NewRequest(httpReq, 0)); sent != expected {
+			t.Fatalf("Inconsistent send result for %s: expected: %v, actual: %v", rawURL, expected, sent)
+		}
+	}
+
+	if summary := sched.Summary().Struct().Scope; summary.DefaultDenied != 1 || summary.Rules[0].Hits != 1 {
+		t.Fatalf("Inconsistent scope summary: %#v", summary)
+	}
+}
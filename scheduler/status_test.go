@@ -95,6 +95,61 @@ func TestCheckStatus(t *testing.T) {
 	if err := checkStatus(currentStatus, wantedStatus, nil); err != nil {
 		t.Fatalf("检查状态时出错. %s (currentStatus: %q, wantedStatus: %q)", err, GetStatusDescription(currentStatus), GetStatusDescription(wantedStatus))
 	}
+
+	currentStatus = SCHED_STATUS_PAUSED
+	if err := checkStatus(currentStatus, wantedStatus, nil); err != nil {
+		t.Fatalf("检查状态时出错. %s (currentStatus: %q, wantedStatus: %q)", err, GetStatusDescription(currentStatus), GetStatusDescription(wantedStatus))
+	}
+
+	// 6. 只要未处于已启动状态就不能变为正在暂停状态
+	currentStatusList = []Status{
+		SCHED_STATUS_UNINITIALIZED,
+		SCHED_STATUS_INITIALIZED,
+		SCHED_STATUS_PAUSING,
+		SCHED_STATUS_PAUSED,
+		SCHED_STATUS_RESUMING,
+		SCHED_STATUS_STOPPED,
+	}
+
+	wantedStatus = SCHED_STATUS_PAUSING
+	for _, currentStatus := range currentStatusList {
+		if err := checkStatus(currentStatus, wantedStatus, nil); err == nil {
+			t.Fatalf("它仍然可以检查当前状态 %q 和 想要状态 %q", GetStatusDescription(currentStatus), GetStatusDescription(wantedStatus))
+		}
+	}
+
+	currentStatus = SCHED_STATUS_STARTED
+	if err := checkStatus(currentStatus, wantedStatus, nil); err != nil {
+		t.Fatalf("检查状态时出错. %s (currentStatus: %q, wantedStatus: %q)", err, GetStatusDescription(currentStatus), GetStatusDescription(wantedStatus))
+	}
+
+	// 7. 只要未处于已暂停状态就不能变为正在恢复状态
+	currentStatusList = []Status{
+		SCHED_STATUS_UNINITIALIZED,
+		SCHED_STATUS_INITIALIZED,
+		SCHED_STATUS_STARTED,
+		SCHED_STATUS_PAUSING,
+		SCHED_STATUS_STOPPED,
+	}
+
+	wantedStatus = SCHED_STATUS_RESUMING
+	for _, currentStatus := range currentStatusList {
+		if err := checkStatus(currentStatus, wantedStatus, nil); err == nil {
+			t.Fatalf("它仍然可以检查当前状态 %q 和 想要状态 %q", GetStatusDescription(currentStatus), GetStatusDescription(wantedStatus))
+		}
+	}
+
+	currentStatus = SCHED_STATUS_PAUSED
+	if err := checkStatus(currentStatus, wantedStatus, nil); err != nil {
+		t.Fatalf("检查状态时出错. %s (currentStatus: %q, wantedStatus: %q)", err, GetStatusDescription(currentStatus), GetStatusDescription(wantedStatus))
+	}
+
+	// 处于已暂停状态时，不能变为正在初始化和正在启动状态
+	for _, wantedStatus := range []Status{SCHED_STATUS_INITIALIZING, SCHED_STATUS_STARTING} {
+		if err := checkStatus(currentStatus, wantedStatus, nil); err == nil {
+			t.Fatalf("它仍然可以检查当前状态 %q 和 想要状态 %q", GetStatusDescription(currentStatus), GetStatusDescription(wantedStatus))
+		}
+	}
 }
 
 func TestCheckStatusInParallel(t *testing.T) {
@@ -162,7 +217,10 @@ func TestGetStatusDescription(t *testing.T) {
 		SCHED_STATUS_STARTED:       "started",
 		SCHED_STATUS_STOPPING:      "stopping",
 		SCHED_STATUS_STOPPED:       "stopped",
-		Status(7):                  "unknown",
+		SCHED_STATUS_PAUSING:       "pausing",
+		SCHED_STATUS_PAUSED:        "paused",
+		SCHED_STATUS_RESUMING:      "resuming",
+		Status(10):                 "unknown",
 	}
 
 	for status, expectedDesc := range statusMap {
@@ -3,6 +3,7 @@ package scheduler
 import (
 	"crawler/module"
 	"crawler/toolkit/buffer"
+	"crawler/toolkit/dedupe"
 	"encoding/json"
 	"sort"
 )
@@ -29,6 +30,12 @@ type SummaryStruct struct {
 	ItemBufferPool  BufferPoolSummaryStruct `json:"item_buffer_pool"`
 	ErrorBufferPool BufferPoolSummaryStruct `json:"error_buffer_pool"`
 	NumURL          uint64                  `json:"url_number"`
+	Hosts           []HostSummaryStruct     `json:"hosts"`
+	Scope           ScopeSummaryStruct      `json:"scope"`
+	Dedupe          dedupe.SummaryStruct    `json:"dedupe"`
+	Budget          BudgetSummaryStruct     `json:"budget"`
+	StopReason      StopReason              `json:"stop_reason"`
+	Latency         LatencySummaryStruct    `json:"latency"`
 }
 
 // SchedSummary 代表调度器摘要的接口类型。
@@ -90,7 +97,7 @@ func (one *SummaryStruct) Same(another SummaryStruct) bool {
 	}
 
 	for i, ds := range another.Downloaders {
-		if ds != one.Downloaders[i] {
+		if !ds.Same(one.Downloaders[i]) {
 			return false
 		}
 	}
@@ -100,7 +107,7 @@ func (one *SummaryStruct) Same(another SummaryStruct) bool {
 	}
 
 	for i, as := range another.Analyzers {
-		if as != one.Analyzers[i] {
+		if !as.Same(one.Analyzers[i]) {
 			return false
 		}
 	}
@@ -110,7 +117,7 @@ func (one *SummaryStruct) Same(another SummaryStruct) bool {
 	}
 
 	for i, ps := range another.Pipelines {
-		if ps != one.Pipelines[i] {
+		if !ps.Same(one.Pipelines[i]) {
 			return false
 		}
 	}
@@ -135,6 +142,32 @@ func (one *SummaryStruct) Same(another SummaryStruct) bool {
 		return false
 	}
 
+	if len(another.Hosts) != len(one.Hosts) {
+		return false
+	}
+
+	for i, hs := range another.Hosts {
+		if hs != one.Hosts[i] {
+			return false
+		}
+	}
+
+	if !one.Scope.Same(another.Scope) {
+		return false
+	}
+
+	if !one.Dedupe.Same(another.Dedupe) {
+		return false
+	}
+
+	if one.Budget != another.Budget || one.StopReason != another.StopReason {
+		return false
+	}
+
+	if one.Latency != another.Latency {
+		return false
+	}
+
 	return true
 }
 
@@ -152,7 +185,13 @@ func (ss *mySchedSummary) Struct() SummaryStruct {
 		RespBufferPool:  getBufferPoolSummary(ss.sched.respBufferPool),
 		ItemBufferPool:  getBufferPoolSummary(ss.sched.itemBufferPool),
 		ErrorBufferPool: getBufferPoolSummary(ss.sched.errorBufferPool),
-		NumURL:          ss.sched.urlMap.Len(),
+		NumURL:          ss.sched.deduper.Len(),
+		Hosts:           ss.sched.politeness.summary(),
+		Scope:           ss.sched.scope.summary(),
+		Dedupe:          ss.sched.deduper.Summary(),
+		Budget:          ss.sched.budget.summary(),
+		StopReason:      ss.sched.StopReason(),
+		Latency:         ss.sched.latency.summary(),
 	}
 }
 
@@ -180,8 +219,12 @@ func getModuleSummaries(registrar module.Registrar, mType module.Type) []module.
 	moduleMap, _ := registrar.GetAllByType(mType)
 	summaries := []module.SummaryStruct{}
 	if len(moduleMap) > 0 {
-		for _, module := range moduleMap {
-			summaries = append(summaries, module.Summary())
+		for _, m := range moduleMap {
+			summary := m.Summary()
+			if health, ok := registrar.Health(m.ID()); ok {
+				summary.Health = &health
+			}
+			summaries = append(summaries, summary)
 		}
 	}
 	if len(summaries) > 1 {
@@ -169,6 +169,13 @@ func TestSummaryStruct(t *testing.T) {
 	}
 
 	another.NumURL = one.NumURL
+	// 不同的主机摘要。
+	another.Hosts = []HostSummaryStruct{{Host: "cn.bing.com"}}
+	if one.Same(another) {
+		t.Fatalf("Same scheduler summaries with different hosts summary!")
+	}
+
+	another.Hosts = one.Hosts
 	if !one.Same(another) {
 		t.Fatalf("Different scheduler summaries: one: %#v, another: %#v", one, another)
 	}
@@ -188,7 +195,43 @@ func TestSummaryString(t *testing.T) {
 	expectedSummaryStr := `{
     "request_args": {
         "accepted_primary_domains": [],
-        "max_depth": 0
+        "max_depth": 0,
+        "max_redirects": 0,
+        "ignore_seed_domains": false,
+        "host_delay": 0,
+        "host_delay_jitter": 0,
+        "host_max_in_flight": 0,
+        "ignore_robots": false,
+        "robots_user_agent": "",
+        "robots_expiry": 0,
+        "canonical": {
+            "keep_fragment": false,
+            "keep_query_order": false,
+            "remove_params": null
+        },
+        "fingerprint_headers": null,
+        "budget": {
+            "max_pages": 0,
+            "max_bytes": 0,
+            "max_duration": 0,
+            "max_pages_per_domain": 0,
+            "domain_max_pages": null
+        },
+        "dedupe": {
+            "kind": "",
+            "bloom_capacity": 0,
+            "bloom_false_positive_rate": 0,
+            "disk_dir": "",
+            "disk_memory_keys": 0
+        },
+        "scope_rules": null,
+        "retry": {
+            "max_attempts": 0,
+            "backoff_base": 0,
+            "backoff_cap": 0,
+            "retryable_status_codes": null,
+            "retryable_errors": null
+        }
     },
     "data_args": {
         "req_buffer_cap": 10,
@@ -198,12 +241,25 @@ func TestSummaryString(t *testing.T) {
         "item_buffer_cap": 10,
         "item_max_buffer_number": 2,
         "error_buffer_cap": 10,
-        "error_max_buffer_number": 2
+        "error_max_buffer_number": 2,
+        "download_worker_number": 0,
+        "analyze_worker_number": 0,
+        "pick_worker_number": 0
     },
     "module_args": {
         "downloader_list_size": 2,
         "analyzer_list_size": 2,
-        "pipeline_list_size": 1
+        "pipeline_list_size": 1,
+        "health": {
+            "disabled": false,
+            "failure_threshold": 0,
+            "window_size": 0,
+            "max_error_rate": 0,
+            "open_timeout": 0
+        },
+        "downloader_selector": "score",
+        "analyzer_selector": "score",
+        "pipeline_selector": "score"
     },
     "status": "initialized",
     "downloaders": [
@@ -212,14 +268,28 @@ func TestSummaryString(t *testing.T) {
             "called": 0,
             "accepted": 0,
             "completed": 0,
-            "handling": 0
+            "handling": 0,
+            "health": {
+                "state": "closed",
+                "consecutive_failures": 0,
+                "error_rate": 0,
+                "successes": 0,
+                "failures": 0
+            }
         },
         {
             "id": "D2",
             "called": 0,
             "accepted": 0,
             "completed": 0,
-            "handling": 0
+            "handling": 0,
+            "health": {
+                "state": "closed",
+                "consecutive_failures": 0,
+                "error_rate": 0,
+                "successes": 0,
+                "failures": 0
+            }
         }
     ],
     "analyzers": [
@@ -228,14 +298,28 @@ func TestSummaryString(t *testing.T) {
             "called": 0,
             "accepted": 0,
             "completed": 0,
-            "handling": 0
+            "handling": 0,
+            "health": {
+                "state": "closed",
+                "consecutive_failures": 0,
+                "error_rate": 0,
+                "successes": 0,
+                "failures": 0
+            }
         },
         {
             "id": "A4",
             "called": 0,
             "accepted": 0,
             "completed": 0,
-            "handling": 0
+            "handling": 0,
+            "health": {
+                "state": "closed",
+                "consecutive_failures": 0,
+                "error_rate": 0,
+                "successes": 0,
+                "failures": 0
+            }
         }
     ],
     "pipelines": [
@@ -248,6 +332,13 @@ func TestSummaryString(t *testing.T) {
             "extra": {
                 "fail_fast": false,
                 "processor_number": 1
+            },
+            "health": {
+                "state": "closed",
+                "consecutive_failures": 0,
+                "error_rate": 0,
+                "successes": 0,
+                "failures": 0
             }
         }
     ],
@@ -275,7 +366,111 @@ func TestSummaryString(t *testing.T) {
         "buffer_number": 1,
         "total": 0
     },
-    "url_number": 0
+    "url_number": 0,
+    "hosts": [],
+    "scope": {
+        "allow_rules": 0,
+        "deny_rules": 0,
+        "default_denied": 0,
+        "rules": []
+    },
+    "dedupe": {
+        "kind": "map",
+        "keys": 0
+    },
+    "budget": {
+        "pages": 0,
+        "bytes": 0,
+        "capped_domains": 0
+    },
+    "stop_reason": "",
+    "latency": {
+        "dns": {
+            "count": 0,
+            "sum": 0,
+            "max": 0,
+            "buckets": [
+                0,
+                0,
+                0,
+                0,
+                0,
+                0,
+                0,
+                0,
+                0,
+                0
+            ]
+        },
+        "connect": {
+            "count": 0,
+            "sum": 0,
+            "max": 0,
+            "buckets": [
+                0,
+                0,
+                0,
+                0,
+                0,
+                0,
+                0,
+                0,
+                0,
+                0
+            ]
+        },
+        "tls": {
+            "count": 0,
+            "sum": 0,
+            "max": 0,
+            "buckets": [
+                0,
+                0,
+                0,
+                0,
+                0,
+                0,
+                0,
+                0,
+                0,
+                0
+            ]
+        },
+        "ttfb": {
+            "count": 0,
+            "sum": 0,
+            "max": 0,
+            "buckets": [
+                0,
+                0,
+                0,
+                0,
+                0,
+                0,
+                0,
+                0,
+                0,
+                0
+            ]
+        },
+        "total": {
+            "count": 0,
+            "sum": 0,
+            "max": 0,
+            "buckets": [
+                0,
+                0,
+                0,
+                0,
+                0,
+                0,
+                0,
+                0,
+                0,
+                0
+            ]
+        }
+    }
 }`
 	summaryStr := summary.String()
 	if summaryStr != expectedSummaryStr {
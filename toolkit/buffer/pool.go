@@ -182,6 +182,7 @@ func (pool *myPool) getData(buf Buffer, count *uint32, maxCount uint32) (datum i
 		} else {
 			pool.bufCh <- buf
 		}
+		pool.rwlock.RUnlock()
 	}()
 
 	datum, err = buf.Get()
@@ -0,0 +1,195 @@
+package buffer
+
+import (
+	"container/heap"
+	"crawler/errors"
+	"fmt"
+	"sync"
+)
+
+// Prioritized 代表带有优先级的数据的接口类型
+// 优先级的值越大，数据越先被取出
+type Prioritized interface {
+	// Priority 用于获取数据的优先级
+	Priority() int
+}
+
+// priorityEntry 代表优先级缓冲池中的条目
+type priorityEntry struct {
+	// datum 代表数据
+	datum interface{}
+	// priority 代表数据的优先级
+	priority int
+	// seq 代表数据放入的序号，用于保证同优先级的数据先进先出
+	seq uint64
+}
+
+// priorityEntries 代表以堆的方式组织的条目列表
+type priorityEntries []priorityEntry
+
+func (entries priorityEntries) Len() int {
+	return len(entries)
+}
+
+func (entries priorityEntries) Less(i, j int) bool {
+	if entries[i].priority != entries[j].priority {
+		return entries[i].priority > entries[j].priority
+	}
+	return entries[i].seq < entries[j].seq
+}
+
+func (entries priorityEntries) Swap(i, j int) {
+	entries[i], entries[j] = entries[j], entries[i]
+}
+
+func (entries *priorityEntries) Push(x interface{}) {
+	*entries = append(*entries, x.(priorityEntry))
+}
+
+func (entries *priorityEntries) Pop() interface{} {
+	old := *entries
+	n := len(old)
+	entry := old[n-1]
+	old[n-1] = priorityEntry{}
+	*entries = old[:n-1]
+	return entry
+}
+
+// myPriorityPool 代表按优先级取出数据的缓冲池的实现类型
+// 池中最多容纳 bufferCap * maxBufferNumber 个数据
+type myPriorityPool struct {
+	// bufferCap 代表缓冲器的统一容量
+	bufferCap uint32
+	// maxBufferNumber 代表缓冲器的最大数量
+	maxBufferNumber uint32
+	// entries 代表池中的条目
+	entries priorityEntries
+	// seq 代表下一个条目的序号
+	seq uint64
+	// closed 代表缓冲池的关闭状态
+	closed bool
+	// lock 代表保护内部共享资源的互斥锁
+	lock sync.Mutex
+	// notEmpty 代表池非空的条件
+	notEmpty *sync.Cond
+	// notFull 代表池未满的条件
+	notFull *sync.Cond
+}
+
+// NewPriorityPool 用于创建一个按优先级取出数据的缓冲池
+// 实现了 Prioritized 接口的数据按优先级从高到低取出，其他数据的优先级视为0
+// 同优先级的数据按放入的顺序取出
+// 参数的含义与 NewPool 相同，池的总容量为两者之积
+func NewPriorityPool(bufferCap uint32, maxBufferNumber uint32) (Pool, error) {
+	if bufferCap == 0 {
+		errMsg := fmt.Sprintf("缓冲池的非法缓冲上限: %d", bufferCap)
+		return nil, errors.NewIllegalParameterError(errMsg)
+	}
+
+	if maxBufferNumber == 0 {
+		errMsg := fmt.Sprintf("缓冲池的非法最大缓冲区数: %d", maxBufferNumber)
+		return nil, errors.NewIllegalParameterError(errMsg)
+	}
+
+	pool := &myPriorityPool{
+		bufferCap:       bufferCap,
+		maxBufferNumber: maxBufferNumber,
+	}
+	pool.notEmpty = sync.NewCond(&pool.lock)
+	pool.notFull = sync.NewCond(&pool.lock)
+	return pool, nil
+}
+
+func (pool *myPriorityPool) BufferCap() uint32 {
+	return pool.bufferCap
+}
+
+func (pool *myPriorityPool) MaxBufferNumber() uint32 {
+	return pool.maxBufferNumber
+}
+
+// BufferNumber 会按照池中数据的总数折算出缓冲器的数量，最少为1
+func (pool *myPriorityPool) BufferNumber() uint32 {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	number := (uint32(len(pool.entries)) + pool.bufferCap - 1) / pool.bufferCap
+	if number == 0 {
+		number = 1
+	}
+	return number
+}
+
+func (pool *myPriorityPool) Total() uint64 {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	return uint64(len(pool.entries))
+}
+
+// capacity 用于获取池的总容量
+func (pool *myPriorityPool) capacity() int {
+	return int(pool.bufferCap) * int(pool.maxBufferNumber)
+}
+
+func (pool *myPriorityPool) Put(datum interface{}) error {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	for !pool.closed && len(pool.entries) >= pool.capacity() {
+		pool.notFull.Wait()
+	}
+
+	if pool.closed {
+		return ErrClosedBufferPool
+	}
+
+	var priority int
+	if p, ok := datum.(Prioritized); ok {
+		priority = p.Priority()
+	}
+
+	heap.Push(&pool.entries, priorityEntry{datum: datum, priority: priority, seq: pool.seq})
+	pool.seq++
+	pool.notEmpty.Signal()
+	return nil
+}
+
+func (pool *myPriorityPool) Get() (datum interface{}, err error) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	for !pool.closed && len(pool.entries) == 0 {
+		pool.notEmpty.Wait()
+	}
+
+	if pool.closed {
+		return nil, ErrClosedBufferPool
+	}
+
+	entry := heap.Pop(&pool.entries).(priorityEntry)
+	pool.notFull.Signal()
+	return entry.datum, nil
+}
+
+func (pool *myPriorityPool) Close() bool {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	if pool.closed {
+		return false
+	}
+
+	pool.closed = true
+	pool.entries = nil
+	pool.notEmpty.Broadcast()
+	pool.notFull.Broadcast()
+	return true
+}
+
+func (pool *myPriorityPool) Closed() bool {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	return pool.closed
+}
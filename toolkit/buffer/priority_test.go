@@ -0,0 +1,125 @@
+package buffer
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// prioritizedDatum 代表测试用的带有优先级的数据
+type prioritizedDatum struct {
+	id       int
+	priority int
+}
+
+func (datum prioritizedDatum) Priority() int {
+	return datum.priority
+}
+
+func TestPriorityPoolNew(t *testing.T) {
+	pool, err := NewPriorityPool(10, 2)
+	if err != nil {
+		t.Fatalf("新建优先级缓冲池时出错: %s", err)
+	}
+
+	if pool.BufferCap() != 10 || pool.MaxBufferNumber() != 2 {
+		t.Fatalf("缓冲池参数不一致: 预期: %d/%d, 实际: %d/%d", 10, 2, pool.BufferCap(), pool.MaxBufferNumber())
+	}
+
+	if pool.BufferNumber() != 1 {
+		t.Fatalf("缓冲区数不一致: 预期: %d, 实际: %d", 1, pool.BufferNumber())
+	}
+
+	if _, err = NewPriorityPool(0, 1); err == nil {
+		t.Fatalf("新建一个缓冲容量为零的缓冲池时没有错误!")
+	}
+
+	if _, err = NewPriorityPool(1, 0); err == nil {
+		t.Fatalf("新建一个最大缓冲区数为零的缓冲池时没有错误!")
+	}
+}
+
+func TestPriorityPoolOrder(t *testing.T) {
+	pool, _ := NewPriorityPool(2, 5)
+	data := []prioritizedDatum{
+		{id: 0, priority: 0},
+		{id: 1, priority: 5},
+		{id: 2, priority: -1},
+		{id: 3, priority: 5},
+		{id: 4, priority: 0},
+	}
+	for _, datum := range data {
+		if err := pool.Put(datum); err != nil {
+			t.Fatalf("放入数据时出错: %s (数据: %v)", err, datum)
+		}
+	}
+	// 非 Prioritized 的数据的优先级视为0。
+	pool.Put("plain")
+
+	if pool.Total() != 6 || pool.BufferNumber() != 3 {
+		t.Fatalf("数据总数或缓冲区数不一致: 预期: %d/%d, 实际: %d/%d", 6, 3, pool.Total(), pool.BufferNumber())
+	}
+
+	expected := []interface{}{data[1], data[3], data[0], data[4], "plain", data[2]}
+	for i, e := range expected {
+		datum, err := pool.Get()
+		if err != nil {
+			t.Fatalf("获取数据时出错: %s", err)
+		}
+		if datum != e {
+			t.Fatalf("第%d个数据不一致: 预期: %v, 实际: %v", i, e, datum)
+		}
+	}
+}
+
+func TestPriorityPoolBlocking(t *testing.T) {
+	pool, _ := NewPriorityPool(1, 2)
+	pool.Put(1)
+	pool.Put(2)
+
+	sign := addExtraDatum(pool, 3)
+	select {
+	case err := <-sign:
+		t.Fatalf("仍然可以向已满的缓冲池放入数据! (错误: %v)", err)
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	if datum, _ := pool.Get(); datum != 1 {
+		t.Fatalf("数据不一致: 预期: %d, 实际: %v", 1, datum)
+	}
+
+	select {
+	case err := <-sign:
+		if err != nil {
+			t.Fatalf("放入数据时出错: %s", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("放入数据超时!")
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 3; i++ {
+			pool.Get()
+		}
+		if _, err := pool.Get(); err == nil {
+			t.Errorf("仍然可以从已关闭的缓冲池获取数据!")
+		}
+	}()
+
+	time.Sleep(time.Millisecond * 50)
+	if !pool.Close() {
+		t.Fatal("无法关闭缓冲池!")
+	}
+	wg.Wait()
+
+	if pool.Close() {
+		t.Fatal("仍然可以重复关闭缓冲池!")
+	}
+
+	if err := pool.Put(4); err == nil {
+		t.Fatal("仍然可以向已关闭的缓冲池放入数据!")
+	}
+}
@@ -0,0 +1,262 @@
+package dedupe
+
+import (
+	"bytes"
+	"crawler/errors"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sync"
+)
+
+// 可扩展布隆过滤器的参数，见Almeida等人的论文"Scalable Bloom Filters"
+const (
+	// bloomGrowth 代表每个新的过滤器的容量相对于前一个的倍数
+	bloomGrowth = 2
+	// bloomTightening 代表每个新的过滤器的误判率相对于前一个的比例
+	bloomTightening = 0.5
+)
+
+// bloomMagic 代表布隆过滤器状态数据的开头
+var bloomMagic = []byte("BLM1")
+
+// bloomFilter 代表容量固定的布隆过滤器
+type bloomFilter struct {
+	// bits 代表位数组
+	bits []uint64
+	// m 代表位数
+	m uint64
+	// k 代表散列函数的个数
+	k uint32
+	// capacity 代表过滤器的容量
+	capacity uint64
+	// count 代表已添加的键的数量
+	count uint64
+}
+
+// newBloomFilter 用于创建一个能以给定误判率容纳给定数量的键的布隆过滤器
+func newBloomFilter(capacity uint64, falsePositiveRate float64) *bloomFilter {
+	m := uint64(math.Ceil(-float64(capacity) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	if m < 64 {
+		m = 64
+	}
+	k := uint32(math.Ceil(-math.Log2(falsePositiveRate)))
+	if k == 0 {
+		k = 1
+	}
+	return &bloomFilter{
+		bits:     make([]uint64, (m+63)/64),
+		m:        m,
+		k:        k,
+		capacity: capacity,
+	}
+}
+
+// bloomHash 用于计算键的两个散列值，各位置由它们的线性组合得出
+func bloomHash(key string) (uint64, uint64) {
+	h := fnv.New128a()
+	h.Write([]byte(key))
+	sum := h.Sum(nil)
+	return binary.BigEndian.Uint64(sum[:8]), binary.BigEndian.Uint64(sum[8:]) | 1
+}
+
+// contains 用于判断键是否可能已存在
+func (filter *bloomFilter) contains(h1, h2 uint64) bool {
+	for i := uint64(0); i < uint64(filter.k); i++ {
+		pos := (h1 + i*h2) % filter.m
+		if filter.bits[pos/64]&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// add 用于添加键
+func (filter *bloomFilter) add(h1, h2 uint64) {
+	for i := uint64(0); i < uint64(filter.k); i++ {
+		pos := (h1 + i*h2) % filter.m
+		filter.bits[pos/64] |= 1 << (pos % 64)
+	}
+	filter.count++
+}
+
+// falsePositiveRate 用于估算过滤器当前的误判率
+func (filter *bloomFilter) falsePositiveRate() float64 {
+	k := float64(filter.k)
+	return math.Pow(1-math.Exp(-k*float64(filter.count)/float64(filter.m)), k)
+}
+
+// bloomDeduper 代表基于可扩展布隆过滤器的去重器的实现类型
+// 当前过滤器满了之后会添加一个容量更大、误判率更低的过滤器，
+// 以保证总的误判率不超过给定的值
+type bloomDeduper struct {
+	// capacity 代表第一个过滤器的容量
+	capacity uint64
+	// rate 代表总的误判率的上限
+	rate float64
+	// filters 代表过滤器的列表
+	filters []*bloomFilter
+	// count 代表已添加的键的数量
+	count uint64
+	// rwlock 代表读写锁
+	rwlock sync.RWMutex
+}
+
+// NewBloomDeduper 用于创建一个基于可扩展布隆过滤器的去重器
+// 参数capacity代表第一个过滤器的容量，参数falsePositiveRate代表总的误判率的上限
+func NewBloomDeduper(capacity uint64, falsePositiveRate float64) (Deduper, error) {
+	if capacity == 0 {
+		return nil, errors.NewIllegalParameterError("零布隆过滤器容量")
+	}
+
+	if !(falsePositiveRate > 0 && falsePositiveRate < 1) {
+		errMsg := fmt.Sprintf("非法误判率: %v", falsePositiveRate)
+		return nil, errors.NewIllegalParameterError(errMsg)
+	}
+
+	deduper := &bloomDeduper{capacity: capacity, rate: falsePositiveRate}
+	deduper.grow()
+	return deduper, nil
+}
+
+// grow 用于添加一个新的过滤器
+func (deduper *bloomDeduper) grow() {
+	n := len(deduper.filters)
+	capacity := deduper.capacity * uint64(math.Pow(bloomGrowth, float64(n)))
+	rate := deduper.rate * (1 - bloomTightening) * math.Pow(bloomTightening, float64(n))
+	deduper.filters = append(deduper.filters, newBloomFilter(capacity, rate))
+}
+
+func (deduper *bloomDeduper) Add(key string) (bool, error) {
+	h1, h2 := bloomHash(key)
+	deduper.rwlock.Lock()
+	defer deduper.rwlock.Unlock()
+	if deduper.contains(h1, h2) {
+		return false, nil
+	}
+
+	last := deduper.filters[len(deduper.filters)-1]
+	if last.count >= last.capacity {
+		deduper.grow()
+		last = deduper.filters[len(deduper.filters)-1]
+	}
+	last.add(h1, h2)
+	deduper.count++
+	return true, nil
+}
+
+func (deduper *bloomDeduper) Contains(key string) (bool, error) {
+	h1, h2 := bloomHash(key)
+	deduper.rwlock.RLock()
+	defer deduper.rwlock.RUnlock()
+	return deduper.contains(h1, h2), nil
+}
+
+// contains 用于判断键是否可能已存在于任一过滤器中，调用方需持有锁
+func (deduper *bloomDeduper) contains(h1, h2 uint64) bool {
+	for _, filter := range deduper.filters {
+		if filter.contains(h1, h2) {
+			return true
+		}
+	}
+	return false
+}
+
+func (deduper *bloomDeduper) Len() uint64 {
+	deduper.rwlock.RLock()
+	defer deduper.rwlock.RUnlock()
+	return deduper.count
+}
+
+func (deduper *bloomDeduper) Summary() SummaryStruct {
+	deduper.rwlock.RLock()
+	defer deduper.rwlock.RUnlock()
+	summary := SummaryStruct{
+		Kind:    KIND_BLOOM,
+		Keys:    deduper.count,
+		Filters: len(deduper.filters),
+	}
+
+	// 任一过滤器误判都会导致误判。
+	notFalsePositive := 1.0
+	for _, filter := range deduper.filters {
+		summary.Bits += filter.m
+		notFalsePositive *= 1 - filter.falsePositiveRate()
+	}
+	summary.FalsePositiveRate = 1 - notFalsePositive
+	return summary
+}
+
+func (deduper *bloomDeduper) Close() error {
+	return nil
+}
+
+// MarshalBinary 用于把去重器的状态编码为二进制数据
+func (deduper *bloomDeduper) MarshalBinary() ([]byte, error) {
+	deduper.rwlock.RLock()
+	defer deduper.rwlock.RUnlock()
+	var buf bytes.Buffer
+	buf.Write(bloomMagic)
+	fields := []interface{}{
+		deduper.capacity,
+		math.Float64bits(deduper.rate),
+		deduper.count,
+		uint32(len(deduper.filters)),
+	}
+	for _, filter := range deduper.filters {
+		fields = append(fields, filter.m, filter.k, filter.capacity, filter.count, filter.bits)
+	}
+	for _, field := range fields {
+		if err := binary.Write(&buf, binary.BigEndian, field); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary 用于从二进制数据中恢复去重器的状态
+func (deduper *bloomDeduper) UnmarshalBinary(data []byte) error {
+	if !bytes.HasPrefix(data, bloomMagic) {
+		return ErrInvalidState
+	}
+
+	r := bytes.NewReader(data[len(bloomMagic):])
+	var capacity, rateBits, count uint64
+	var n uint32
+	for _, field := range []interface{}{&capacity, &rateBits, &count, &n} {
+		if err := binary.Read(r, binary.BigEndian, field); err != nil {
+			return ErrInvalidState
+		}
+	}
+
+	filters := make([]*bloomFilter, 0, n)
+	for i := uint32(0); i < n; i++ {
+		filter := &bloomFilter{}
+		for _, field := range []interface{}{&filter.m, &filter.k, &filter.capacity, &filter.count} {
+			if err := binary.Read(r, binary.BigEndian, field); err != nil {
+				return ErrInvalidState
+			}
+		}
+		if filter.m == 0 || filter.k == 0 || (filter.m+63)/64 > uint64(r.Len())/8 {
+			return ErrInvalidState
+		}
+		filter.bits = make([]uint64, (filter.m+63)/64)
+		if err := binary.Read(r, binary.BigEndian, filter.bits); err != nil {
+			return ErrInvalidState
+		}
+		filters = append(filters, filter)
+	}
+
+	if len(filters) == 0 || r.Len() != 0 {
+		return ErrInvalidState
+	}
+
+	deduper.rwlock.Lock()
+	defer deduper.rwlock.Unlock()
+	deduper.capacity = capacity
+	deduper.rate = math.Float64frombits(rateBits)
+	deduper.count = count
+	deduper.filters = filters
+	return nil
+}
@@ -0,0 +1,87 @@
+package dedupe
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestNewBloomDeduper(t *testing.T) {
+	if _, err := NewBloomDeduper(0, 0.01); err == nil {
+		t.Fatal("No error when creating bloom deduper with zero capacity!")
+	}
+
+	for _, rate := range []float64{0, 1, -0.1, 2} {
+		if _, err := NewBloomDeduper(100, rate); err == nil {
+			t.Fatalf("No error when creating bloom deduper with false positive rate %v!", rate)
+		}
+	}
+}
+
+func TestBloomDeduper(t *testing.T) {
+	deduper, err := NewBloomDeduper(1000, 0.01)
+	if err != nil {
+		t.Fatalf("An error occurs when creating bloom deduper: %s", err)
+	}
+	defer deduper.Close()
+
+	testDeduper(t, deduper, 1000)
+
+	// 超出第一个过滤器的容量后会添加新的过滤器。
+	for i := 1000; i < 10000; i++ {
+		deduper.Add(fmt.Sprintf("key-%d", i))
+	}
+	summary := deduper.Summary()
+	if summary.Kind != KIND_BLOOM || summary.Filters < 2 || summary.Bits == 0 {
+		t.Fatalf("Inconsistent bloom deduper summary: %#v", summary)
+	}
+	if summary.FalsePositiveRate <= 0 || summary.FalsePositiveRate > 0.01 {
+		t.Fatalf("The estimated false positive rate %v should be in (0, 0.01]!", summary.FalsePositiveRate)
+	}
+
+	// 实际的误判率不应明显超过给定的上限。
+	falsePositives := 0
+	for i := 0; i < 10000; i++ {
+		if exists, _ := deduper.Contains(fmt.Sprintf("absent-%d", i)); exists {
+			falsePositives++
+		}
+	}
+	if rate := float64(falsePositives) / 10000; rate > 0.02 {
+		t.Fatalf("The false positive rate is too high: %v", rate)
+	}
+
+	testParallel(t, deduper, false)
+}
+
+func TestBloomDeduperBinary(t *testing.T) {
+	deduper, _ := NewBloomDeduper(100, 0.001)
+	for i := 0; i < 300; i++ {
+		deduper.Add(fmt.Sprintf("key-%d", i))
+	}
+
+	data, err := deduper.(*bloomDeduper).MarshalBinary()
+	if err != nil {
+		t.Fatalf("An error occurs when marshaling bloom deduper: %s", err)
+	}
+
+	restored, _ := NewBloomDeduper(1, 0.5)
+	if err = restored.(*bloomDeduper).UnmarshalBinary(data); err != nil {
+		t.Fatalf("An error occurs when unmarshaling bloom deduper: %s", err)
+	}
+
+	summary := restored.Summary()
+	if !summary.Same(deduper.Summary()) {
+		t.Fatalf("Inconsistent summary: expected: %#v, actual: %#v", deduper.Summary(), summary)
+	}
+
+	for i := 0; i < 300; i++ {
+		if exists, _ := restored.Contains(fmt.Sprintf("key-%d", i)); !exists {
+			t.Fatalf("The key %q should exist after restore!", fmt.Sprintf("key-%d", i))
+		}
+	}
+
+	for _, invalid := range [][]byte{nil, []byte("BLM1"), data[:len(data)-1], append(data, 0)} {
+		if err = restored.(*bloomDeduper).UnmarshalBinary(invalid); err != ErrInvalidState {
+			t.Fatalf("Inconsistent error for invalid state: expected: %v, actual: %v", ErrInvalidState, err)
+		}
+	}
+}
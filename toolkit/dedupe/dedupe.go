@@ -0,0 +1,104 @@
+package dedupe
+
+import (
+	"crawler/cmap"
+)
+
+// Kind 代表去重器的种类
+type Kind string
+
+// 去重器的种类常量
+const (
+	// KIND_MAP 代表基于并发安全字典的去重器，精确但会在内存中保存所有的键
+	KIND_MAP Kind = "map"
+	// KIND_BLOOM 代表基于可扩展布隆过滤器的去重器，占用内存少但存在误判
+	KIND_BLOOM Kind = "bloom"
+	// KIND_DISK 代表基于磁盘上的有序文件的去重器，精确且内存中只保存少量的键
+	KIND_DISK Kind = "disk"
+)
+
+// Deduper 代表去重器的接口类型
+// 去重器用于记录已处理过的键，其实现必须是并发安全的
+type Deduper interface {
+	// Add 用于添加键，若键之前不存在则返回true
+	// 对于存在误判的实现，返回false也可能意味着键被误判为已存在
+	Add(key string) (bool, error)
+	// Contains 用于判断键是否已存在
+	Contains(key string) (bool, error)
+	// Len 用于获取已添加的键的数量
+	Len() uint64
+	// Summary 用于获取去重器的摘要
+	Summary() SummaryStruct
+	// Close 用于关闭去重器并释放其占用的资源
+	Close() error
+}
+
+// Ranger 代表可以遍历已添加的键的去重器的接口类型
+type Ranger interface {
+	// Range 会依次以每个键调用参数fn，若fn返回false则停止遍历
+	Range(fn func(key string) bool) error
+}
+
+// SummaryStruct 代表去重器的摘要类型
+type SummaryStruct struct {
+	// Kind 代表去重器的种类
+	Kind Kind `json:"kind"`
+	// Keys 代表已添加的键的数量
+	Keys uint64 `json:"keys"`
+	// Filters 代表布隆过滤器的数量
+	Filters int `json:"filters,omitempty"`
+	// Bits 代表布隆过滤器占用的位数
+	Bits uint64 `json:"bits,omitempty"`
+	// FalsePositiveRate 代表估算的当前误判率
+	FalsePositiveRate float64 `json:"false_positive_rate,omitempty"`
+	// MemoryKeys 代表保存在内存中的键的数量
+	MemoryKeys int `json:"memory_keys,omitempty"`
+	// Runs 代表磁盘上的有序文件的数量
+	Runs int `json:"runs,omitempty"`
+	// DiskBytes 代表磁盘上的有序文件的总大小
+	DiskBytes int64 `json:"disk_bytes,omitempty"`
+}
+
+// Same 用于判断两个去重器摘要是否相同
+func (one *SummaryStruct) Same(another SummaryStruct) bool {
+	return *one == another
+}
+
+// mapDeduper 代表基于并发安全字典的去重器的实现类型
+type mapDeduper struct {
+	// m 代表保存键的字典
+	m cmap.ConcurrentMap
+}
+
+// NewMapDeduper 用于创建一个基于并发安全字典的去重器
+func NewMapDeduper() Deduper {
+	m, _ := cmap.NewConcurrentMap(16, nil)
+	return &mapDeduper{m: m}
+}
+
+func (deduper *mapDeduper) Add(key string) (bool, error) {
+	return deduper.m.Put(key, struct{}{})
+}
+
+func (deduper *mapDeduper) Contains(key string) (bool, error) {
+	return deduper.m.Get(key) != nil, nil
+}
+
+func (deduper *mapDeduper) Len() uint64 {
+	return deduper.m.Len()
+}
+
+func (deduper *mapDeduper) Range(fn func(key string) bool) error {
+	deduper.m.Range(func(key string, element interface{}) bool {
+		return fn(key)
+	})
+	return nil
+}
+
+func (deduper *mapDeduper) Summary() SummaryStruct {
+	return SummaryStruct{Kind: KIND_MAP, Keys: deduper.m.Len()}
+}
+
+func (deduper *mapDeduper) Close() error {
+	return nil
+}
@@ -0,0 +1,128 @@
+package dedupe
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// testDeduper 用于测试去重器的基本功能。
+func testDeduper(t *testing.T, deduper Deduper, number int) {
+	for i := 0; i < number; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		added, err := deduper.Add(key)
+		if err != nil {
+			t.Fatalf("An error occurs when adding key %q: %s", key, err)
+		}
+		if !added {
+			t.Fatalf("The key %q should be added!", key)
+		}
+	}
+
+	for i := 0; i < number; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		if exists, err := deduper.Contains(key); err != nil || !exists {
+			t.Fatalf("The key %q should exist! (error: %v)", key, err)
+		}
+		if added, err := deduper.Add(key); err != nil || added {
+			t.Fatalf("The key %q should not be added again! (error: %v)", key, err)
+		}
+	}
+
+	if n := deduper.Len(); n != uint64(number) {
+		t.Fatalf("Inconsistent deduper length: expected: %d, actual: %d", number, n)
+	}
+
+	if summary := deduper.Summary(); summary.Keys != uint64(number) {
+		t.Fatalf("Inconsistent key number in summary: expected: %d, actual: %d", number, summary.Keys)
+	}
+}
+
+// testRanger 用于测试去重器的遍历功能。
+func testRanger(t *testing.T, deduper Deduper, number int) {
+	ranger, ok := deduper.(Ranger)
+	if !ok {
+		t.Fatalf("The deduper %T should be a ranger!", deduper)
+	}
+
+	var keys []string
+	if err := ranger.Range(func(key string) bool {
+		keys = append(keys, key)
+		return true
+	}); err != nil {
+		t.Fatalf("An error occurs when ranging deduper: %s", err)
+	}
+
+	if len(keys) != number {
+		t.Fatalf("Inconsistent key number in range: expected: %d, actual: %d", number, len(keys))
+	}
+
+	sort.Strings(keys)
+	for i := 1; i < len(keys); i++ {
+		if keys[i] == keys[i-1] {
+			t.Fatalf("Repeated key in range: %q", keys[i])
+		}
+	}
+
+	count := 0
+	ranger.Range(func(key string) bool {
+		count++
+		return count < 3
+	})
+	if count != 3 {
+		t.Fatalf("The range should stop when fn returns false: count: %d", count)
+	}
+}
+
+// testParallel 用于测试去重器的并发安全性。
+// 对于存在误判的去重器，参数exact应为false，此时只要求添加成功的次数不超过键的数量。
+func testParallel(t *testing.T, deduper Deduper, exact bool) {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	added := 0
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				ok, err := deduper.Add(fmt.Sprintf("parallel-%d", i))
+				if err != nil {
+					t.Errorf("An error occurs when adding key: %s", err)
+					return
+				}
+				if ok {
+					mu.Lock()
+					added++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if added > 500 || (exact && added != 500) {
+		t.Fatalf("Inconsistent added number: expected: %d, actual: %d", 500, added)
+	}
+}
+
+func TestMapDeduper(t *testing.T) {
+	deduper := NewMapDeduper()
+	defer deduper.Close()
+	testDeduper(t, deduper, 1000)
+	testRanger(t, deduper, 1000)
+	if kind := deduper.Summary().Kind; kind != KIND_MAP {
+		t.Fatalf("Inconsistent deduper kind: expected: %s, actual: %s", KIND_MAP, kind)
+	}
+	testParallel(t, NewMapDeduper(), true)
+}
+
+func TestSummarySame(t *testing.T) {
+	one := SummaryStruct{Kind: KIND_DISK, Keys: 3, Runs: 1}
+	if !one.Same(SummaryStruct{Kind: KIND_DISK, Keys: 3, Runs: 1}) {
+		t.Fatal("The summaries should be the same!")
+	}
+	if one.Same(SummaryStruct{Kind: KIND_DISK, Keys: 3, Runs: 2}) {
+		t.Fatal("The summaries should not be the same!")
+	}
+}
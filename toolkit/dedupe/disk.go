@@ -0,0 +1,445 @@
+package dedupe
+
+import (
+	"bufio"
+	"container/heap"
+	"crawler/errors"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+const (
+	// diskIndexInterval 代表有序文件的稀疏索引中相邻两项之间的键的数量
+	diskIndexInterval = 64
+	// diskMaxRuns 代表触发合并之前有序文件的最大数量
+	diskMaxRuns = 8
+	// diskRunFalsePositiveRate 代表每个有序文件的布隆过滤器的误判率
+	// 布隆过滤器只用于减少磁盘读取，误判不会导致错误的结果
+	diskRunFalsePositiveRate = 0.01
+)
+
+// diskIndexEntry 代表有序文件的稀疏索引中的一项
+type diskIndexEntry struct {
+	// key 代表该位置上的键
+	key string
+	// offset 代表该键在文件中的偏移量
+	offset int64
+}
+
+// diskRun 代表磁盘上的有序文件
+// 文件由按升序排列的记录组成，每条记录是变长编码的键长度加上键本身
+type diskRun struct {
+	// file 代表已打开的文件
+	file *os.File
+	// size 代表文件的大小
+	size int64
+	// count 代表文件中的键的数量
+	count uint64
+	// index 代表稀疏索引
+	index []diskIndexEntry
+	// filter 代表文件中的键的布隆过滤器
+	filter *bloomFilter
+}
+
+// diskRunWriter 代表有序文件的写入器
+type diskRunWriter struct {
+	run *diskRun
+	w   *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+}
+
+// newDiskRunWriter 用于在给定的路径上创建有序文件并返回其写入器
+// 参数count代表将要写入的键的数量，用于确定布隆过滤器的大小
+func newDiskRunWriter(path string, count uint64) (*diskRunWriter, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	if count == 0 {
+		count = 1
+	}
+	return &diskRunWriter{
+		run: &diskRun{file: file, filter: newBloomFilter(count, diskRunFalsePositiveRate)},
+		w:   bufio.NewWriter(file),
+	}, nil
+}
+
+// write 用于写入一个键，键必须比之前写入的键大
+func (writer *diskRunWriter) write(key string) error {
+	run := writer.run
+	if run.count%diskIndexInterval == 0 {
+		run.index = append(run.index, diskIndexEntry{key: key, offset: run.size})
+	}
+
+	n := binary.PutUvarint(writer.buf[:], uint64(len(key)))
+	if _, err := writer.w.Write(writer.buf[:n]); err != nil {
+		return err
+	}
+	if _, err := writer.w.WriteString(key); err != nil {
+		return err
+	}
+
+	run.size += int64(n + len(key))
+	run.count++
+	run.filter.add(bloomHash(key))
+	return nil
+}
+
+// finish 用于完成写入并返回有序文件
+func (writer *diskRunWriter) finish() (*diskRun, error) {
+	if err := writer.w.Flush(); err != nil {
+		writer.abort()
+		return nil, err
+	}
+	return writer.run, nil
+}
+
+// abort 用于放弃写入并删除文件
+func (writer *diskRunWriter) abort() {
+	writer.run.remove()
+}
+
+// remove 用于关闭并删除有序文件
+func (run *diskRun) remove() error {
+	run.file.Close()
+	return os.Remove(run.file.Name())
+}
+
+// contains 用于判断键是否存在于有序文件中
+func (run *diskRun) contains(key string) (bool, error) {
+	if run.count == 0 || !run.filter.contains(bloomHash(key)) {
+		return false, nil
+	}
+
+	// 找到最后一个不大于key的索引项，键只可能位于它之后的一段记录中。
+	i := sort.Search(len(run.index), func(i int) bool {
+		return run.index[i].key > key
+	}) - 1
+	if i < 0 {
+		return false, nil
+	}
+
+	end := run.size
+	if i+1 < len(run.index) {
+		end = run.index[i+1].offset
+	}
+	block := make([]byte, end-run.index[i].offset)
+	if _, err := run.file.ReadAt(block, run.index[i].offset); err != nil {
+		return false, err
+	}
+
+	for len(block) > 0 {
+		l, n := binary.Uvarint(block)
+		if n <= 0 || uint64(len(block)-n) < l {
+			return false, fmt.Errorf("corrupted deduper file %s", run.file.Name())
+		}
+		current := string(block[n : n+int(l)])
+		if current == key {
+			return true, nil
+		}
+		if current > key {
+			return false, nil
+		}
+		block = block[n+int(l):]
+	}
+	return false, nil
+}
+
+// diskRunReader 代表有序文件的顺序读取器
+type diskRunReader struct {
+	r   *bufio.Reader
+	key string
+	err error
+}
+
+// newDiskRunReader 用于创建有序文件的顺序读取器
+func newDiskRunReader(run *diskRun) *diskRunReader {
+	return &diskRunReader{r: bufio.NewReader(io.NewSectionReader(run.file, 0, run.size))}
+}
+
+// next 用于读取下一个键，没有更多的键时返回false
+func (reader *diskRunReader) next() bool {
+	l, err := binary.ReadUvarint(reader.r)
+	if err != nil {
+		if err != io.EOF {
+			reader.err = err
+		}
+		return false
+	}
+
+	b := make([]byte, l)
+	if _, err = io.ReadFull(reader.r, b); err != nil {
+		reader.err = err
+		return false
+	}
+	reader.key = string(b)
+	return true
+}
+
+// diskRunHeap 代表用于合并有序文件的最小堆
+type diskRunHeap []*diskRunReader
+
+func (h diskRunHeap) Len() int            { return len(h) }
+func (h diskRunHeap) Less(i, j int) bool  { return h[i].key < h[j].key }
+func (h diskRunHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *diskRunHeap) Push(x interface{}) { *h = append(*h, x.(*diskRunReader)) }
+func (h *diskRunHeap) Pop() interface{} {
+	old := *h
+	reader := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return reader
+}
+
+// diskDeduper 代表基于磁盘上的有序文件的去重器的实现类型
+// 新的键先保存在内存中，数量达到上限后被排序并写入一个新的有序文件，
+// 有序文件过多时会被合并为一个
+type diskDeduper struct {
+	// dir 代表保存有序文件的目录，该目录由去重器创建并在关闭时删除
+	dir string
+	// memoryKeys 代表内存中的键的数量上限
+	memoryKeys int
+	// memory 代表内存中的键
+	memory map[string]struct{}
+	// runs 代表磁盘上的有序文件
+	runs []*diskRun
+	// count 代表已添加的键的数量
+	count uint64
+	// seq 代表用于生成文件名的序号
+	seq uint64
+	// closed 代表去重器是否已关闭
+	closed bool
+	// rwlock 代表读写锁
+	rwlock sync.RWMutex
+}
+
+// NewDiskDeduper 用于创建一个基于磁盘上的有序文件的去重器
+// 参数dir代表用于创建工作目录的父目录，为空时使用系统的临时目录
+// 参数memoryKeys代表内存中的键的数量上限
+func NewDiskDeduper(dir string, memoryKeys int) (Deduper, error) {
+	if memoryKeys <= 0 {
+		errMsg := fmt.Sprintf("非法内存键数量: %d", memoryKeys)
+		return nil, errors.NewIllegalParameterError(errMsg)
+	}
+
+	workDir, err := ioutil.TempDir(dir, "dedupe")
+	if err != nil {
+		return nil, err
+	}
+
+	return &diskDeduper{
+		dir:        workDir,
+		memoryKeys: memoryKeys,
+		memory:     make(map[string]struct{}),
+	}, nil
+}
+
+func (deduper *diskDeduper) Add(key string) (bool, error) {
+	deduper.rwlock.Lock()
+	defer deduper.rwlock.Unlock()
+	if deduper.closed {
+		return false, ErrClosedDeduper
+	}
+
+	exists, err := deduper.contains(key)
+	if err != nil || exists {
+		return false, err
+	}
+
+	deduper.memory[key] = struct{}{}
+	deduper.count++
+	if len(deduper.memory) >= deduper.memoryKeys {
+		if err = deduper.flush(); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+func (deduper *diskDeduper) Contains(key string) (bool, error) {
+	deduper.rwlock.RLock()
+	defer deduper.rwlock.RUnlock()
+	if deduper.closed {
+		return false, ErrClosedDeduper
+	}
+	return deduper.contains(key)
+}
+
+// contains 用于判断键是否已存在，调用方需持有锁
+func (deduper *diskDeduper) contains(key string) (bool, error) {
+	if _, ok := deduper.memory[key]; ok {
+		return true, nil
+	}
+
+	for _, run := range deduper.runs {
+		exists, err := run.contains(key)
+		if err != nil || exists {
+			return exists, err
+		}
+	}
+	return false, nil
+}
+
+// nextRunPath 用于生成下一个有序文件的路径
+func (deduper *diskDeduper) nextRunPath() string {
+	deduper.seq++
+	return filepath.Join(deduper.dir, fmt.Sprintf("run-%08d", deduper.seq))
+}
+
+// flush 用于把内存中的键写入一个新的有序文件，调用方需持有写锁
+func (deduper *diskDeduper) flush() error {
+	keys := make([]string, 0, len(deduper.memory))
+	for key := range deduper.memory {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	writer, err := newDiskRunWriter(deduper.nextRunPath(), uint64(len(keys)))
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if err = writer.write(key); err != nil {
+			writer.abort()
+			return err
+		}
+	}
+
+	run, err := writer.finish()
+	if err != nil {
+		return err
+	}
+	deduper.runs = append(deduper.runs, run)
+	deduper.memory = make(map[string]struct{})
+
+	if len(deduper.runs) > diskMaxRuns {
+		return deduper.compact()
+	}
+	return nil
+}
+
+// compact 用于把所有的有序文件合并为一个，调用方需持有写锁
+func (deduper *diskDeduper) compact() error {
+	var count uint64
+	h := make(diskRunHeap, 0, len(deduper.runs))
+	for _, run := range deduper.runs {
+		count += run.count
+		reader := newDiskRunReader(run)
+		if reader.next() {
+			h = append(h, reader)
+		} else if reader.err != nil {
+			return reader.err
+		}
+	}
+	heap.Init(&h)
+
+	writer, err := newDiskRunWriter(deduper.nextRunPath(), count)
+	if err != nil {
+		return err
+	}
+
+	for h.Len() > 0 {
+		reader := h[0]
+		if err = writer.write(reader.key); err != nil {
+			writer.abort()
+			return err
+		}
+		if reader.next() {
+			heap.Fix(&h, 0)
+		} else {
+			if reader.err != nil {
+				writer.abort()
+				return reader.err
+			}
+			heap.Pop(&h)
+		}
+	}
+
+	run, err := writer.finish()
+	if err != nil {
+		return err
+	}
+
+	for _, old := range deduper.runs {
+		old.remove()
+	}
+	deduper.runs = []*diskRun{run}
+	return nil
+}
+
+func (deduper *diskDeduper) Len() uint64 {
+	deduper.rwlock.RLock()
+	defer deduper.rwlock.RUnlock()
+	return deduper.count
+}
+
+func (deduper *diskDeduper) Range(fn func(key string) bool) error {
+	deduper.rwlock.RLock()
+	defer deduper.rwlock.RUnlock()
+	if deduper.closed {
+		return ErrClosedDeduper
+	}
+
+	for key := range deduper.memory {
+		if !fn(key) {
+			return nil
+		}
+	}
+
+	for _, run := range deduper.runs {
+		reader := newDiskRunReader(run)
+		for reader.next() {
+			if !fn(reader.key) {
+				return nil
+			}
+		}
+		if reader.err != nil {
+			return reader.err
+		}
+	}
+	return nil
+}
+
+func (deduper *diskDeduper) Summary() SummaryStruct {
+	deduper.rwlock.RLock()
+	defer deduper.rwlock.RUnlock()
+	summary := SummaryStruct{
+		Kind:       KIND_DISK,
+		Keys:       deduper.count,
+		MemoryKeys: len(deduper.memory),
+		Runs:       len(deduper.runs),
+	}
+	for _, run := range deduper.runs {
+		summary.DiskBytes += run.size
+	}
+	return summary
+}
+
+func (deduper *diskDeduper) Close() error {
+	deduper.rwlock.Lock()
+	defer deduper.rwlock.Unlock()
+	if deduper.closed {
+		return nil
+	}
+
+	deduper.closed = true
+	for _, run := range deduper.runs {
+		run.file.Close()
+	}
+	deduper.runs = nil
+	deduper.memory = nil
+	return os.RemoveAll(deduper.dir)
+}
+
+// 确保各实现类型满足相应的接口。
+var (
+	_ Ranger = (*mapDeduper)(nil)
+	_ Ranger = (*diskDeduper)(nil)
+)
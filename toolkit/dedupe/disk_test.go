@@ -0,0 +1,57 @@
+package dedupe
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestNewDiskDeduper(t *testing.T) {
+	if _, err := NewDiskDeduper(t.TempDir(), 0); err == nil {
+		t.Fatal("No error when creating disk deduper with zero memory keys!")
+	}
+
+	if _, err := NewDiskDeduper("/nonexistent/dir", 10); err == nil {
+		t.Fatal("No error when creating disk deduper in a missing directory!")
+	}
+}
+
+func TestDiskDeduper(t *testing.T) {
+	deduper, err := NewDiskDeduper(t.TempDir(), 100)
+	if err != nil {
+		t.Fatalf("An error occurs when creating disk deduper: %s", err)
+	}
+
+	// 键的数量足以触发多次写入和合并。
+	number := 100*(diskMaxRuns+3) + 17
+	testDeduper(t, deduper, number)
+	testRanger(t, deduper, number)
+
+	summary := deduper.Summary()
+	if summary.Kind != KIND_DISK || summary.Runs == 0 || summary.Runs > diskMaxRuns ||
+		summary.MemoryKeys >= 100 || summary.DiskBytes == 0 {
+		t.Fatalf("Inconsistent disk deduper summary: %#v", summary)
+	}
+
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("absent-%d", i)
+		if exists, err := deduper.Contains(key); err != nil || exists {
+			t.Fatalf("The key %q should not exist! (error: %v)", key, err)
+		}
+	}
+
+	testParallel(t, deduper, true)
+
+	dir := deduper.(*diskDeduper).dir
+	if err = deduper.Close(); err != nil {
+		t.Fatalf("An error occurs when closing disk deduper: %s", err)
+	}
+
+	if _, err = os.Stat(dir); !os.IsNotExist(err) {
+		t.Fatalf("The work directory %s should be removed after closing!", dir)
+	}
+
+	if _, err = deduper.Add("key"); err != ErrClosedDeduper {
+		t.Fatalf("Inconsistent error after closing: expected: %v, actual: %v", ErrClosedDeduper, err)
+	}
+}
@@ -0,0 +1,9 @@
+package dedupe
+
+import "errors"
+
+// ErrClosedDeduper 表示去重器已关闭的错误的变量
+var ErrClosedDeduper = errors.New("closed deduper")
+
+// ErrInvalidState 表示无法识别的去重器状态数据的错误的变量
+var ErrInvalidState = errors.New("invalid deduper state")
@@ -0,0 +1,203 @@
+package publicsuffix
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// listData 代表内嵌的公共后缀列表
+// 来源于 https://publicsuffix.org/list/public_suffix_list.dat
+//go:embed public_suffix_list.dat
+var listData []byte
+
+// ruleKind 代表规则的种类
+type ruleKind uint8
+
+// 规则种类常量
+const (
+	// ruleNormal 代表普通规则，如 "co.uk"
+	ruleNormal ruleKind = 1 << iota
+	// ruleWildcard 代表通配规则，如 "*.ck"，存储时去掉了 "*." 前缀
+	ruleWildcard
+	// ruleException 代表例外规则，如 "!www.ck"，存储时去掉了 "!" 前缀
+	ruleException
+)
+
+// errorf 用于生成公共后缀相关的错误
+func errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("publicsuffix: "+format, args...)
+}
+
+// List 代表公共后缀列表
+type List struct {
+	// rules 代表规则与其种类的映射，规则均为小写的ASCII形式
+	rules map[string]ruleKind
+}
+
+// Parse 用于从给定的读取器解析公共后缀列表
+// 列表的格式参见 https://publicsuffix.org/list/
+func Parse(reader io.Reader) (*List, error) {
+	list := &List{rules: map[string]ruleKind{}}
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+
+		// 规则以第一个空白字符结束
+		if fields := strings.Fields(line); len(fields) > 0 {
+			line = fields[0]
+		}
+
+		kind := ruleNormal
+		switch {
+		case strings.HasPrefix(line, "!"):
+			kind = ruleException
+			line = line[1:]
+		case strings.HasPrefix(line, "*."):
+			kind = ruleWildcard
+			line = line[2:]
+		}
+
+		rule, err := ToASCII(line)
+		if err != nil || rule == "" {
+			continue
+		}
+		list.rules[rule] |= kind
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(list.rules) == 0 {
+		return nil, errorf("empty list")
+	}
+	return list, nil
+}
+
+// LoadFile 用于从给定的文件加载公共后缀列表
+func LoadFile(filePath string) (*List, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Parse(file)
+}
+
+// Len 用于获取列表中规则的数量
+func (list *List) Len() int {
+	return len(list.rules)
+}
+
+// PublicSuffix 用于获取给定域名的公共后缀
+// 第二个结果值代表后缀是否由列表中的规则明确给出，
+// 若为false，则说明使用了默认规则 "*"，即把顶级域名视为公共后缀
+// 参数domain应该是小写的ASCII形式
+func (list *List) PublicSuffix(domain string) (suffix string, explicit bool) {
+	labels := strings.Split(domain, ".")
+	n := len(labels)
+	// matched 代表匹配到的后缀的标签数
+	matched := 0
+	for i := n - 1; i >= 0; i-- {
+		candidate := strings.Join(labels[i:], ".")
+		kind := list.rules[candidate]
+		if kind&ruleException != 0 {
+			// 例外规则优先，其公共后缀为去掉最左侧标签后的部分
+			return strings.Join(labels[i+1:], "."), true
+		}
+
+		if kind&ruleNormal != 0 {
+			matched = n - i
+		}
+
+		if i > 0 && kind&ruleWildcard != 0 {
+			matched = n - i + 1
+		}
+	}
+
+	if matched == 0 {
+		return labels[n-1], false
+	}
+
+	return strings.Join(labels[n-matched:], "."), true
+}
+
+// EffectiveTLDPlusOne 用于获取给定域名的有效顶级域名加一级，即主域名
+// 与公共后缀列表的算法一致，顶级域名不在列表中时使用默认规则 "*"，如intranet.corp的主域名为其本身
+// 若域名本身就是公共后缀，则返回错误
+// 参数domain应该是小写的ASCII形式
+func (list *List) EffectiveTLDPlusOne(domain string) (string, error) {
+	if domain == "" || strings.HasPrefix(domain, ".") ||
+		strings.HasSuffix(domain, ".") || strings.Contains(domain, "..") {
+		return "", errorf("invalid domain %q", domain)
+	}
+
+	suffix, _ := list.PublicSuffix(domain)
+	if len(domain) <= len(suffix) {
+		return "", errorf("domain %q is a public suffix", domain)
+	}
+
+	i := len(domain) - len(suffix) - 1
+	if domain[i] != '.' {
+		return "", errorf("invalid domain %q", domain)
+	}
+	return domain[1+strings.LastIndex(domain[:i], "."):], nil
+}
+
+// 默认的公共后缀列表
+var (
+	// defaultList 代表默认的公共后缀列表
+	defaultList *List
+	// defaultOnce 用于保证内嵌的列表只被解析一次
+	defaultOnce sync.Once
+	// defaultLock 代表保护默认列表的读写锁
+	defaultLock sync.RWMutex
+)
+
+// Default 用于获取默认的公共后缀列表
+// 若未通过 SetDefault 设置，则使用内嵌的列表
+func Default() *List {
+	defaultOnce.Do(func() {
+		list, err := Parse(bytes.NewReader(listData))
+		if err != nil {
+			panic(err)
+		}
+
+		defaultLock.Lock()
+		if defaultList == nil {
+			defaultList = list
+		}
+		defaultLock.Unlock()
+	})
+
+	defaultLock.RLock()
+	defer defaultLock.RUnlock()
+	return defaultList
+}
+
+// SetDefault 用于替换默认的公共后缀列表，如使用 LoadFile 加载的更新版本
+// 参数list为nil时不做任何操作
+func SetDefault(list *List) {
+	if list == nil {
+		return
+	}
+
+	defaultLock.Lock()
+	defer defaultLock.Unlock()
+	defaultList = list
+}
+
+// EffectiveTLDPlusOne 会使用默认的公共后缀列表获取给定域名的主域名
+func EffectiveTLDPlusOne(domain string) (string, error) {
+	return Default().EffectiveTLDPlusOne(domain)
+}
@@ -0,0 +1,137 @@
+package publicsuffix
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestToASCII(t *testing.T) {
+	cases := map[string]string{
+		"cn.bing.com":  "cn.bing.com",
+		"CN.Bing.COM":  "cn.bing.com",
+		"bücher.de":    "xn--bcher-kva.de",
+		"münchen.de":   "xn--mnchen-3ya.de",
+		"例子.中国":        "xn--fsqu00a.xn--fiqs8s",
+		"xn--fiqs8s":   "xn--fiqs8s",
+		"ünïcödé.test": "xn--ncd-dma1a7bzb.test",
+	}
+
+	for domain, expected := range cases {
+		ascii, err := ToASCII(domain)
+		if err != nil {
+			t.Fatalf("An error occurs when converting domain %q: %s", domain, err)
+		}
+		if ascii != expected {
+			t.Fatalf("Inconsistent ASCII domain for %q: expected: %s, actual: %s", domain, expected, ascii)
+		}
+	}
+
+	if _, err := ToASCII("a.\xff"); err == nil {
+		t.Fatal("No error when converting a domain with invalid UTF-8!")
+	}
+}
+
+func TestEffectiveTLDPlusOne(t *testing.T) {
+	cases := map[string]string{
+		"cn.bing.com":            "bing.com",
+		"bing.com":               "bing.com",
+		"www.bbc.co.uk":          "bbc.co.uk",
+		"foo.bar.github.io":      "bar.github.io",
+		"go.dev":                 "go.dev",
+		"a.b.c.example.com.cn":   "example.com.cn",
+		"www.ck":                 "www.ck",
+		"a.b.ck":                 "a.b.ck",
+		"xn--fsqu00a.xn--fiqs8s": "xn--fsqu00a.xn--fiqs8s",
+		"a.xn--55qx5d.cn":        "a.xn--55qx5d.cn",
+		// 顶级域名不在列表中时使用默认规则 "*"
+		"123.notatld":       "123.notatld",
+		"www.intranet.corp": "intranet.corp",
+	}
+
+	for domain, expected := range cases {
+		pd, err := EffectiveTLDPlusOne(domain)
+		if err != nil {
+			t.Fatalf("An error occurs when getting eTLD+1 of %q: %s", domain, err)
+		}
+		if pd != expected {
+			t.Fatalf("Inconsistent eTLD+1 for %q: expected: %s, actual: %s", domain, expected, pd)
+		}
+	}
+
+	invalidDomains := []string{
+		"",
+		"com",
+		"co.uk",
+		"github.io",
+		"b.ck",
+		"notatld",
+		"localhost",
+		".bing.com",
+		"bing.com.",
+		"cn..bing.com",
+	}
+	for _, domain := range invalidDomains {
+		if pd, err := EffectiveTLDPlusOne(domain); err == nil {
+			t.Fatalf("No error when getting eTLD+1 of %q! (result: %s)", domain, pd)
+		}
+	}
+}
+
+func TestParseAndLoad(t *testing.T) {
+	content := `// comment
+com
+*.kawasaki.jp
+!city.kawasaki.jp
+example.test extra-text
+`
+	list, err := Parse(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("An error occurs when parsing list: %s", err)
+	}
+
+	if list.Len() != 4 {
+		t.Fatalf("Inconsistent rule number: expected: %d, actual: %d", 4, list.Len())
+	}
+
+	cases := map[string]string{
+		"a.b.kawasaki.jp":      "a.b.kawasaki.jp",
+		"www.city.kawasaki.jp": "city.kawasaki.jp",
+		"a.example.test":       "a.example.test",
+	}
+	for domain, expected := range cases {
+		pd, err := list.EffectiveTLDPlusOne(domain)
+		if err != nil {
+			t.Fatalf("An error occurs when getting eTLD+1 of %q: %s", domain, err)
+		}
+		if pd != expected {
+			t.Fatalf("Inconsistent eTLD+1 for %q: expected: %s, actual: %s", domain, expected, pd)
+		}
+	}
+
+	if _, err := Parse(strings.NewReader("// only comments\n")); err == nil {
+		t.Fatal("No error when parsing an empty list!")
+	}
+
+	filePath := filepath.Join(t.TempDir(), "list.dat")
+	if err := os.WriteFile(filePath, []byte("test\n"), 0644); err != nil {
+		t.Fatalf("An error occurs when writing list file: %s", err)
+	}
+
+	loaded, err := LoadFile(filePath)
+	if err != nil {
+		t.Fatalf("An error occurs when loading list file: %s", err)
+	}
+
+	if _, err := LoadFile(filePath + ".missing"); err == nil {
+		t.Fatal("No error when loading a missing list file!")
+	}
+
+	defaultList := Default()
+	SetDefault(loaded)
+	defer SetDefault(defaultList)
+	if pd, err := EffectiveTLDPlusOne("a.b.test"); err != nil || pd != "b.test" {
+		t.Fatalf("The default list was not replaced! (result: %s, error: %v)", pd, err)
+	}
+}
@@ -0,0 +1,143 @@
+package publicsuffix
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// punycode 编码的参数，参见 RFC 3492
+const (
+	punyBase        int32 = 36
+	punyTMin        int32 = 1
+	punyTMax        int32 = 26
+	punySkew        int32 = 38
+	punyDamp        int32 = 700
+	punyInitialBias int32 = 72
+	punyInitialN    int32 = 128
+	// acePrefix 代表国际化域名的ASCII兼容编码的前缀
+	acePrefix = "xn--"
+)
+
+// adapt 用于调整 punycode 编码的偏差值
+func adapt(delta, numPoints int32, firstTime bool) int32 {
+	if firstTime {
+		delta /= punyDamp
+	} else {
+		delta /= 2
+	}
+	delta += delta / numPoints
+
+	k := int32(0)
+	for delta > ((punyBase-punyTMin)*punyTMax)/2 {
+		delta /= punyBase - punyTMin
+		k += punyBase
+	}
+	return k + (punyBase-punyTMin+1)*delta/(delta+punySkew)
+}
+
+// encodeDigit 用于把数值编码为 punycode 的字符
+func encodeDigit(digit int32) byte {
+	if digit < 26 {
+		return byte('a' + digit)
+	}
+	return byte('0' + digit - 26)
+}
+
+// punycodeEncode 用于对单个标签进行 punycode 编码
+func punycodeEncode(label string) (string, error) {
+	runes := []rune(label)
+	output := make([]byte, 0, len(label)+1)
+	for _, r := range runes {
+		if r < 0x80 {
+			output = append(output, byte(r))
+		}
+	}
+
+	basicNumber := int32(len(output))
+	handled := basicNumber
+	if basicNumber > 0 {
+		output = append(output, '-')
+	}
+
+	n := punyInitialN
+	bias := punyInitialBias
+	delta := int32(0)
+	for handled < int32(len(runes)) {
+		m := int32(utf8.MaxRune + 1)
+		for _, r := range runes {
+			if r >= n && r < m {
+				m = r
+			}
+		}
+
+		if (m - n) > (1<<31-1-delta)/(handled+1) {
+			return "", errorf("punycode overflow: %q", label)
+		}
+		delta += (m - n) * (handled + 1)
+		n = m
+
+		for _, r := range runes {
+			if r < n {
+				delta++
+			}
+
+			if r != n {
+				continue
+			}
+
+			q := delta
+			for k := punyBase; ; k += punyBase {
+				t := k - bias
+				if t < punyTMin {
+					t = punyTMin
+				} else if t > punyTMax {
+					t = punyTMax
+				}
+				if q < t {
+					break
+				}
+				output = append(output, encodeDigit(t+(q-t)%(punyBase-t)))
+				q = (q - t) / (punyBase - t)
+			}
+			output = append(output, encodeDigit(q))
+			bias = adapt(delta, handled+1, handled == basicNumber)
+			delta = 0
+			handled++
+		}
+		delta++
+		n++
+	}
+
+	return string(output), nil
+}
+
+// ToASCII 用于把域名转换为小写的ASCII形式
+// 含有非ASCII字符的标签会被编码为以"xn--"开头的 punycode 形式
+func ToASCII(domain string) (string, error) {
+	if !utf8.ValidString(domain) {
+		return "", errorf("invalid UTF-8 domain: %q", domain)
+	}
+
+	domain = strings.ToLower(domain)
+	labels := strings.Split(domain, ".")
+	for i, label := range labels {
+		ascii := true
+		for j := 0; j < len(label); j++ {
+			if label[j] >= utf8.RuneSelf {
+				ascii = false
+				break
+			}
+		}
+
+		if ascii {
+			continue
+		}
+
+		encoded, err := punycodeEncode(label)
+		if err != nil {
+			return "", err
+		}
+		labels[i] = acePrefix + encoded
+	}
+	return strings.Join(labels, "."), nil
+}
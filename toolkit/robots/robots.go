@@ -0,0 +1,224 @@
+package robots
+
+import (
+	"bufio"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rule 代表一条Allow或Disallow规则
+type rule struct {
+	// pattern 代表路径模式，可以包含通配符"*"和结尾锚点"$"
+	pattern string
+	// allow 代表该规则是否为允许规则
+	allow bool
+}
+
+// group 代表robots.txt中针对若干user-agent的规则组
+type group struct {
+	// agents 代表规则组适用的user-agent列表(小写)
+	agents []string
+	// rules 代表规则组中的规则列表
+	rules []rule
+	// crawlDelay 代表规则组中的抓取间隔
+	crawlDelay time.Duration
+}
+
+// Rules 代表适用于某个user-agent的robots.txt规则
+type Rules struct {
+	// rules 代表适用的规则列表
+	rules []rule
+	// crawlDelay 代表适用的抓取间隔
+	crawlDelay time.Duration
+}
+
+// AllowAll 用于生成允许访问所有路径的规则
+func AllowAll() *Rules {
+	return &Rules{}
+}
+
+// DisallowAll 用于生成禁止访问所有路径的规则
+func DisallowAll() *Rules {
+	return &Rules{rules: []rule{{pattern: "/", allow: false}}}
+}
+
+// Parse 用于解析robots.txt的内容，并返回适用于给定user-agent的规则
+// 若存在与user-agent匹配的规则组，则使用匹配最具体的那一个，否则使用"*"规则组
+// 若二者都不存在，则返回允许访问所有路径的规则
+func Parse(reader io.Reader, userAgent string) (*Rules, error) {
+	groups, err := parseGroups(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	agent := strings.ToLower(strings.TrimSpace(userAgent))
+	var matched []*group
+	var matchedLen int
+	var wildcard []*group
+	for _, g := range groups {
+		for _, a := range g.agents {
+			if a == "*" {
+				wildcard = append(wildcard, g)
+				continue
+			}
+			if agent == "" || agent == "*" || !strings.Contains(agent, a) {
+				continue
+			}
+			if len(a) > matchedLen {
+				matched = []*group{g}
+				matchedLen = len(a)
+			} else if len(a) == matchedLen {
+				matched = append(matched, g)
+			}
+		}
+	}
+
+	if len(matched) == 0 {
+		matched = wildcard
+	}
+
+	rules := &Rules{}
+	for _, g := range matched {
+		rules.rules = append(rules.rules, g.rules...)
+		if g.crawlDelay > rules.crawlDelay {
+			rules.crawlDelay = g.crawlDelay
+		}
+	}
+
+	return rules, nil
+}
+
+// parseGroups 用于把robots.txt的内容解析为规则组列表
+func parseGroups(reader io.Reader) ([]*group, error) {
+	var groups []*group
+	var current *group
+	// inAgents 代表当前是否正处于连续的user-agent行中
+	var inAgents bool
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if index := strings.Index(line, "#"); index >= 0 {
+			line = line[:index]
+		}
+
+		index := strings.Index(line, ":")
+		if index < 0 {
+			continue
+		}
+
+		key := strings.ToLower(strings.TrimSpace(line[:index]))
+		value := strings.TrimSpace(line[index+1:])
+		switch key {
+		case "user-agent":
+			if !inAgents || current == nil {
+				current = &group{}
+				groups = append(groups, current)
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+			inAgents = true
+		case "allow", "disallow":
+			inAgents = false
+			if current == nil || value == "" {
+				continue
+			}
+			current.rules = append(current.rules, rule{pattern: value, allow: key == "allow"})
+		case "crawl-delay":
+			inAgents = false
+			if current == nil {
+				continue
+			}
+			seconds, err := strconv.ParseFloat(value, 64)
+			if err != nil || seconds < 0 {
+				continue
+			}
+			current.crawlDelay = time.Duration(seconds * float64(time.Second))
+		default:
+			// 其他的指令(如Sitemap)不会结束当前的user-agent行
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+// CrawlDelay 用于获取抓取间隔，若未指定则返回0
+func (r *Rules) CrawlDelay() time.Duration {
+	return r.crawlDelay
+}
+
+// Allowed 用于判断给定的URL是否允许被访问
+func (r *Rules) Allowed(u *url.URL) bool {
+	if u == nil {
+		return false
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+
+	return r.AllowedPath(path)
+}
+
+// AllowedPath 用于判断给定的路径(可包含查询字符串)是否允许被访问
+// 最长的匹配规则优先。长度相同时，允许规则优先
+func (r *Rules) AllowedPath(path string) bool {
+	allowed := true
+	matchedLen := -1
+	for _, rl := range r.rules {
+		if !match(rl.pattern, path) {
+			continue
+		}
+		l := len(rl.pattern)
+		if l > matchedLen || (l == matchedLen && rl.allow) {
+			allowed = rl.allow
+			matchedLen = l
+		}
+	}
+
+	return allowed
+}
+
+// match 用于判断给定路径是否与规则模式相匹配
+func match(pattern string, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = pattern[:len(pattern)-1]
+	}
+
+	parts := strings.Split(pattern, "*")
+	// 第一个部分必须是路径的前缀
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+
+	rest := path[len(parts[0]):]
+	if len(parts) == 1 {
+		return !anchored || rest == ""
+	}
+
+	for i, part := range parts[1:] {
+		last := i == len(parts)-2
+		if last && anchored {
+			return strings.HasSuffix(rest, part)
+		}
+
+		index := strings.Index(rest, part)
+		if index < 0 {
+			return false
+		}
+		rest = rest[index+len(part):]
+	}
+
+	return true
+}
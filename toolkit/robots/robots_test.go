@@ -0,0 +1,147 @@
+package robots
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+const content = `# robots.txt
+User-agent: *
+Disallow: /private/
+Allow: /private/public/
+Disallow: /*.pdf$
+Disallow: /search?*sessionid=
+Crawl-delay: 2
+
+User-agent: crawler
+User-agent: other
+Disallow: /
+Allow: /docs/
+Crawl-delay: 0.5
+
+User-agent: empty
+Disallow:
+`
+
+func TestParseWildcardGroup(t *testing.T) {
+	rules, err := Parse(strings.NewReader(content), "*")
+	if err != nil {
+		t.Fatalf("解析robots.txt时出错: %s", err)
+	}
+
+	cases := map[string]bool{
+		"/":                           true,
+		"/index.html":                 true,
+		"/private/":                   false,
+		"/private/a.html":             false,
+		"/private/public/a.html":      true,
+		"/files/a.pdf":                false,
+		"/files/a.pdf?x=1":            true,
+		"/search?q=go":                true,
+		"/search?q=go&sessionid=1234": false,
+	}
+
+	for path, expected := range cases {
+		if allowed := rules.AllowedPath(path); allowed != expected {
+			t.Fatalf("路径 %q 的结果不一致: 预期: %v, 实际: %v", path, expected, allowed)
+		}
+	}
+
+	if delay := rules.CrawlDelay(); delay != 2*time.Second {
+		t.Fatalf("抓取间隔不一致: 预期: %s, 实际: %s", 2*time.Second, delay)
+	}
+}
+
+func TestParseAgentGroup(t *testing.T) {
+	rules, err := Parse(strings.NewReader(content), "Crawler/1.0")
+	if err != nil {
+		t.Fatalf("解析robots.txt时出错: %s", err)
+	}
+
+	if rules.AllowedPath("/index.html") {
+		t.Fatalf("路径 %q 应该被禁止", "/index.html")
+	}
+
+	if !rules.AllowedPath("/docs/a.html") {
+		t.Fatalf("路径 %q 应该被允许", "/docs/a.html")
+	}
+
+	if delay := rules.CrawlDelay(); delay != 500*time.Millisecond {
+		t.Fatalf("抓取间隔不一致: 预期: %s, 实际: %s", 500*time.Millisecond, delay)
+	}
+
+	// 同一规则组中的第二个user-agent。
+	rules, _ = Parse(strings.NewReader(content), "other")
+	if rules.AllowedPath("/index.html") {
+		t.Fatalf("路径 %q 应该被禁止", "/index.html")
+	}
+
+	// 空的Disallow代表允许所有路径。
+	rules, _ = Parse(strings.NewReader(content), "empty")
+	if !rules.AllowedPath("/private/") {
+		t.Fatalf("路径 %q 应该被允许", "/private/")
+	}
+}
+
+func TestParseEmpty(t *testing.T) {
+	rules, err := Parse(strings.NewReader(""), "crawler")
+	if err != nil {
+		t.Fatalf("解析robots.txt时出错: %s", err)
+	}
+
+	if !rules.AllowedPath("/") {
+		t.Fatalf("路径 %q 应该被允许", "/")
+	}
+
+	if !AllowAll().AllowedPath("/a") {
+		t.Fatalf("路径 %q 应该被允许", "/a")
+	}
+
+	if DisallowAll().AllowedPath("/a") {
+		t.Fatalf("路径 %q 应该被禁止", "/a")
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	rules, _ := Parse(strings.NewReader(content), "*")
+	u, _ := url.Parse("http://example.com/search?q=go&sessionid=1")
+	if rules.Allowed(u) {
+		t.Fatalf("URL %q 应该被禁止", u)
+	}
+
+	u, _ = url.Parse("http://example.com")
+	if !rules.Allowed(u) {
+		t.Fatalf("URL %q 应该被允许", u)
+	}
+
+	if rules.Allowed(nil) {
+		t.Fatalf("nil URL 应该被禁止")
+	}
+}
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		pattern string
+		path    string
+		matched bool
+	}{
+		{"/", "/a", true},
+		{"/a", "/a/b", true},
+		{"/a$", "/a", true},
+		{"/a$", "/a/b", false},
+		{"/*.php", "/x/y.php?z", true},
+		{"/*.php$", "/x/y.php?z", false},
+		{"/a*b*c", "/a1b2c3", true},
+		{"/a*b*c", "/a1c2b", false},
+		{"*", "/anything", true},
+		{"/b", "/a/b", false},
+	}
+
+	for _, c := range cases {
+		if matched := match(c.pattern, c.path); matched != c.matched {
+			t.Fatalf("模式 %q 与路径 %q 的匹配结果不一致: 预期: %v, 实际: %v", c.pattern, c.path, c.matched, matched)
+		}
+	}
+}